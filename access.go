@@ -0,0 +1,253 @@
+package log
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// AccessMessage is the message of the JSON access records
+const AccessMessage = "access"
+
+// AccessEntry is one request in the access log
+type AccessEntry struct {
+	Time time.Time
+	// ClientIP is the address of the client without port
+	ClientIP string
+	// User is the authenticated user, empty when unknown
+	User   string
+	Method string
+	// Path is the request URI with the query
+	Path      string
+	Proto     string
+	Status    int
+	Bytes     int64
+	Duration  time.Duration
+	Referer   string
+	UserAgent string
+	// RequestID is set by HTTPMiddleware, written to JSON records only
+	RequestID string
+}
+
+// AccessLogger writes the requests of an HTTP server to their own file, in
+// the Apache combined format or as JSON records with the keys time, level,
+// msg, client_ip, user, method, path, proto, status, bytes, duration,
+// referer, user_agent and request_id. The combined format has no duration.
+type AccessLogger struct {
+	out     zapcore.WriteSyncer
+	enc     zapcore.Encoder
+	loc     *time.Location
+	closers []io.Closer
+}
+
+// NewAccessLogger opens the access log of the config: JSON when
+// EncodeLogsAsJson is set, the combined format otherwise. With
+// FileLoggingEnabled it writes the file Filename, access.log by default, in
+// Directory rotated like the other log files, and to the console otherwise.
+// Only the output, encoding and time settings of the config apply, with
+// ContainerMode the records are JSON on the console.
+func NewAccessLogger(config Config) (*AccessLogger, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	config = outputModes(config)
+	a := &AccessLogger{}
+	a.loc, _ = timeLocation(config.TimeZone)
+	if config.EncodeLogsAsJson {
+		a.enc = newEncoder(config)
+	}
+
+	var writers []zapcore.WriteSyncer
+	if config.FileLoggingEnabled {
+		dir, err := resolveDirectory(config)
+		if err != nil {
+			return nil, err
+		}
+		config.Directory = dir
+		if config.fileCipher, err = newFileCipher(config.Encryption); err != nil {
+			return nil, err
+		}
+		if config.Filename == "" {
+			config.Filename = "access.log"
+		}
+		file, err := newRollingFile(config, config.Filename)
+		if err != nil {
+			return nil, err
+		}
+		writers = append(writers, file)
+		a.closers = fileClosers(file)
+	}
+	if config.ConsoleLoggingEnabled || !config.FileLoggingEnabled {
+		infoConsole, _ := consoleWriters(config)
+		writers = append(writers, infoConsole)
+	}
+	a.out = zapcore.Lock(zapcore.NewMultiWriteSyncer(writers...))
+	return a, nil
+}
+
+// Log writes the entry
+func (a *AccessLogger) Log(e AccessEntry) error {
+	if a.enc == nil {
+		_, err := a.out.Write(a.combined(e))
+		return err
+	}
+	buf, err := a.enc.EncodeEntry(zapcore.Entry{Level: InfoLevel, Time: e.Time, Message: AccessMessage}, []zapcore.Field{
+		zap.String("client_ip", e.ClientIP),
+		zap.String("user", e.User),
+		zap.String("method", e.Method),
+		zap.String("path", e.Path),
+		zap.String("proto", e.Proto),
+		zap.Int("status", e.Status),
+		zap.Int64("bytes", e.Bytes),
+		zap.Duration(DurationKey, e.Duration),
+		zap.String("referer", e.Referer),
+		zap.String("user_agent", e.UserAgent),
+		zap.String("request_id", e.RequestID),
+	})
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+	_, err = a.out.Write(buf.Bytes())
+	return err
+}
+
+// combined formats the entry like Apache's
+// "%h %l %u %t \"%r\" %>s %b \"%{Referer}i\" \"%{User-agent}i\""
+func (a *AccessLogger) combined(e AccessEntry) []byte {
+	t := e.Time
+	if a.loc != nil {
+		t = t.In(a.loc)
+	}
+	size := "-"
+	if e.Bytes > 0 {
+		size = strconv.FormatInt(e.Bytes, 10)
+	}
+	var b strings.Builder
+	b.WriteString(orDash(e.ClientIP) + " - " + orDash(strings.ReplaceAll(e.User, " ", "_")))
+	b.WriteString(" [" + t.Format("02/Jan/2006:15:04:05 -0700") + "] ")
+	b.WriteString(quoteAccess(e.Method+" "+e.Path+" "+e.Proto) + " ")
+	b.WriteString(strconv.Itoa(e.Status) + " " + size + " ")
+	b.WriteString(quoteAccess(orDash(e.Referer)) + " " + quoteAccess(orDash(e.UserAgent)) + "\n")
+	return []byte(b.String())
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// quoteAccess quotes s escaping quotes, backslashes and control characters
+// like Apache does
+func quoteAccess(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			b.WriteString(`\x` + strconv.FormatUint(uint64(c)|0x100, 16)[1:])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// Middleware writes an entry for every request served by next
+func (a *AccessLogger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		e := AccessEntry{
+			Time:      start,
+			ClientIP:  r.RemoteAddr,
+			Method:    r.Method,
+			Path:      r.URL.RequestURI(),
+			Proto:     r.Proto,
+			Status:    sw.status,
+			Bytes:     sw.bytes,
+			Duration:  time.Since(start),
+			Referer:   r.Referer(),
+			UserAgent: r.UserAgent(),
+		}
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			e.ClientIP = host
+		}
+		if user, _, ok := r.BasicAuth(); ok {
+			e.User = user
+		}
+		if e.Status == 0 {
+			e.Status = http.StatusOK
+		}
+		e.RequestID, _ = RequestIDFromContext(r.Context())
+		if e.RequestID == "" {
+			e.RequestID = w.Header().Get(RequestIDHeader)
+		}
+		if err := a.Log(e); err != nil {
+			Errorv("failed write access log", zap.Error(err))
+		}
+	})
+}
+
+// Sync flushes the access log
+func (a *AccessLogger) Sync() error {
+	return a.out.Sync()
+}
+
+// Close syncs the access log and closes its file
+func (a *AccessLogger) Close() error {
+	err := a.Sync()
+	for _, c := range a.closers {
+		err = multierr.Append(err, c.Close())
+	}
+	return err
+}
+
+// statusWriter records the status and the size of a response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap gives http.ResponseController access to the wrapped writer
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,192 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrorAlertConfig raises alerts on bursts of error entries. Entries at error
+// level and above are grouped by signature, their message with digits masked,
+// and counted over a sliding Window; reaching Threshold raises an alert.
+type ErrorAlertConfig struct {
+	// Window the entries are counted over. It defaults to one minute
+	Window time.Duration
+	// Threshold is the number of entries with the same signature within the
+	// window raising an alert. It defaults to 10
+	Threshold int
+	// Alert is called with each alert, it must not log at error level
+	Alert func(ErrorAlert)
+	// WebhookURL receives each alert as a JSON POST when set
+	WebhookURL string
+}
+
+// ErrorAlert describes a burst of error entries with the same signature.
+// A signature alerts at most once per window.
+type ErrorAlert struct {
+	Signature string        `json:"signature"`
+	Message   string        `json:"message"`
+	Level     string        `json:"level"`
+	Count     int           `json:"count"`
+	Window    time.Duration `json:"window"`
+	Time      time.Time     `json:"time"`
+}
+
+// maxAlertSignatures bounds the signatures tracked at once, idle ones are
+// swept when it is reached
+const maxAlertSignatures = 1024
+
+type errorAggregator struct {
+	config ErrorAlertConfig
+	client *http.Client
+
+	mu         sync.Mutex
+	signatures map[string]*alertWindow
+}
+
+type alertWindow struct {
+	times     []time.Time
+	alertedAt time.Time
+}
+
+func newErrorAggregator(config *ErrorAlertConfig) *errorAggregator {
+	if config == nil {
+		return nil
+	}
+	a := &errorAggregator{
+		config:     *config,
+		client:     &http.Client{Timeout: 5 * time.Second},
+		signatures: map[string]*alertWindow{},
+	}
+	if a.config.Window <= 0 {
+		a.config.Window = time.Minute
+	}
+	if a.config.Threshold <= 0 {
+		a.config.Threshold = 10
+	}
+	return a
+}
+
+// observe counts the entry and raises an alert when its burst reaches the threshold
+func (a *errorAggregator) observe(ent zapcore.Entry) {
+	signature := errorSignature(ent.Message)
+	now := ent.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	a.mu.Lock()
+	w, ok := a.signatures[signature]
+	if !ok {
+		if len(a.signatures) >= maxAlertSignatures {
+			a.sweep(now)
+		}
+		w = &alertWindow{}
+		a.signatures[signature] = w
+	}
+	cutoff := now.Add(-a.config.Window)
+	kept := w.times[:0]
+	for _, t := range w.times {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	w.times = append(kept, now)
+	count := len(w.times)
+	raise := count >= a.config.Threshold && !w.alertedAt.After(cutoff)
+	if raise {
+		w.alertedAt = now
+	}
+	a.mu.Unlock()
+
+	if raise {
+		a.raise(ErrorAlert{
+			Signature: signature,
+			Message:   ent.Message,
+			Level:     LevelName(ent.Level),
+			Count:     count,
+			Window:    a.config.Window,
+			Time:      now,
+		})
+	}
+}
+
+// sweep drops the signatures without entries in the window
+func (a *errorAggregator) sweep(now time.Time) {
+	cutoff := now.Add(-a.config.Window)
+	for signature, w := range a.signatures {
+		if len(w.times) == 0 || !w.times[len(w.times)-1].After(cutoff) {
+			delete(a.signatures, signature)
+		}
+	}
+}
+
+func (a *errorAggregator) raise(alert ErrorAlert) {
+	if a.config.Alert != nil {
+		a.config.Alert(alert)
+	}
+	if a.config.WebhookURL != "" {
+		go a.post(alert)
+	}
+}
+
+func (a *errorAggregator) post(alert ErrorAlert) {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return
+	}
+	resp, err := a.client.Post(a.config.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+}
+
+// errorSignature masks the digits of the message so entries differing only
+// by ids or counts are grouped together
+func errorSignature(msg string) string {
+	var sb strings.Builder
+	digits := false
+	for _, r := range msg {
+		if '0' <= r && r <= '9' {
+			if !digits {
+				sb.WriteByte('#')
+			}
+			digits = true
+			continue
+		}
+		digits = false
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// alertCore feeds the entries at error level and above to the aggregator. It
+// observes in Check so bursts are counted before sampling drops entries.
+type alertCore struct {
+	zapcore.Core
+	aggregator *errorAggregator
+}
+
+func newAlertCore(core zapcore.Core, aggregator *errorAggregator) zapcore.Core {
+	if aggregator == nil {
+		return core
+	}
+	return &alertCore{Core: core, aggregator: aggregator}
+}
+
+func (c *alertCore) With(fields []zapcore.Field) zapcore.Core {
+	return &alertCore{Core: c.Core.With(fields), aggregator: c.aggregator}
+}
+
+func (c *alertCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level >= ErrorLevel {
+		c.aggregator.observe(ent)
+	}
+	return c.Core.Check(ent, ce)
+}
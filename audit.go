@@ -0,0 +1,236 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sync"
+)
+
+// AuditHashKey holds the hash chaining an audit record to the ones before it
+const AuditHashKey = "audit_hash"
+
+// AuditSink appends records to a file with a hash chain: every line carries
+// the SHA-256 of the previous hash followed by the line as it was written, so
+// editing, removing or reordering records breaks the chain, see
+// VerifyAuditFile. JSON records get the hash as their last key, other lines
+// end with " audit_hash=<hex>".
+type AuditSink struct {
+	mu   sync.Mutex
+	file *os.File
+	prev [sha256.Size]byte
+}
+
+func init() {
+	if err := RegisterSink("audit", newAuditSinkFromURL); err != nil {
+		panic(err)
+	}
+}
+
+// newAuditSinkFromURL opens "audit:///var/log/app/audit.log"
+func newAuditSinkFromURL(u *url.URL) (Sink, error) {
+	path := u.Path
+	if path == "" {
+		path = u.Opaque
+	}
+	if path == "" {
+		return nil, fmt.Errorf("audit sink url %q has no path", u.String())
+	}
+	return NewAuditSink(path)
+}
+
+// NewAuditSink opens the audit file at path, continuing the chain of the
+// records already in it
+func NewAuditSink(path string) (*AuditSink, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	s := &AuditSink{file: file}
+	last, err := lastLine(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if len(last) > 0 {
+		_, hash, ok := splitAuditHash(last)
+		if !ok {
+			file.Close()
+			return nil, fmt.Errorf("audit file %s does not end with an audit record", path)
+		}
+		s.prev = hash
+	}
+	return s, nil
+}
+
+func (s *AuditSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var buf bytes.Buffer
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\n"), []byte("\n")) {
+		s.prev = chainHash(s.prev, line)
+		buf.Write(appendAuditHash(line, s.prev))
+		buf.WriteByte('\n')
+	}
+	if _, err := s.file.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (s *AuditSink) Sync() error {
+	return s.file.Sync()
+}
+
+func (s *AuditSink) Close() error {
+	return s.file.Close()
+}
+
+// AuditError locates the first record breaking the chain
+type AuditError struct {
+	Line   int
+	Reason string
+}
+
+func (e *AuditError) Error() string {
+	return fmt.Sprintf("audit record on line %d: %s", e.Line, e.Reason)
+}
+
+// VerifyAuditFile checks the hash chain of an audit file, returning the number
+// of records verified and an *AuditError at the first tampered record
+func VerifyAuditFile(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return VerifyAudit(f)
+}
+
+// VerifyAudit checks the hash chain of the audit records read from r
+func VerifyAudit(r io.Reader) (int, error) {
+	var prev [sha256.Size]byte
+	br := bufio.NewReader(r)
+	n := 0
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			record, hash, ok := splitAuditHash(bytes.TrimRight(line, "\n"))
+			if !ok {
+				return n, &AuditError{Line: n + 1, Reason: "missing " + AuditHashKey}
+			}
+			if prev = chainHash(prev, record); prev != hash {
+				return n, &AuditError{Line: n + 1, Reason: "hash mismatch"}
+			}
+			n++
+		}
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+func chainHash(prev [sha256.Size]byte, line []byte) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write(prev[:])
+	h.Write(line)
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// jsonAuditSuffix precedes the hash in JSON records with other keys
+var jsonAuditSuffix = []byte(`,"` + AuditHashKey + `":"`)
+
+func isJSONObject(line []byte) bool {
+	return len(line) >= 2 && line[0] == '{' && line[len(line)-1] == '}'
+}
+
+// appendAuditHash returns the line carrying the hash
+func appendAuditHash(line []byte, hash [sha256.Size]byte) []byte {
+	hexHash := hex.EncodeToString(hash[:])
+	out := make([]byte, 0, len(line)+len(hexHash)+len(AuditHashKey)+6)
+	switch {
+	case isJSONObject(line) && len(bytes.TrimSpace(line[1:len(line)-1])) == 0:
+		out = append(out, `{"`+AuditHashKey+`":"`+hexHash+`"}`...)
+	case isJSONObject(line):
+		out = append(out, line[:len(line)-1]...)
+		out = append(out, jsonAuditSuffix...)
+		out = append(out, hexHash+`"}`...)
+	default:
+		out = append(out, line...)
+		out = append(out, " "+AuditHashKey+"="+hexHash...)
+	}
+	return out
+}
+
+// splitAuditHash returns the record as it was hashed and its hash
+func splitAuditHash(line []byte) ([]byte, [sha256.Size]byte, bool) {
+	var hash [sha256.Size]byte
+	hexLen := hex.EncodedLen(sha256.Size)
+	var record, hexHash []byte
+	if isJSONObject(line) {
+		end := len(line) - 2 // before `"}`
+		start := end - hexLen
+		if start < 0 || line[end] != '"' {
+			return nil, hash, false
+		}
+		hexHash = line[start:end]
+		prefix := line[:start]
+		switch {
+		case bytes.HasSuffix(prefix, jsonAuditSuffix):
+			record = append(append([]byte(nil), prefix[:len(prefix)-len(jsonAuditSuffix)]...), '}')
+		case bytes.Equal(prefix, []byte(`{"`+AuditHashKey+`":"`)):
+			record = []byte("{}")
+		default:
+			return nil, hash, false
+		}
+	} else {
+		marker := []byte(" " + AuditHashKey + "=")
+		i := bytes.LastIndex(line, marker)
+		if i < 0 || len(line)-i-len(marker) != hexLen {
+			return nil, hash, false
+		}
+		record, hexHash = line[:i], line[i+len(marker):]
+	}
+	if _, err := hex.Decode(hash[:], hexHash); err != nil {
+		return nil, hash, false
+	}
+	return record, hash, true
+}
+
+// lastLine returns the last non-empty line of f without its newline
+func lastLine(f *os.File) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	const block = 4096
+	var tail []byte
+	for end := info.Size(); end > 0; {
+		start := end - block
+		if start < 0 {
+			start = 0
+		}
+		chunk := make([]byte, end-start)
+		if _, err := f.ReadAt(chunk, start); err != nil && err != io.EOF {
+			return nil, err
+		}
+		tail = append(chunk, tail...)
+		trimmed := bytes.TrimRight(tail, "\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], nil
+		}
+		end = start
+	}
+	return bytes.TrimRight(tail, "\n"), nil
+}
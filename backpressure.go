@@ -0,0 +1,211 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// BackpressurePolicy is what WithBackpressure does with a write when the
+// queue of a stalled sink is full
+type BackpressurePolicy int
+
+const (
+	// BackpressureBlock waits for room in the queue, slowing the logging
+	// goroutines down to the pace of the sink
+	BackpressureBlock BackpressurePolicy = iota
+	// BackpressureDrop drops the write and counts it
+	BackpressureDrop
+	// BackpressureSpill writes it to BackpressureConfig.Spill instead
+	BackpressureSpill
+)
+
+var errSinkClosed = errors.New("log: sink closed")
+
+// BackpressureConfig configures WithBackpressure
+type BackpressureConfig struct {
+	// Name identifies the sink for OnStall
+	Name string
+	// QueueSize is the number of writes held while the sink is busy, 1024 by default
+	QueueSize int
+	Policy    BackpressurePolicy
+	// Spill receives the writes which do not fit in the queue with
+	// BackpressureSpill, e.g. a local overflow file. They are dropped when it
+	// is nil.
+	Spill io.Writer
+	// StallThreshold is the time from which a write to the sink counts as a
+	// stall, 100ms by default
+	StallThreshold time.Duration
+	// OnStall is called after each stall with its duration, by the goroutine
+	// writing to the sink
+	OnStall func(name string, stall time.Duration, stats BackpressureStats)
+}
+
+// BackpressureStats is a snapshot of the counters of a BackpressureSink
+type BackpressureStats struct {
+	// Queued is the number of writes waiting for the sink
+	Queued int
+	// Dropped is the number of writes dropped while the queue was full
+	Dropped int64
+	// Spilled is the number of writes sent to BackpressureConfig.Spill
+	Spilled int64
+	// Failed is the number of writes the sink returned an error for
+	Failed int64
+	// Stalls is the number of writes to the sink taking StallThreshold or longer
+	Stalls int64
+	// StallTime is the total time of the stalls
+	StallTime time.Duration
+}
+
+// BackpressureSink writes to a sink from a queue, so a stalled sink does
+// not hold the logging goroutines up until the queue is full. The time the
+// writes wait in the queue is recorded in PipelineStats.
+type BackpressureSink struct {
+	sink   zapcore.WriteSyncer
+	config BackpressureConfig
+	queue  chan queuedWrite
+	// closeMu guards sending to the queue against Close
+	closeMu sync.RWMutex
+	closed  bool
+	done    chan struct{}
+	spillMu sync.Mutex
+
+	dropped   atomic.Int64
+	spilled   atomic.Int64
+	failed    atomic.Int64
+	stalls    atomic.Int64
+	stallTime atomic.Int64
+}
+
+// queuedWrite is a write or, with synced set, a Sync waiting for the writes
+// queued before it
+type queuedWrite struct {
+	p        []byte
+	queuedAt time.Time
+	synced   chan error
+}
+
+// WithBackpressure wraps the sink, e.g. a network sink of
+// Config.ExtraInfoSinks, to write to it from a queue with the policy of the
+// config for a full queue. Writes succeed until Close, the errors of the sink are
+// counted in the stats. Sync waits for the queued writes.
+func WithBackpressure(sink zapcore.WriteSyncer, config BackpressureConfig) *BackpressureSink {
+	if config.QueueSize <= 0 {
+		config.QueueSize = 1024
+	}
+	if config.StallThreshold <= 0 {
+		config.StallThreshold = 100 * time.Millisecond
+	}
+	s := &BackpressureSink{
+		sink:   sink,
+		config: config,
+		queue:  make(chan queuedWrite, config.QueueSize),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *BackpressureSink) Write(p []byte) (int, error) {
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+	if s.closed {
+		return 0, errSinkClosed
+	}
+	w := queuedWrite{p: append([]byte(nil), p...), queuedAt: time.Now()}
+	if s.config.Policy == BackpressureBlock {
+		s.queue <- w
+		return len(p), nil
+	}
+	select {
+	case s.queue <- w:
+	default:
+		s.overflow(p)
+	}
+	return len(p), nil
+}
+
+// overflow handles a write not fitting in the queue
+func (s *BackpressureSink) overflow(p []byte) {
+	if s.config.Policy != BackpressureSpill || s.config.Spill == nil {
+		s.dropped.Add(1)
+		return
+	}
+	s.spillMu.Lock()
+	_, err := s.config.Spill.Write(p)
+	s.spillMu.Unlock()
+	if err != nil {
+		s.dropped.Add(1)
+		return
+	}
+	s.spilled.Add(1)
+}
+
+// Sync waits for the writes queued before it and syncs the sink
+func (s *BackpressureSink) Sync() error {
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+	if s.closed {
+		return nil
+	}
+	synced := make(chan error, 1)
+	s.queue <- queuedWrite{synced: synced}
+	return <-synced
+}
+
+// Close writes the queued writes and closes the sink if it is an io.Closer.
+// Later writes fail.
+func (s *BackpressureSink) Close() error {
+	s.closeMu.Lock()
+	if s.closed {
+		s.closeMu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.closeMu.Unlock()
+	<-s.done
+	if c, ok := s.sink.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Stats returns the counters of the sink
+func (s *BackpressureSink) Stats() BackpressureStats {
+	return BackpressureStats{
+		Queued:    len(s.queue),
+		Dropped:   s.dropped.Load(),
+		Spilled:   s.spilled.Load(),
+		Failed:    s.failed.Load(),
+		Stalls:    s.stalls.Load(),
+		StallTime: time.Duration(s.stallTime.Load()),
+	}
+}
+
+// run writes the queue to the sink until Close
+func (s *BackpressureSink) run() {
+	defer close(s.done)
+	for w := range s.queue {
+		if w.synced != nil {
+			w.synced <- s.sink.Sync()
+			continue
+		}
+		start := time.Now()
+		pipeline.queueWait.observe(start.Sub(w.queuedAt))
+		if _, err := s.sink.Write(w.p); err != nil {
+			s.failed.Add(1)
+		}
+		if took := time.Since(start); took >= s.config.StallThreshold {
+			s.stalls.Add(1)
+			s.stallTime.Add(int64(took))
+			if s.config.OnStall != nil {
+				s.config.OnStall(s.config.Name, took, s.Stats())
+			}
+		}
+	}
+}
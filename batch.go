@@ -0,0 +1,106 @@
+package log
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+// BatchEntry is an entry of LogBatch
+type BatchEntry struct {
+	Level   Level
+	Message string
+	Fields  Fields
+	// Time of the entry, the time of LogBatch when zero
+	Time time.Time
+}
+
+// LogBatch writes the entries with a single write per output, for event
+// export style workloads logging many records at once. The entries go
+// through the level, sampling and the other stages one by one, only the
+// writes of the encoded entries are joined: the writers of AddWriter still
+// get them one by one. Entries above ErrorLevel are written at ErrorLevel, a
+// batch does not panic or exit. It returns the errors of the writes.
+func (le *LogEntry) LogBatch(entries []BatchEntry) error {
+	batch := &entryBatch{}
+	marker := zapcore.Field{Type: zapcore.SkipType, Interface: batch}
+	for _, e := range entries {
+		level := e.Level
+		if level > ErrorLevel && level != NoticeLevel {
+			level = ErrorLevel
+		}
+		ce := newCheckedEntry(le.loggerFor(level).Check(thresholdLevel(level), e.Message), level)
+		if ce == nil {
+			continue
+		}
+		if !e.Time.IsZero() {
+			ce.ce.Time = e.Time
+		}
+		zfields := getFieldSlice(e.Fields, le.sortFields)
+		*zfields = append(*zfields, marker)
+		ce.Write(*zfields...)
+		putFieldSlice(zfields)
+	}
+	return batch.flush()
+}
+
+// LogBatch writes the entries with the default logger, see LogEntry.LogBatch
+func LogBatch(entries []BatchEntry) error {
+	return Default().WithCallerSkip(1).LogBatch(entries)
+}
+
+// entryBatch collects the encoded entries of LogBatch per output. Entries
+// reaching an output after the flush, like the ones of buffered loggers, are
+// written on their own.
+type entryBatch struct {
+	mu      sync.Mutex
+	flushed bool
+	outputs []*ioCore
+	bufs    [][]byte
+}
+
+// batchOf returns the batch of the entry, nil if it is not part of one
+func batchOf(fields []zapcore.Field) *entryBatch {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Type != zapcore.SkipType {
+			continue
+		}
+		if b, ok := fields[i].Interface.(*entryBatch); ok {
+			return b
+		}
+	}
+	return nil
+}
+
+// add appends the encoded entry for the output of core, it reports false
+// once the batch is flushed
+func (b *entryBatch) add(core *ioCore, p []byte) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.flushed {
+		return false
+	}
+	for i, out := range b.outputs {
+		if out == core {
+			b.bufs[i] = append(b.bufs[i], p...)
+			return true
+		}
+	}
+	b.outputs = append(b.outputs, core)
+	b.bufs = append(b.bufs, append([]byte(nil), p...))
+	return true
+}
+
+func (b *entryBatch) flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flushed = true
+	var err error
+	for i, core := range b.outputs {
+		_, werr := core.out.Write(b.bufs[i])
+		err = multierr.Append(err, werr)
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+package log
+
+import (
+	"runtime/debug"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// The build variables are set with the linker, e.g.
+//
+//	go build -ldflags "-X github.com/olee12/log.BuildVersion=1.4.0 -X github.com/olee12/log.BuildCommit=$(git rev-parse HEAD)"
+//
+// Unset ones are taken from the build info embedded by go build.
+var (
+	BuildVersion string
+	BuildCommit  string
+	BuildDate    string
+)
+
+// WithBuildInfo stamps every entry with the version, commit and build_date
+// of the binary, the known ones, as global fields, see SetGlobalFields, and
+// logs the startup entry of LogStartup. It returns the fields it added.
+func WithBuildInfo() Fields {
+	fields := buildInfoFields()
+
+	globals := loadGlobalFields()
+	merged := make([]zapcore.Field, 0, len(globals)+len(fields))
+	for _, f := range globals {
+		if f.Key != "version" && f.Key != "commit" && f.Key != "build_date" {
+			merged = append(merged, f)
+		}
+	}
+	merged = append(merged, fields...)
+	globalFields.Store(&merged)
+
+	LogStartup(nil)
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return Fields(enc.Fields)
+}
+
+// buildInfoFields returns the build variables which are known, falling back
+// to the module version and the vcs settings of the build info
+func buildInfoFields() []zapcore.Field {
+	version, commit, date := BuildVersion, BuildCommit, BuildDate
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if version == "" && info.Main.Version != "(devel)" {
+			version = info.Main.Version
+		}
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if commit == "" {
+					commit = setting.Value
+				}
+			case "vcs.time":
+				if date == "" {
+					date = setting.Value
+				}
+			case "vcs.modified":
+				if setting.Value == "true" && commit != "" && BuildCommit == "" {
+					commit += "-dirty"
+				}
+			}
+		}
+	}
+	var fields []zapcore.Field
+	for _, f := range []zapcore.Field{
+		zap.String("version", version),
+		zap.String("commit", commit),
+		zap.String("build_date", date),
+	} {
+		if f.String != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
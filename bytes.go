@@ -0,0 +1,64 @@
+package log
+
+import (
+	"strconv"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// ByteSize is a number of bytes. It is written as a raw number in JSON and as
+// a human readable size such as "1.5 MiB" in console mode, see Bytes.
+type ByteSize int64
+
+var byteUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+// String returns the size with a binary unit and one decimal
+func (b ByteSize) String() string {
+	n := float64(b)
+	sign := ""
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	unit := 0
+	for n >= 1024 && unit < len(byteUnits)-1 {
+		n /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return sign + strconv.FormatFloat(n, 'f', 0, 64) + " B"
+	}
+	return sign + strconv.FormatFloat(n, 'f', 1, 64) + " " + byteUnits[unit]
+}
+
+// humanEncoder renders ByteSize fields as strings, it wraps the console encoder
+type humanEncoder struct {
+	zapcore.Encoder
+}
+
+func (e humanEncoder) Clone() zapcore.Encoder {
+	return humanEncoder{e.Encoder.Clone()}
+}
+
+func (e humanEncoder) AddReflected(key string, obj interface{}) error {
+	if b, ok := obj.(ByteSize); ok {
+		e.Encoder.AddString(key, b.String())
+		return nil
+	}
+	return e.Encoder.AddReflected(key, obj)
+}
+
+func (e humanEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	copied := false
+	for i, f := range fields {
+		if b, ok := f.Interface.(ByteSize); ok && f.Type == zapcore.ReflectType {
+			// the fields belong to the caller
+			if !copied {
+				fields = append([]zapcore.Field(nil), fields...)
+				copied = true
+			}
+			fields[i] = zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: b.String()}
+		}
+	}
+	return e.Encoder.EncodeEntry(ent, fields)
+}
@@ -0,0 +1,46 @@
+package log
+
+import (
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// CallerFunc selects how the function name is added to the caller
+type CallerFunc int
+
+const (
+	// CallerFuncNone logs the caller as file:line only
+	CallerFuncNone CallerFunc = iota
+	// CallerFuncShort appends the function with its package name, e.g. "log.Configure"
+	CallerFuncShort
+	// CallerFuncFull appends the function with its import path, e.g. "github.com/olee12/log.Configure"
+	CallerFuncFull
+)
+
+// callerEncoder returns the caller encoder for the function format
+func callerEncoder(format CallerFunc) zapcore.CallerEncoder {
+	if format == CallerFuncNone {
+		return zapcore.ShortCallerEncoder
+	}
+	return func(caller zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
+		function := caller.Function
+		if format == CallerFuncShort {
+			function = shortFuncName(function)
+		}
+		if function == "" {
+			enc.AppendString(caller.TrimmedPath())
+			return
+		}
+		enc.AppendString(caller.TrimmedPath() + " " + function)
+	}
+}
+
+// shortFuncName strips the import path of a function name keeping the package,
+// "github.com/olee12/log.(*LogEntry).Info" becomes "log.(*LogEntry).Info"
+func shortFuncName(function string) string {
+	if i := strings.LastIndexByte(function, '/'); i >= 0 {
+		return function[i+1:]
+	}
+	return function
+}
@@ -0,0 +1,76 @@
+package log
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// CheckedEntry is an entry which passed the level checks, returned by Check
+type CheckedEntry struct {
+	ce     *zapcore.CheckedEntry
+	notice bool
+}
+
+// Write writes the entry with the fields. Like zap, Panic and Fatal entries
+// panic or exit after writing.
+func (c *CheckedEntry) Write(fields ...zapcore.Field) {
+	if c == nil {
+		return
+	}
+	if c.notice {
+		fields = noticeFields(fields)
+	}
+	c.ce.Write(fields...)
+}
+
+// WithTime sets the time of the entry, e.g. to the timestamp of a record
+// from another logging API. The zero time is ignored.
+func (c *CheckedEntry) WithTime(t time.Time) *CheckedEntry {
+	if c != nil && !t.IsZero() {
+		c.ce.Time = t
+	}
+	return c
+}
+
+// Enabled reports whether the default logger writes entries at the level
+func Enabled(level Level) bool {
+	return Default().Enabled(level)
+}
+
+// Check returns the entry of the default logger if it is enabled at the level
+// and nil otherwise, to guard expensive fields:
+//
+//	if ce := log.Check(log.DebugLevel, "state"); ce != nil {
+//		ce.Write(log.Any("state", dump()))
+//	}
+func Check(level Level, msg string) *CheckedEntry {
+	return newCheckedEntry(Default().loggerFor(level).Check(thresholdLevel(level), msg), level)
+}
+
+// Enabled reports whether the logger writes entries at the level
+func (le *LogEntry) Enabled(level Level) bool {
+	return le.loggerFor(level).Core().Enabled(thresholdLevel(level))
+}
+
+// Check returns the entry if the logger is enabled at the level and nil otherwise
+func (le *LogEntry) Check(level Level, msg string) *CheckedEntry {
+	return newCheckedEntry(le.loggerFor(level).Check(thresholdLevel(level), msg), level)
+}
+
+// loggerFor returns the logger writing the level, info and below go to the
+// info output and warn and above to the error output
+func (le *LogEntry) loggerFor(level Level) *zap.Logger {
+	if level <= InfoLevel || level == NoticeLevel {
+		return le.infoLogger
+	}
+	return le.errorLogger
+}
+
+func newCheckedEntry(ce *zapcore.CheckedEntry, level Level) *CheckedEntry {
+	if ce == nil {
+		return nil
+	}
+	return &CheckedEntry{ce: ce, notice: level == NoticeLevel}
+}
@@ -0,0 +1,81 @@
+package log
+
+import (
+	"os"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// CLIMode returns the config of a command line tool: human single lines on
+// stderr, see Config.CLIOutput, keeping stdout for the output of the tool.
+// verbose and quiet are the counts of -v and -q flags, from info level each
+// -v goes down to debug then trace and each -q up to warn then error.
+//
+//	log.Configure(log.CLIMode(*quiet, *verbose))
+func CLIMode(quiet, verbose int) Config {
+	level := InfoLevel
+	switch n := verbose - quiet; {
+	case n >= 2:
+		level = TraceLevel
+	case n == 1:
+		level = DebugLevel
+	case n == -1:
+		level = WarnLevel
+	case n <= -2:
+		level = ErrorLevel
+	}
+	return Config{
+		Level:                 level,
+		ConsoleLoggingEnabled: true,
+		ConsoleInfoStream:     os.Stderr,
+		ConsoleErrorStream:    os.Stderr,
+		CLIOutput:             true,
+	}
+}
+
+// cliEncoder writes the entries as `level: message key=value`, the level left
+// out for info entries. It encodes the entries with the wrapped JSON encoder,
+// which has no time and caller, and reformats them.
+type cliEncoder struct {
+	zapcore.Encoder
+}
+
+// newCLIEncoder returns the encoder of Config.CLIOutput
+func newCLIEncoder(encCfg zapcore.EncoderConfig) zapcore.Encoder {
+	encCfg.TimeKey = ""
+	encCfg.CallerKey = ""
+	encCfg.EncodeLevel = extendLevelEncoder(zapcore.LowercaseLevelEncoder)
+	encCfg.EncodeDuration = zapcore.StringDurationEncoder
+	return cliEncoder{humanEncoder{zapcore.NewJSONEncoder(encCfg)}}
+}
+
+func (e cliEncoder) Clone() zapcore.Encoder {
+	return cliEncoder{e.Encoder.Clone()}
+}
+
+func (e cliEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	buf, err := e.Encoder.EncodeEntry(ent, fields)
+	if err != nil {
+		return nil, err
+	}
+	rec := ParseRecord(buf.Bytes())
+	buf.Reset()
+
+	if rec.Level != InfoLevel {
+		buf.AppendString(LevelName(rec.Level) + ": ")
+	}
+	if rec.Logger != "" {
+		buf.AppendString(rec.Logger + ": ")
+	}
+	buf.AppendString(rec.Message)
+	var b strings.Builder
+	writeRecordFields(&b, rec.Fields)
+	buf.AppendString(b.String())
+	if rec.Stack != "" {
+		buf.AppendString("\n\t" + strings.ReplaceAll(rec.Stack, "\n", "\n\t"))
+	}
+	buf.AppendByte('\n')
+	return buf, nil
+}
@@ -0,0 +1,38 @@
+// Package clientlog routes the diagnostics of client libraries through
+// package log: Sarama's StdLogger and the logging interface of go-redis.
+// Neither library is imported, the adapters satisfy their interfaces.
+// Messages mentioning errors or failures are written at warn level, the
+// others at info level, with a component field naming the library.
+package clientlog
+
+import (
+	"strings"
+
+	"github.com/olee12/log"
+	"go.uber.org/zap"
+)
+
+// ComponentKey names the library an entry comes from
+const ComponentKey = "component"
+
+// warnWords mark the library messages written at warn level
+var warnWords = []string{"error", "fail", "unable", "cannot", "can't", "refused", "timeout", "timed out"}
+
+func levelOf(msg string) log.Level {
+	lower := strings.ToLower(msg)
+	for _, word := range warnWords {
+		if strings.Contains(lower, word) {
+			return log.WarnLevel
+		}
+	}
+	return log.InfoLevel
+}
+
+// write logs the library message, le skips the adapter method and write to
+// report the caller in the library
+func write(le *log.LogEntry, component, msg string) {
+	msg = strings.TrimRight(msg, "\n")
+	if ce := le.Check(levelOf(msg), msg); ce != nil {
+		ce.Write(zap.String(ComponentKey, component))
+	}
+}
@@ -0,0 +1,34 @@
+package clientlog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/olee12/log"
+)
+
+// RedisLogger satisfies the logging interface of go-redis:
+//
+//	redis.SetLogger(clientlog.NewRedisLogger(nil))
+type RedisLogger struct {
+	le *log.LogEntry
+}
+
+// NewRedisLogger writes the messages of go-redis to le, nil uses the logger
+// of the context passed with every message, see log.FromContext
+func NewRedisLogger(le *log.LogEntry) *RedisLogger {
+	if le != nil {
+		le = le.WithCallerSkip(2)
+	}
+	return &RedisLogger{le: le}
+}
+
+func (l *RedisLogger) Printf(ctx context.Context, format string, v ...interface{}) {
+	le := l.le
+	if le == nil && ctx == nil {
+		le = log.Default().WithCallerSkip(2)
+	} else if le == nil {
+		le = log.FromContext(ctx).WithCallerSkip(2)
+	}
+	write(le, "redis", fmt.Sprintf(format, v...))
+}
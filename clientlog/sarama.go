@@ -0,0 +1,42 @@
+package clientlog
+
+import (
+	"fmt"
+
+	"github.com/olee12/log"
+)
+
+// SaramaLogger satisfies sarama.StdLogger:
+//
+//	sarama.Logger = clientlog.NewSaramaLogger(nil)
+type SaramaLogger struct {
+	le *log.LogEntry
+}
+
+// NewSaramaLogger writes Sarama's messages to le, nil uses the default logger
+// at the time of every message
+func NewSaramaLogger(le *log.LogEntry) *SaramaLogger {
+	if le != nil {
+		le = le.WithCallerSkip(2)
+	}
+	return &SaramaLogger{le: le}
+}
+
+func (l *SaramaLogger) logger() *log.LogEntry {
+	if l.le != nil {
+		return l.le
+	}
+	return log.Default().WithCallerSkip(2)
+}
+
+func (l *SaramaLogger) Print(v ...interface{}) {
+	write(l.logger(), "sarama", fmt.Sprint(v...))
+}
+
+func (l *SaramaLogger) Printf(format string, v ...interface{}) {
+	write(l.logger(), "sarama", fmt.Sprintf(format, v...))
+}
+
+func (l *SaramaLogger) Println(v ...interface{}) {
+	write(l.logger(), "sarama", fmt.Sprintln(v...))
+}
@@ -0,0 +1,81 @@
+package log
+
+import (
+	"maps"
+	"slices"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// DeriveConfig returns a copy of base with the options applied, e.g. an
+// access log config sharing the settings of the application log:
+//
+//	access := log.DeriveConfig(appConfig, func(c *log.Config) { c.Filename = "access.log" })
+//
+// Slices, maps and the nested configs are copied so the options do not
+// change base.
+func DeriveConfig(base Config, overrides ...Option) Config {
+	config := base
+	config.ExtraInfoSinks = slices.Clone(base.ExtraInfoSinks)
+	config.ExtraErrorSinks = slices.Clone(base.ExtraErrorSinks)
+	config.InfoSinkURLs = slices.Clone(base.InfoSinkURLs)
+	config.ErrorSinkURLs = slices.Clone(base.ErrorSinkURLs)
+	config.ErrorReporters = slices.Clone(base.ErrorReporters)
+	config.ZapOptions = slices.Clone(base.ZapOptions)
+	config.InitialFields = maps.Clone(base.InitialFields)
+	config.HeaderFields = maps.Clone(base.HeaderFields)
+	config.DeadLetter = clonePtr(base.DeadLetter)
+	config.Sampling = clonePtr(base.Sampling)
+	config.ErrorAlerts = clonePtr(base.ErrorAlerts)
+	config.Encryption = clonePtr(base.Encryption)
+	if base.Schema != nil {
+		schema := *base.Schema
+		schema.Required = slices.Clone(base.Schema.Required)
+		schema.Types = maps.Clone(base.Schema.Types)
+		config.Schema = &schema
+	}
+	// the experiment stats belong to the logger they were made for
+	config.stats = nil
+	for _, override := range overrides {
+		override(&config)
+	}
+	return config
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	clone := *p
+	return &clone
+}
+
+// Clone creates a new logger from the config of le changed by modify, e.g. at
+// another level or into another file. A clone writing to the same files as le
+// shares them, with their rotation and encryption, and leaves closing them to
+// le. Loggers not built from a config, like NewNop, are cloned from the zero
+// Config.
+func (le *LogEntry) Clone(modify func(*Config)) *LogEntry {
+	var base Config
+	if le.config != nil {
+		base = *le.config
+	}
+	config := DeriveConfig(base)
+	if modify != nil {
+		config = DeriveConfig(base, modify)
+	}
+	config.files = le.files
+	return NewLogEntry(config)
+}
+
+// logFiles are the info and error files opened for a logger
+type logFiles struct {
+	// dir is the resolved Directory
+	dir, filename string
+	info, err     zapcore.WriteSyncer
+}
+
+// at reports whether the files are the ones of the directory and filename
+func (f *logFiles) at(dir, filename string) bool {
+	return f != nil && f.dir == dir && f.filename == filename
+}
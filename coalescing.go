@@ -0,0 +1,128 @@
+package log
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+// coalesceMaxBytes is the size the writes held by a coalescingFile are
+// written at before the end of the window
+const coalesceMaxBytes = 64 << 10
+
+// coalescingFile holds the writes to a log file for up to Config.CoalesceWindow
+// and writes them to it in one call. The error of a write at the end of the
+// window is returned by the next Write or Sync.
+type coalescingFile struct {
+	mu     sync.Mutex
+	out    zapcore.WriteSyncer
+	window time.Duration
+	buf    []byte
+	timer  *time.Timer
+	err    error
+}
+
+func newCoalescingFile(out zapcore.WriteSyncer, window time.Duration) *coalescingFile {
+	return &coalescingFile{out: out, window: window}
+}
+
+func (c *coalescingFile) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := c.takeErr()
+	if len(c.buf) > 0 && len(c.buf)+len(p) > coalesceMaxBytes {
+		err = multierr.Append(err, c.flush())
+	}
+	c.buf = append(c.buf, p...)
+	switch {
+	case len(c.buf) >= coalesceMaxBytes:
+		err = multierr.Append(err, c.flush())
+	case c.timer == nil:
+		c.timer = time.AfterFunc(c.window, c.flushWindow)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// flushWindow writes the held writes at the end of the window
+func (c *coalescingFile) flushWindow() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timer = nil
+	if err := c.flush(); err != nil {
+		c.err = multierr.Append(c.err, err)
+	}
+}
+
+// flush writes the held writes, c.mu must be held
+func (c *coalescingFile) flush() error {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if len(c.buf) == 0 {
+		return nil
+	}
+	_, err := c.out.Write(c.buf)
+	c.buf = c.buf[:0]
+	if cap(c.buf) > 4*coalesceMaxBytes {
+		c.buf = nil
+	}
+	return err
+}
+
+// takeErr returns the error of the last window and forgets it, c.mu must be held
+func (c *coalescingFile) takeErr() error {
+	err := c.err
+	c.err = nil
+	return err
+}
+
+func (c *coalescingFile) Sync() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := multierr.Append(c.takeErr(), c.flush())
+	return multierr.Append(err, c.out.Sync())
+}
+
+// Close writes the held writes and closes the file
+func (c *coalescingFile) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := multierr.Append(c.takeErr(), c.flush())
+	if closer, ok := c.out.(io.Closer); ok {
+		err = multierr.Append(err, closer.Close())
+	}
+	return err
+}
+
+// Rotate writes the held writes to the file before rotating it
+func (c *coalescingFile) Rotate() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := c.flush()
+	if r, ok := c.out.(interface{ Rotate() error }); ok {
+		err = multierr.Append(err, r.Rotate())
+	}
+	return err
+}
+
+// Reopen writes the held writes to the file before reopening it, see
+// LogEntry.Reopen
+func (c *coalescingFile) Reopen() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := c.flush()
+	if r, ok := c.out.(interface{ Reopen() error }); ok {
+		return multierr.Append(err, r.Reopen())
+	}
+	if closer, ok := c.out.(io.Closer); ok {
+		err = multierr.Append(err, closer.Close())
+	}
+	return err
+}
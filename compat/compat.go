@@ -0,0 +1,311 @@
+// Package compat keeps the original package-level API of package log, with
+// the same signatures and routing: debug and info entries go to the info
+// output, warn and more severe entries to the error output. Every function
+// forwards to the logger of package log and reports the caller's file:line,
+// so a codebase can switch its import to compat when upgrading and then move
+// call sites to package log one at a time.
+//
+// The DefaultZapLogger variable is the DefaultZapLogger function here.
+// log.Default and log.SetDefault have no counterpart, code using them
+// directly keeps working against package log.
+package compat
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/olee12/log"
+	"go.uber.org/zap/zapcore"
+)
+
+type (
+	Level    = log.Level
+	Config   = log.Config
+	Fields   = log.Fields
+	LogEntry = log.LogEntry
+)
+
+const (
+	DebugLevel  = log.DebugLevel
+	InfoLevel   = log.InfoLevel
+	WarnLevel   = log.WarnLevel
+	ErrorLevel  = log.ErrorLevel
+	DPanicLevel = log.DPanicLevel
+	PanicLevel  = log.PanicLevel
+	FatalLevel  = log.FatalLevel
+)
+
+const DefaultFieldName = log.DefaultFieldName
+
+var DefaultRotateLoggerConfig = log.DefaultRotateLoggerConfig
+
+// skippedLogger is log.Default() skipping the forwarding frame of this package
+type skippedLogger struct {
+	base, logger *log.LogEntry
+}
+
+var skipped atomic.Pointer[skippedLogger]
+
+// defaultLogger returns the skipping logger, rebuilt when Configure or
+// SetOutput replaced the default logger
+func defaultLogger() *log.LogEntry {
+	base := log.Default()
+	if s := skipped.Load(); s != nil && s.base == base {
+		return s.logger
+	}
+	s := &skippedLogger{base: base, logger: base.WithCallerSkip(1)}
+	skipped.Store(s)
+	return s.logger
+}
+
+// DefaultZapLogger returns the default logger, what the DefaultZapLogger
+// variable held. It follows Configure and SetOutput.
+func DefaultZapLogger() *LogEntry {
+	return log.Default()
+}
+
+func SetLevel(l Level) {
+	log.SetLevel(l)
+}
+
+func GetLevel() Level {
+	return log.GetLevel()
+}
+
+func ShortTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	log.ShortTimeEncoder(t, enc)
+}
+
+func ConsoleLogTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	log.ConsoleLogTimeEncoder(t, enc)
+}
+
+func Configure(config Config) error {
+	return log.Configure(config)
+}
+
+func NewLogEntry(config Config) *LogEntry {
+	return log.NewLogEntry(config)
+}
+
+func DeclareLogger(config Config, logv func(msg string, fields ...zapcore.Field)) {
+	log.DeclareLogger(config, logv)
+}
+
+// AtLevel logs the message at a specific log level. DPanic and unknown levels
+// are logged at warn level after a warning, as they always were.
+func AtLevel(level zapcore.Level, msg string, fields ...zapcore.Field) {
+	logger := defaultLogger()
+	switch level {
+	case zapcore.DebugLevel:
+		logger.Debugv(msg, fields...)
+	case zapcore.PanicLevel:
+		logger.Panicv(msg, fields...)
+	case zapcore.ErrorLevel:
+		logger.Errorv(msg, fields...)
+	case zapcore.WarnLevel:
+		logger.Warnv(msg, fields...)
+	case zapcore.InfoLevel:
+		logger.Infov(msg, fields...)
+	case zapcore.FatalLevel:
+		logger.Fatalv(msg, fields...)
+	default:
+		logger.Warnw("Logging at unkown level", "level", level)
+		logger.Warnv(msg, fields...)
+	}
+}
+
+func Debugv(msg string, fields ...zapcore.Field) {
+	defaultLogger().Debugv(msg, fields...)
+}
+
+func Debugw(msg string, keysAndValues ...interface{}) {
+	defaultLogger().Debugw(msg, keysAndValues...)
+}
+
+func Debugf(template string, args ...interface{}) {
+	defaultLogger().Debugf(template, args...)
+}
+
+func Debugln(args ...interface{}) {
+	defaultLogger().Debugln(args...)
+}
+
+func Debug(msg string) {
+	defaultLogger().Debug(msg)
+}
+
+func DebugWith(msg string, fields Fields) {
+	defaultLogger().DebugWith(msg, fields)
+}
+
+func Infov(msg string, fields ...zapcore.Field) {
+	defaultLogger().Infov(msg, fields...)
+}
+
+func Infow(msg string, keysAndValues ...interface{}) {
+	defaultLogger().Infow(msg, keysAndValues...)
+}
+
+func Infof(template string, args ...interface{}) {
+	defaultLogger().Infof(template, args...)
+}
+
+func Infoln(args ...interface{}) {
+	defaultLogger().Infoln(args...)
+}
+
+func Info(msg string) {
+	defaultLogger().Info(msg)
+}
+
+func InfoWith(msg string, fields Fields) {
+	defaultLogger().InfoWith(msg, fields)
+}
+
+func Warnv(msg string, fields ...zapcore.Field) {
+	defaultLogger().Warnv(msg, fields...)
+}
+
+func Warnw(msg string, keysAndValues ...interface{}) {
+	defaultLogger().Warnw(msg, keysAndValues...)
+}
+
+func Warnf(template string, args ...interface{}) {
+	defaultLogger().Warnf(template, args...)
+}
+
+func Warnln(args ...interface{}) {
+	defaultLogger().Warnln(args...)
+}
+
+func Warn(msg string) {
+	defaultLogger().Warn(msg)
+}
+
+func WarnWith(msg string, fields Fields) {
+	defaultLogger().WarnWith(msg, fields)
+}
+
+func Errorv(msg string, fields ...zapcore.Field) {
+	defaultLogger().Errorv(msg, fields...)
+}
+
+func Errorw(msg string, keysAndValues ...interface{}) {
+	defaultLogger().Errorw(msg, keysAndValues...)
+}
+
+func Errorf(template string, args ...interface{}) {
+	defaultLogger().Errorf(template, args...)
+}
+
+func Errorln(args ...interface{}) {
+	defaultLogger().Errorln(args...)
+}
+
+func Error(msg string) {
+	defaultLogger().Error(msg)
+}
+
+func ErrorWith(msg string, fields Fields) {
+	defaultLogger().ErrorWith(msg, fields)
+}
+
+func Panicv(msg string, fields ...zapcore.Field) {
+	defaultLogger().Panicv(msg, fields...)
+}
+
+func Panicw(msg string, keysAndValues ...interface{}) {
+	defaultLogger().Panicw(msg, keysAndValues...)
+}
+
+func Panicf(template string, args ...interface{}) {
+	defaultLogger().Panicf(template, args...)
+}
+
+func Panicln(args ...interface{}) {
+	defaultLogger().Panicln(args...)
+}
+
+func Panic(msg string) {
+	defaultLogger().Panic(msg)
+}
+
+func PanicWith(msg string, fields Fields) {
+	defaultLogger().PanicWith(msg, fields)
+}
+
+func Fatalv(msg string, fields ...zapcore.Field) {
+	defaultLogger().Fatalv(msg, fields...)
+}
+
+func Fatalw(msg string, keysAndValues ...interface{}) {
+	defaultLogger().Fatalw(msg, keysAndValues...)
+}
+
+func Fatalf(template string, args ...interface{}) {
+	defaultLogger().Fatalf(template, args...)
+}
+
+func Fatalln(args ...interface{}) {
+	defaultLogger().Fatalln(args...)
+}
+
+func Fatal(msg string) {
+	defaultLogger().Fatal(msg)
+}
+
+func FatalWith(msg string, fields Fields) {
+	defaultLogger().FatalWith(msg, fields)
+}
+
+func DPanicv(msg string, fields ...zapcore.Field) {
+	defaultLogger().DPanicv(msg, fields...)
+}
+
+func DPanicw(msg string, keysAndValues ...interface{}) {
+	defaultLogger().DPanicw(msg, keysAndValues...)
+}
+
+func DPanicf(template string, args ...interface{}) {
+	defaultLogger().DPanicf(template, args...)
+}
+
+func DPanicln(args ...interface{}) {
+	defaultLogger().DPanicln(args...)
+}
+
+func DPanic(msg string) {
+	defaultLogger().DPanic(msg)
+}
+
+func DPanicWith(msg string, fields Fields) {
+	defaultLogger().DPanicWith(msg, fields)
+}
+
+func WithFields(fields Fields) *LogEntry {
+	return log.WithFields(fields)
+}
+
+func With(data string) *LogEntry {
+	return log.With(data)
+}
+
+func WithField(k, v string) *LogEntry {
+	return log.WithField(k, v)
+}
+
+// FromContext returns the logger stored in the context or the default logger.
+// Fields of the context (request id, trace) are stamped like log.FromContext does.
+func FromContext(ctx context.Context) *LogEntry {
+	return log.FromContext(ctx)
+}
+
+func ContextWithLogger(ctx context.Context) context.Context {
+	return log.ContextWithLogger(ctx)
+}
+
+func ContextWithCustomizedLogger(ctx context.Context, logEntry *LogEntry) context.Context {
+	return log.ContextWithCustomizedLogger(ctx, logEntry)
+}
@@ -0,0 +1,107 @@
+package compat_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	root "github.com/olee12/log"
+	log "github.com/olee12/log/compat"
+	"github.com/olee12/log/logtest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// TestPackageAPI calls the package level functions through the import name
+// of the original package, checking each reaches the default logger at its
+// level and reports the line of the test as caller. Fatal entries exit and
+// are left out.
+func TestPackageAPI(t *testing.T) {
+	tests := []struct {
+		name  string
+		call  func()
+		level zapcore.Level
+		msg   string
+	}{
+		{"Debug", func() { log.Debug("msg") }, zapcore.DebugLevel, "msg"},
+		{"Debugf", func() { log.Debugf("msg %d", 1) }, zapcore.DebugLevel, "msg 1"},
+		{"Debugln", func() { log.Debugln("msg", 1) }, zapcore.DebugLevel, "msg 1"},
+		{"Debugv", func() { log.Debugv("msg", zap.Int("n", 1)) }, zapcore.DebugLevel, "msg"},
+		{"Debugw", func() { log.Debugw("msg", "n", 1) }, zapcore.DebugLevel, "msg"},
+		{"DebugWith", func() { log.DebugWith("msg", log.Fields{"n": 1}) }, zapcore.DebugLevel, "msg"},
+		{"Info", func() { log.Info("msg") }, zapcore.InfoLevel, "msg"},
+		{"Infof", func() { log.Infof("msg %d", 1) }, zapcore.InfoLevel, "msg 1"},
+		{"Infoln", func() { log.Infoln("msg", 1) }, zapcore.InfoLevel, "msg 1"},
+		{"Infov", func() { log.Infov("msg", zap.Int("n", 1)) }, zapcore.InfoLevel, "msg"},
+		{"Infow", func() { log.Infow("msg", "n", 1) }, zapcore.InfoLevel, "msg"},
+		{"InfoWith", func() { log.InfoWith("msg", log.Fields{"n": 1}) }, zapcore.InfoLevel, "msg"},
+		{"Warn", func() { log.Warn("msg") }, zapcore.WarnLevel, "msg"},
+		{"Warnf", func() { log.Warnf("msg %d", 1) }, zapcore.WarnLevel, "msg 1"},
+		{"Warnln", func() { log.Warnln("msg", 1) }, zapcore.WarnLevel, "msg 1"},
+		{"Warnv", func() { log.Warnv("msg", zap.Int("n", 1)) }, zapcore.WarnLevel, "msg"},
+		{"Warnw", func() { log.Warnw("msg", "n", 1) }, zapcore.WarnLevel, "msg"},
+		{"WarnWith", func() { log.WarnWith("msg", log.Fields{"n": 1}) }, zapcore.WarnLevel, "msg"},
+		{"Error", func() { log.Error("msg") }, zapcore.ErrorLevel, "msg"},
+		{"Errorf", func() { log.Errorf("msg %d", 1) }, zapcore.ErrorLevel, "msg 1"},
+		{"Errorln", func() { log.Errorln("msg", 1) }, zapcore.ErrorLevel, "msg 1"},
+		{"Errorv", func() { log.Errorv("msg", zap.Int("n", 1)) }, zapcore.ErrorLevel, "msg"},
+		{"Errorw", func() { log.Errorw("msg", "n", 1) }, zapcore.ErrorLevel, "msg"},
+		{"ErrorWith", func() { log.ErrorWith("msg", log.Fields{"n": 1}) }, zapcore.ErrorLevel, "msg"},
+		{"DPanic", func() { log.DPanic("msg") }, zapcore.DPanicLevel, "msg"},
+		{"DPanicf", func() { log.DPanicf("msg %d", 1) }, zapcore.DPanicLevel, "msg 1"},
+		{"DPanicln", func() { log.DPanicln("msg", 1) }, zapcore.DPanicLevel, "msg 1"},
+		{"DPanicv", func() { log.DPanicv("msg", zap.Int("n", 1)) }, zapcore.DPanicLevel, "msg"},
+		{"DPanicw", func() { log.DPanicw("msg", "n", 1) }, zapcore.DPanicLevel, "msg"},
+		{"DPanicWith", func() { log.DPanicWith("msg", log.Fields{"n": 1}) }, zapcore.DPanicLevel, "msg"},
+		{"Panic", func() { log.Panic("msg") }, zapcore.PanicLevel, "msg"},
+		{"Panicf", func() { log.Panicf("msg %d", 1) }, zapcore.PanicLevel, "msg 1"},
+		{"Panicln", func() { log.Panicln("msg", 1) }, zapcore.PanicLevel, "msg 1"},
+		{"Panicv", func() { log.Panicv("msg", zap.Int("n", 1)) }, zapcore.PanicLevel, "msg"},
+		{"Panicw", func() { log.Panicw("msg", "n", 1) }, zapcore.PanicLevel, "msg"},
+		{"PanicWith", func() { log.PanicWith("msg", log.Fields{"n": 1}) }, zapcore.PanicLevel, "msg"},
+		{"AtLevel", func() { log.AtLevel(zapcore.WarnLevel, "msg") }, zapcore.WarnLevel, "msg"},
+		{"WithFields", func() { log.WithFields(log.Fields{"n": 1}).Info("msg") }, zapcore.InfoLevel, "msg"},
+		{"WithField", func() { log.WithField("n", "1").Info("msg") }, zapcore.InfoLevel, "msg"},
+		{"With", func() { log.With("data").Info("msg") }, zapcore.InfoLevel, "msg"},
+		{"DefaultZapLogger", func() { log.DefaultZapLogger().Info("msg") }, zapcore.InfoLevel, "msg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, logs := logtest.NewTestLogger(t)
+			previous := root.Default()
+			root.SetDefault(logger)
+			t.Cleanup(func() { root.SetDefault(previous) })
+
+			func() {
+				defer func() {
+					if r := recover(); r != nil && tt.level != zapcore.PanicLevel {
+						t.Errorf("unexpected panic: %v", r)
+					}
+				}()
+				tt.call()
+			}()
+
+			entries := logs.All()
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			got := entries[0]
+			if got.Level != tt.level || got.Message != tt.msg {
+				t.Errorf("got %s %q, want %s %q", got.Level, got.Message, tt.level, tt.msg)
+			}
+			if file := filepath.Base(got.Caller.File); file != "compat_test.go" {
+				t.Errorf("got caller %s, want compat_test.go", got.Caller.File)
+			}
+		})
+	}
+}
+
+func TestDefaultZapLogger(t *testing.T) {
+	logger, _ := logtest.NewTestLogger(t)
+	previous := root.Default()
+	root.SetDefault(logger)
+	t.Cleanup(func() { root.SetDefault(previous) })
+
+	if log.DefaultZapLogger() != logger {
+		t.Error("DefaultZapLogger does not return the default logger")
+	}
+}
@@ -0,0 +1,42 @@
+package log
+
+import "encoding/json"
+
+// UnmarshalJSON reads the config from a JSON file, Level, ConsoleLevel and
+// FileLevel accept the names of ParseLevel as well as numbers
+func (c *Config) UnmarshalJSON(data []byte) error {
+	type plain Config
+	aux := struct {
+		*plain
+		Level        *jsonLevel
+		ConsoleLevel *jsonLevel
+		FileLevel    *jsonLevel
+	}{plain: (*plain)(c), Level: (*jsonLevel)(&c.Level)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	c.ConsoleLevel = (*Level)(aux.ConsoleLevel)
+	c.FileLevel = (*Level)(aux.FileLevel)
+	return nil
+}
+
+// jsonLevel decodes a level given as a name or a number
+type jsonLevel Level
+
+func (l *jsonLevel) UnmarshalJSON(data []byte) error {
+	var n int8
+	if err := json.Unmarshal(data, &n); err == nil {
+		*l = jsonLevel(n)
+		return nil
+	}
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	level, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	*l = jsonLevel(level)
+	return nil
+}
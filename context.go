@@ -0,0 +1,216 @@
+package log
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// CtxErrKey holds the error of a done context in the entries of the Ctx methods
+	CtxErrKey = "ctx_err"
+	// CtxCauseKey holds the cause of a context canceled with one
+	CtxCauseKey = "ctx_cause"
+	// DeadlineRemainingKey holds the time left until the deadline of the
+	// context when it is near, negative once it passed
+	DeadlineRemainingKey = "deadline_remaining"
+)
+
+// DefaultNearDeadline is used when Config.NearDeadline is zero
+const DefaultNearDeadline = time.Second
+
+// ContextExtractor turns application values stored in a context (auth
+// principal, shard id, ...) into fields
+type ContextExtractor func(ctx context.Context) Fields
+
+var (
+	extractorsMu      sync.RWMutex
+	contextExtractors []ContextExtractor
+)
+
+// RegisterContextExtractor adds an extractor whose fields are applied by
+// FromContext and the *Ctx methods
+func RegisterContextExtractor(extractor ContextExtractor) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	contextExtractors = append(contextExtractors, extractor)
+}
+
+func extractContextFields(ctx context.Context, sorted bool) []zapcore.Field {
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+
+	var fields []zapcore.Field
+	for _, extractor := range contextExtractors {
+		fields = appendFields(fields, extractor(ctx), sorted)
+	}
+	return fields
+}
+
+// contextFields returns the fields the context contributes to le's records
+func (le *LogEntry) contextFields(ctx context.Context) []zapcore.Field {
+	var fields []zapcore.Field
+	if id, ok := RequestIDFromContext(ctx); ok {
+		fields = append(fields, zap.String(RequestIDKey, id))
+	}
+	if le.traceCorrelation {
+		if tc, ok := contextTrace(ctx); ok {
+			fields = append(fields, traceFields(tc)...)
+		}
+	}
+	return append(fields, extractContextFields(ctx, le.sortFields)...)
+}
+
+// fromContext returns a child logger carrying the context fields, le when
+// it carries them already, e.g. when it was derived from FromContext of the
+// same context and stored in it
+func (le *LogEntry) fromContext(ctx context.Context) *LogEntry {
+	fields := le.newContextFields(le.contextFields(ctx))
+	if len(fields) == 0 {
+		return le
+	}
+	l := le.with(fields)
+	l.ctxFields = append(le.ctxFields[:len(le.ctxFields):len(le.ctxFields)], fields...)
+	return l
+}
+
+// newContextFields returns the fields le does not carry already
+func (le *LogEntry) newContextFields(fields []zapcore.Field) []zapcore.Field {
+	if len(le.ctxFields) == 0 {
+		return fields
+	}
+	kept := fields[:0]
+	for _, f := range fields {
+		if !le.carries(f) {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+func (le *LogEntry) carries(f zapcore.Field) bool {
+	for _, c := range le.ctxFields {
+		if sameField(c, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameField is zapcore.Field.Equals, which panics on Stringers which are not
+// comparable
+func sameField(a, b zapcore.Field) bool {
+	if a.Key != b.Key || a.Type != b.Type {
+		return false
+	}
+	if a.Type == zapcore.StringerType {
+		return reflect.DeepEqual(a.Interface, b.Interface)
+	}
+	return a.Equals(b)
+}
+
+// FromContext returns a child logger carrying the fields of the context,
+// what the package FromContext does with the logger of the context
+func (le *LogEntry) FromContext(ctx context.Context) *LogEntry {
+	return le.fromContext(ctx)
+}
+
+// contextState returns the fields with the state of the context added: its
+// error once it is done and the time left when its deadline is near
+func (le *LogEntry) contextState(ctx context.Context, fields []zapcore.Field) []zapcore.Field {
+	if ctx.Done() == nil {
+		return fields
+	}
+	// the fields are the variadic arguments of the caller, don't append in place
+	fields = fields[:len(fields):len(fields)]
+	if err := ctx.Err(); err != nil {
+		fields = append(fields, zap.String(CtxErrKey, err.Error()))
+		if cause := context.Cause(ctx); cause != nil && cause != err {
+			fields = append(fields, zap.String(CtxCauseKey, cause.Error()))
+		}
+	}
+	near := le.nearDeadline
+	if near == 0 {
+		near = DefaultNearDeadline
+	}
+	if deadline, ok := ctx.Deadline(); ok && near > 0 {
+		if remaining := time.Until(deadline); remaining < near {
+			fields = append(fields, zap.Duration(DeadlineRemainingKey, remaining))
+		}
+	}
+	return fields
+}
+
+func DebugCtx(ctx context.Context, msg string, fields ...zapcore.Field) {
+	l := FromContext(ctx)
+	l.infoLogger.Debug(msg, l.contextState(ctx, fields)...)
+}
+
+func InfoCtx(ctx context.Context, msg string, fields ...zapcore.Field) {
+	l := FromContext(ctx)
+	l.infoLogger.Info(msg, l.contextState(ctx, fields)...)
+}
+
+func WarnCtx(ctx context.Context, msg string, fields ...zapcore.Field) {
+	l := FromContext(ctx)
+	l.errorLogger.Warn(msg, l.contextState(ctx, fields)...)
+}
+
+func ErrorCtx(ctx context.Context, msg string, fields ...zapcore.Field) {
+	l := FromContext(ctx)
+	l.errorLogger.Error(msg, l.contextState(ctx, fields)...)
+}
+
+func DPanicCtx(ctx context.Context, msg string, fields ...zapcore.Field) {
+	l := FromContext(ctx)
+	l.errorLogger.DPanic(msg, l.contextState(ctx, fields)...)
+}
+
+func PanicCtx(ctx context.Context, msg string, fields ...zapcore.Field) {
+	l := FromContext(ctx)
+	l.errorLogger.Panic(msg, l.contextState(ctx, fields)...)
+}
+
+func FatalCtx(ctx context.Context, msg string, fields ...zapcore.Field) {
+	l := FromContext(ctx)
+	l.errorLogger.Fatal(msg, l.contextState(ctx, fields)...)
+}
+
+func (le *LogEntry) DebugCtx(ctx context.Context, msg string, fields ...zapcore.Field) {
+	l := le.fromContext(ctx)
+	l.infoLogger.Debug(msg, l.contextState(ctx, fields)...)
+}
+
+func (le *LogEntry) InfoCtx(ctx context.Context, msg string, fields ...zapcore.Field) {
+	l := le.fromContext(ctx)
+	l.infoLogger.Info(msg, l.contextState(ctx, fields)...)
+}
+
+func (le *LogEntry) WarnCtx(ctx context.Context, msg string, fields ...zapcore.Field) {
+	l := le.fromContext(ctx)
+	l.errorLogger.Warn(msg, l.contextState(ctx, fields)...)
+}
+
+func (le *LogEntry) ErrorCtx(ctx context.Context, msg string, fields ...zapcore.Field) {
+	l := le.fromContext(ctx)
+	l.errorLogger.Error(msg, l.contextState(ctx, fields)...)
+}
+
+func (le *LogEntry) DPanicCtx(ctx context.Context, msg string, fields ...zapcore.Field) {
+	l := le.fromContext(ctx)
+	l.errorLogger.DPanic(msg, l.contextState(ctx, fields)...)
+}
+
+func (le *LogEntry) PanicCtx(ctx context.Context, msg string, fields ...zapcore.Field) {
+	l := le.fromContext(ctx)
+	l.errorLogger.Panic(msg, l.contextState(ctx, fields)...)
+}
+
+func (le *LogEntry) FatalCtx(ctx context.Context, msg string, fields ...zapcore.Field) {
+	l := le.fromContext(ctx)
+	l.errorLogger.Fatal(msg, l.contextState(ctx, fields)...)
+}
@@ -0,0 +1,87 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+// keyCounts counts the top level keys of each JSON line of out
+func keyCounts(t *testing.T, out string) []map[string]int {
+	t.Helper()
+	var lines []map[string]int
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		dec := json.NewDecoder(strings.NewReader(line))
+		if _, err := dec.Token(); err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+		counts := map[string]int{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				t.Fatalf("%q: %v", line, err)
+			}
+			counts[key.(string)]++
+			var value json.RawMessage
+			if err := dec.Decode(&value); err != nil {
+				t.Fatalf("%q: %v", line, err)
+			}
+		}
+		lines = append(lines, counts)
+	}
+	return lines
+}
+
+func TestFromContextRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(ctx context.Context)
+	}{
+		{
+			name: "logger derived from the context",
+			log: func(ctx context.Context) {
+				ctx = ContextWithCustomizedLogger(ctx, FromContext(ctx).WithFields(Fields{"user": 42}))
+				FromContext(ctx).Info("entry")
+			},
+		},
+		{
+			name: "twice derived",
+			log: func(ctx context.Context) {
+				ctx = ContextWithCustomizedLogger(ctx, FromContext(ctx).WithFields(Fields{"user": 42}))
+				ctx = ContextWithCustomizedLogger(ctx, FromContext(ctx))
+				FromContext(ctx).InfoCtx(ctx, "entry")
+			},
+		},
+		{
+			name: "span logger",
+			log: func(ctx context.Context) {
+				ctx, sl := StartSpanLogger(ctx)
+				FromContext(ctx).Info("entry")
+				_ = sl.End(io.EOF)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			le := NewWithWriter(Config{Level: InfoLevel, EncodeLogsAsJson: true, TraceCorrelation: true}, &buf)
+			ctx := ContextWithCustomizedLogger(context.Background(), le)
+			ctx = ContextWithRequestID(ctx, "r1")
+			ctx = ContextWithTrace(ctx, TraceContext{TraceID: "t1", SpanID: "s1", Sampled: true})
+			tt.log(ctx)
+
+			lines := keyCounts(t, buf.String())
+			if len(lines) != 1 {
+				t.Fatalf("%d entries, want 1:\n%s", len(lines), buf.String())
+			}
+			for _, key := range []string{RequestIDKey, TraceIDKey, SpanIDKey, SampledKey} {
+				if n := lines[0][key]; n != 1 {
+					t.Errorf("%s written %d times, want once:\n%s", key, n, buf.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package log
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// CrashDumpMessage is the message of the entry preceding a crash dump
+const CrashDumpMessage = "recent log entries before crash"
+
+// crash keeps the recent entries of every level for DumpRecent once a logger
+// is configured with Config.CrashBuffer
+var crash atomic.Pointer[lineRing]
+
+// DumpRecent writes the entries kept for Config.CrashBuffer to w, oldest first
+func DumpRecent(w io.Writer) error {
+	ring := crash.Load()
+	if ring == nil {
+		return nil
+	}
+	lines, _, _ := ring.last(len(ring.lines))
+	for _, line := range lines {
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// crashMarker makes the crash recorder dump the ring after writing the entry,
+// for panics which recover.go logs at error level before panicking again
+type crashMarker struct{}
+
+var crashField = zapcore.Field{Type: zapcore.SkipType, Interface: crashMarker{}}
+
+// crashCore records every entry into the crash ring, including the entries
+// the logger does not write because of their level, so it reports every
+// level as enabled
+type crashCore struct {
+	zapcore.Core
+	recorder *crashRecorder
+}
+
+func newCrashCore(core zapcore.Core, size int, enc zapcore.Encoder, out zapcore.WriteSyncer) zapcore.Core {
+	if size <= 0 {
+		return core
+	}
+	ring := installRing(&crash, size)
+	return &crashCore{Core: core, recorder: &crashRecorder{
+		Core: zapcore.NewCore(enc.Clone(), ringWriter{ring}, allLevels),
+		ring: ring,
+		enc:  enc,
+		out:  out,
+	}}
+}
+
+func (c *crashCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *crashCore) With(fields []zapcore.Field) zapcore.Core {
+	return &crashCore{Core: c.Core.With(fields), recorder: c.recorder.with(fields)}
+}
+
+func (c *crashCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return c.Core.Check(ent, ce).AddCore(ent, c.recorder)
+}
+
+func (c *crashCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	err := c.Core.Write(ent, fields)
+	if recErr := c.recorder.Write(ent, fields); err == nil {
+		err = recErr
+	}
+	return err
+}
+
+// crashRecorder encodes the entries into the ring and dumps it to out on
+// entries at panic level and above
+type crashRecorder struct {
+	zapcore.Core
+	ring *lineRing
+	enc  zapcore.Encoder
+	out  zapcore.WriteSyncer
+}
+
+func (r *crashRecorder) with(fields []zapcore.Field) *crashRecorder {
+	clone := *r
+	clone.Core = r.Core.With(fields)
+	return &clone
+}
+
+func (r *crashRecorder) With(fields []zapcore.Field) zapcore.Core {
+	return r.with(fields)
+}
+
+func (r *crashRecorder) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	crashing := ent.Level >= PanicLevel
+	for _, f := range fields {
+		if _, ok := f.Interface.(crashMarker); ok && f.Type == zapcore.SkipType {
+			crashing = true
+		}
+	}
+	if err := r.Core.Write(ent, fields); err != nil || !crashing {
+		return err
+	}
+	return r.dump(ent)
+}
+
+// dump writes the ring to out after an entry announcing it
+func (r *crashRecorder) dump(ent zapcore.Entry) error {
+	lines, _, _ := r.ring.last(len(r.ring.lines))
+	header, err := r.enc.EncodeEntry(zapcore.Entry{
+		Level:   ent.Level,
+		Time:    time.Now(),
+		Message: CrashDumpMessage,
+	}, []zapcore.Field{zap.Int("entries", len(lines))})
+	if err != nil {
+		return err
+	}
+	_, err = r.out.Write(header.Bytes())
+	header.Free()
+	for _, line := range lines {
+		if err != nil {
+			break
+		}
+		_, err = r.out.Write(line)
+	}
+	// like zap after panic and fatal entries, sync errors of consoles are ignored
+	_ = r.out.Sync()
+	return err
+}
+
+// ringWriter adds every encoded entry to the ring
+type ringWriter struct {
+	ring *lineRing
+}
+
+func (w ringWriter) Write(p []byte) (int, error) {
+	w.ring.add(append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func (ringWriter) Sync() error {
+	return nil
+}
@@ -0,0 +1,147 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// RejectionError is returned by sinks which permanently refuse a record,
+// e.g. because it violates the schema or size limits of the remote side.
+// Retrying such a record is pointless, it is written to the dead-letter file.
+type RejectionError struct {
+	Reason string
+}
+
+func (e *RejectionError) Error() string {
+	return "record rejected: " + e.Reason
+}
+
+// NewRejectionError returns a RejectionError for the reason
+func NewRejectionError(format string, args ...interface{}) error {
+	return &RejectionError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// IsRejection reports whether err is or wraps a RejectionError
+func IsRejection(err error) (reason string, ok bool) {
+	var rejection *RejectionError
+	if errors.As(err, &rejection) {
+		return rejection.Reason, true
+	}
+	return "", false
+}
+
+// DeadLetterConfig configures the local file receiving rejected records
+type DeadLetterConfig struct {
+	// Directory of the dead-letter file
+	Directory string
+	// Filename defaults to deadletter.ndjson
+	Filename string
+	// MaxSize is the size in megabytes before the file is rotated
+	MaxSize int
+	// MaxBackups the max number of rolled files to keep
+	MaxBackups int
+	// MaxAge the max age in days to keep a file
+	MaxAge int
+}
+
+// DeadLetterWriter writes rejected records with the rejection reason as
+// newline delimited JSON
+type DeadLetterWriter struct {
+	mu   sync.Mutex
+	file *lumberjack.Logger
+}
+
+// NewDeadLetterWriter returns a writer for the config
+func NewDeadLetterWriter(config DeadLetterConfig) (*DeadLetterWriter, error) {
+	filename := config.Filename
+	if filename == "" {
+		filename = "deadletter.ndjson"
+	}
+	if err := os.MkdirAll(config.Directory, 0744); err != nil {
+		return nil, err
+	}
+	return &DeadLetterWriter{file: &lumberjack.Logger{
+		Filename:   longPath(filepath.Join(config.Directory, filename)),
+		MaxSize:    config.MaxSize,
+		MaxAge:     config.MaxAge,
+		MaxBackups: config.MaxBackups,
+		LocalTime:  true,
+	}}, nil
+}
+
+type deadLetterRecord struct {
+	Time   string          `json:"@t"`
+	Sink   string          `json:"sink"`
+	Reason string          `json:"reason"`
+	Record json.RawMessage `json:"record,omitempty"`
+	Raw    string          `json:"raw,omitempty"`
+}
+
+// WriteRejected records that sink rejected record for reason. The record is
+// embedded as JSON when it is valid JSON and as a string otherwise.
+func (w *DeadLetterWriter) WriteRejected(sink string, record []byte, reason string) error {
+	rec := deadLetterRecord{
+		Time:   time.Now().Format(time.RFC3339Nano),
+		Sink:   sink,
+		Reason: reason,
+	}
+	trimmed := trimNewline(record)
+	if json.Valid(trimmed) {
+		rec.Record = trimmed
+	} else {
+		rec.Raw = string(trimmed)
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err = w.file.Write(append(line, '\n'))
+	return err
+}
+
+func (w *DeadLetterWriter) Close() error {
+	return w.file.Close()
+}
+
+func trimNewline(p []byte) []byte {
+	for len(p) > 0 && (p[len(p)-1] == '\n' || p[len(p)-1] == '\r') {
+		p = p[:len(p)-1]
+	}
+	return p
+}
+
+// deadLetterSink writes the records rejected by the sink to the dead-letter file
+type deadLetterSink struct {
+	zapcore.WriteSyncer
+	name       string
+	deadLetter *DeadLetterWriter
+}
+
+// WithDeadLetter wraps the sink so records it rejects with a RejectionError
+// are written to the dead-letter writer instead of being lost. name identifies
+// the sink in the dead-letter records.
+func WithDeadLetter(sink zapcore.WriteSyncer, name string, deadLetter *DeadLetterWriter) zapcore.WriteSyncer {
+	return &deadLetterSink{WriteSyncer: sink, name: name, deadLetter: deadLetter}
+}
+
+func (s *deadLetterSink) Write(p []byte) (int, error) {
+	n, err := s.WriteSyncer.Write(p)
+	if reason, ok := IsRejection(err); ok {
+		if dlErr := s.deadLetter.WriteRejected(s.name, p, reason); dlErr != nil {
+			return n, dlErr
+		}
+		return len(p), nil
+	}
+	return n, err
+}
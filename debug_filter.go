@@ -0,0 +1,241 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// allLevels enables every level, the level is applied by levelCore
+var allLevels = zap.LevelEnablerFunc(func(zapcore.Level) bool { return true })
+
+var (
+	debugFiltersMu sync.RWMutex
+	debugFilters   = map[string]string{}
+	// debugFiltersActive avoids taking the lock in the hot path when no filter is set
+	debugFiltersActive atomic.Bool
+	// debugFiltersGen changes with every change of the filters
+	debugFiltersGen atomic.Uint64
+)
+
+// SetDebugFilter makes the loggers carrying the field with the value write
+// entries down to debug level whatever the configured level, e.g.
+// SetDebugFilter("user_id", 123) for targeted debugging of a single customer.
+// The field has to be bound with With, WithFields or FromContext, also before
+// the filter is set; fields passed at the call site are not matched, so
+// entries below the level of other loggers cost nothing.
+func SetDebugFilter(field string, value interface{}) {
+	debugFiltersMu.Lock()
+	defer debugFiltersMu.Unlock()
+	debugFilters[field] = fmt.Sprint(value)
+	debugFiltersGen.Add(1)
+	debugFiltersActive.Store(true)
+}
+
+// ClearDebugFilter removes the filter on the field
+func ClearDebugFilter(field string) {
+	debugFiltersMu.Lock()
+	defer debugFiltersMu.Unlock()
+	delete(debugFilters, field)
+	debugFiltersGen.Add(1)
+	debugFiltersActive.Store(len(debugFilters) > 0)
+}
+
+// ClearDebugFilters removes every filter
+func ClearDebugFilters() {
+	debugFiltersMu.Lock()
+	defer debugFiltersMu.Unlock()
+	debugFilters = map[string]string{}
+	debugFiltersGen.Add(1)
+	debugFiltersActive.Store(false)
+}
+
+// matchesDebugFilter reports whether one of the fields matches a filter
+func matchesDebugFilter(fields []zapcore.Field) bool {
+	if !debugFiltersActive.Load() {
+		return false
+	}
+	debugFiltersMu.RLock()
+	defer debugFiltersMu.RUnlock()
+
+	for _, f := range fields {
+		want, ok := debugFilters[f.Key]
+		if !ok {
+			continue
+		}
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		if fmt.Sprint(enc.Fields[f.Key]) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// levelCore applies the logger level. Loggers whose bound fields match a
+// debug filter, or which belong to a sampled trace with sampledTraces, are
+// enabled below it. The filters are matched when an entry is checked, so
+// filters set after With apply to the loggers derived before. With a floor,
+// the lowest level of the outputs having their own, entries below the level
+// and down to the floor are written marked for these outputs only. All of
+// them go through the Check of the wrapped cores, sampling included.
+type levelCore struct {
+	zapcore.Core
+	level         zap.AtomicLevel
+	floor         *Level
+	sampledTraces bool
+	// sampled is set when the bound fields carry a sampled trace
+	sampled bool
+	// fields are the bound fields, matched against the debug filters
+	fields []zapcore.Field
+	// filtered caches the match of fields for a generation of the filters,
+	// as the generation shifted left with the result in the lowest bit
+	filtered atomic.Uint64
+}
+
+func newLevelCore(core zapcore.Core, level zap.AtomicLevel, floor *Level, sampledTraces bool) zapcore.Core {
+	return &levelCore{Core: core, level: level, floor: floor, sampledTraces: sampledTraces}
+}
+
+func (c *levelCore) Level() zapcore.Level {
+	return c.level.Level()
+}
+
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return c.enabled(lvl) || c.aboveFloor(lvl)
+}
+
+// enabled reports whether entries at lvl are written to every output
+func (c *levelCore) enabled(lvl zapcore.Level) bool {
+	return c.level.Enabled(lvl) || c.sampled || lvl >= DebugLevel && c.matchesFilter()
+}
+
+func (c *levelCore) aboveFloor(lvl zapcore.Level) bool {
+	return c.floor != nil && lvl >= thresholdLevel(*c.floor)
+}
+
+// matchesFilter reports whether the bound fields match a debug filter
+func (c *levelCore) matchesFilter() bool {
+	if len(c.fields) == 0 || !debugFiltersActive.Load() {
+		return false
+	}
+	gen := debugFiltersGen.Load()
+	if cached := c.filtered.Load(); cached>>1 == gen {
+		return cached&1 == 1
+	}
+	matched := matchesDebugFilter(c.fields)
+	cached := gen << 1
+	if matched {
+		cached |= 1
+	}
+	c.filtered.Store(cached)
+	return matched
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{
+		Core:          c.Core.With(fields),
+		level:         c.level,
+		floor:         c.floor,
+		sampledTraces: c.sampledTraces,
+		sampled:       c.sampled || c.sampledTraces && hasSampledTrace(fields),
+		fields:        append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.enabled(ent.Level) {
+		return c.Core.Check(ent, ce)
+	}
+	if c.aboveFloor(ent.Level) {
+		return addChecked(ent, ce, c.Core, belowLevelMarker{})
+	}
+	return ce
+}
+
+// Write is reached for the entries written directly by the wrapping cores
+func (c *levelCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if c.enabled(ent.Level) {
+		return c.Core.Write(ent, fields)
+	}
+	if c.aboveFloor(ent.Level) {
+		return c.Core.Write(ent, belowLevelMarker{}.addFields(fields))
+	}
+	return nil
+}
+
+// checkedWriter writes the entries of a checkedCore, adding fields to them
+// or holding them
+type checkedWriter interface {
+	writeChecked(ce *zapcore.CheckedEntry, fields []zapcore.Field)
+}
+
+// checkedCore writes an entry which the wrapped cores accepted in their
+// Check, for the cores adding fields at Write or holding entries without
+// skipping these checks, e.g. the one of sampling. They are pooled, an entry
+// is written once.
+type checkedCore struct {
+	ce *zapcore.CheckedEntry
+	// msg is the message before the wrapped cores changed it
+	msg  string
+	core zapcore.Core
+	w    checkedWriter
+}
+
+var checkedCores = sync.Pool{New: func() any { return &checkedCore{} }}
+
+// addChecked checks the entry with core and adds it to ce when core accepts
+// it, to be written by w
+func addChecked(ent zapcore.Entry, ce *zapcore.CheckedEntry, core zapcore.Core, w checkedWriter) *zapcore.CheckedEntry {
+	checked := core.Check(ent, nil)
+	if checked == nil {
+		return ce
+	}
+	c := checkedCores.Get().(*checkedCore)
+	c.ce, c.msg, c.core, c.w = checked, ent.Message, core, w
+	return ce.AddCore(ent, c)
+}
+
+func (c *checkedCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *checkedCore) With([]zapcore.Field) zapcore.Core {
+	return c
+}
+
+func (c *checkedCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+// Write writes ent, which has the caller and stack zap adds after Check,
+// keeping the message if a wrapped core changed it
+func (c *checkedCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if c.ce.Entry.Message != c.msg {
+		ent.Message = c.ce.Entry.Message
+	}
+	c.ce.Entry = ent
+	ce, w := c.ce, c.w
+	*c = checkedCore{}
+	checkedCores.Put(c)
+	w.writeChecked(ce, fields)
+	return nil
+}
+
+func (c *checkedCore) Sync() error {
+	return c.core.Sync()
+}
+
+// belowLevelMarker adds the marker of the entries below the logger level
+type belowLevelMarker struct{}
+
+func (belowLevelMarker) addFields(fields []zapcore.Field) []zapcore.Field {
+	return append(fields[:len(fields):len(fields)], belowLevelField)
+}
+
+func (m belowLevelMarker) writeChecked(ce *zapcore.CheckedEntry, fields []zapcore.Field) {
+	ce.Write(m.addFields(fields)...)
+}
@@ -0,0 +1,43 @@
+package log
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DeprecatedKey is the field naming the deprecated feature in the entries of Deprecated
+const DeprecatedKey = "deprecated"
+
+// DeprecatedMessage is the message of the entries of Deprecated
+const DeprecatedMessage = "deprecated feature used"
+
+// deprecationsLogged holds the features Deprecated logged with once
+var deprecationsLogged sync.Map
+
+// Deprecated logs at warn level that the feature is deprecated, for the
+// libraries embedding this package. It is called by the deprecated function,
+// the caller reported is the code calling that function. With once the
+// feature is logged only the first time, the same feature logged without once
+// is not suppressed.
+func Deprecated(feature string, once bool, fields ...zapcore.Field) {
+	Default().deprecated(feature, once, fields)
+}
+
+// Deprecated logs the deprecation of the feature to le, see Deprecated
+func (le *LogEntry) Deprecated(feature string, once bool, fields ...zapcore.Field) {
+	le.deprecated(feature, once, fields)
+}
+
+func (le *LogEntry) deprecated(feature string, once bool, fields []zapcore.Field) {
+	if once {
+		if _, logged := deprecationsLogged.LoadOrStore(feature, struct{}{}); logged {
+			return
+		}
+	}
+	// skips deprecated, Deprecated and the deprecated function
+	if ce := le.errorLogger.WithOptions(zap.AddCallerSkip(3)).Check(WarnLevel, DeprecatedMessage); ce != nil {
+		ce.Write(append([]zapcore.Field{zap.String(DeprecatedKey, feature)}, fields...)...)
+	}
+}
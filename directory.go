@@ -0,0 +1,45 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// resolveDirectory makes the log directory absolute. Relative directories are
+// resolved against Config.BaseDir, or the directory of the executable when
+// BaseDir is empty, so the result doesn't depend on the working directory the
+// process was started from.
+func resolveDirectory(config Config) (string, error) {
+	base := config.BaseDir
+	if base == "" || !filepath.IsAbs(base) {
+		base = filepath.Join(executableDir(), base)
+	}
+	base = filepath.Clean(base)
+
+	dir := config.Directory
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(base, dir)
+	}
+	dir = filepath.Clean(dir)
+
+	if config.ConfineToBaseDir {
+		rel, err := filepath.Rel(base, dir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("log directory %q escapes base directory %q", dir, base)
+		}
+	}
+	return dir, nil
+}
+
+func executableDir() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return "."
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	return filepath.Dir(exe)
+}
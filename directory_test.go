@@ -0,0 +1,97 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveDirectory(t *testing.T) {
+	base := t.TempDir()
+	tests := []struct {
+		name    string
+		config  Config
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "relative to the base",
+			config: Config{BaseDir: base, Directory: "logs"},
+			want:   filepath.Join(base, "logs"),
+		},
+		{
+			name:   "absolute",
+			config: Config{BaseDir: base, Directory: filepath.Join(base, "abs")},
+			want:   filepath.Join(base, "abs"),
+		},
+		{
+			name:   "relative to the executable",
+			config: Config{Directory: "logs"},
+			want:   filepath.Join(executableDir(), "logs"),
+		},
+		{
+			name:   "empty is the base",
+			config: Config{BaseDir: base},
+			want:   base,
+		},
+		{
+			name:    "escaping a confined base",
+			config:  Config{BaseDir: base, Directory: "../logs", ConfineToBaseDir: true},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveDirectory(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolved %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeadLetterDirectory(t *testing.T) {
+	base := t.TempDir()
+	tests := []struct {
+		name    string
+		config  Config
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "relative to the base",
+			config: Config{BaseDir: base, DeadLetter: &DeadLetterConfig{Directory: "deadletter"}},
+			want:   filepath.Join(base, "deadletter"),
+		},
+		{
+			name:   "absolute",
+			config: Config{BaseDir: base, DeadLetter: &DeadLetterConfig{Directory: filepath.Join(base, "abs")}},
+			want:   filepath.Join(base, "abs"),
+		},
+		{
+			name:    "escaping a confined base",
+			config:  Config{BaseDir: base, ConfineToBaseDir: true, DeadLetter: &DeadLetterConfig{Directory: "../deadletter"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, closers, err := openExtraSinks(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			for _, c := range closers {
+				c.Close()
+			}
+			if tt.wantErr {
+				return
+			}
+			if info, err := os.Stat(tt.want); err != nil || !info.IsDir() {
+				t.Errorf("dead-letter directory %s not created: %v", tt.want, err)
+			}
+		})
+	}
+}
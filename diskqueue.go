@@ -0,0 +1,517 @@
+package log
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+// DiskQueueConfig configures WithDiskQueue
+type DiskQueueConfig struct {
+	// Directory holds the segment files and the cursor of the queue, one
+	// directory per queue
+	Directory string
+	// SegmentSize is the size in bytes a new segment file is started at,
+	// 8 MiB by default
+	SegmentSize int64
+	// MaxSize is the size in bytes of the queue on disk, 256 MiB by default.
+	// Beyond it the oldest segments are removed, their unsent entries lost.
+	MaxSize int64
+	// RetryInterval is the time between the attempts to write to a failing
+	// sink, 1s by default
+	RetryInterval time.Duration
+	// Fsync syncs the segment file after every write, so the entries survive
+	// a crash of the machine and not only of the process
+	Fsync bool
+}
+
+// DiskQueueStats is a snapshot of the state of a DiskQueue
+type DiskQueueStats struct {
+	// Size is the number of bytes of the segments on disk
+	Size int64
+	// Segments is the number of segment files
+	Segments int
+	// Sent is the number of entries written to the sink
+	Sent int64
+	// DroppedBytes is the size of the unsent entries removed for MaxSize or
+	// skipped as garbled
+	DroppedBytes int64
+	// Failed is the number of failed attempts to write to the sink
+	Failed int64
+}
+
+// diskQueueCursor is the file holding the position of the next entry to send
+const diskQueueCursor = "cursor"
+
+// recordHeader is the size of the length and the checksum before the entry
+// in a record of a segment file
+const recordHeader = 8
+
+// recordTable is the CRC-32 table of the record checksums
+var recordTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errBadRecord is returned by readRecord for a torn or garbled record
+var errBadRecord = errors.New("torn or garbled record")
+
+// cursorEvery is the number of entries sent between two updates of the
+// cursor file, besides idle times and Close
+const cursorEvery = 64
+
+// DiskQueue appends the writes to segment files and sends them to the sink
+// from there, so the entries survive restarts of the process and outages of
+// a remote sink. Entries are sent at least once: the ones sent after the
+// last update of the cursor are sent again after a restart.
+type DiskQueue struct {
+	sink   zapcore.WriteSyncer
+	config DiskQueueConfig
+
+	mu sync.Mutex
+	// segments are the sequence numbers of the segment files, oldest first,
+	// the last one being written
+	segments  []int64
+	sizes     map[int64]int64
+	writeFile *os.File
+	readSeq   int64
+	readOff   int64
+	readFile  *os.File
+	unsaved   int
+	closed    bool
+
+	notify chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+
+	sent         atomic.Int64
+	droppedBytes atomic.Int64
+	failed       atomic.Int64
+}
+
+// WithDiskQueue wraps a remote sink, e.g. of Kafka, Loki or Fluentd, in a
+// queue on disk in config.Directory. The entries left by a previous process
+// are sent first. Writes fail only when the disk does, Sync syncs the
+// segment file and does not wait for the sink.
+func WithDiskQueue(sink zapcore.WriteSyncer, config DiskQueueConfig) (*DiskQueue, error) {
+	if config.Directory == "" {
+		return nil, errors.New("log disk queue: no Directory")
+	}
+	if config.SegmentSize <= 0 {
+		config.SegmentSize = 8 << 20
+	}
+	if config.MaxSize <= 0 {
+		config.MaxSize = 256 << 20
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = time.Second
+	}
+	q := &DiskQueue{
+		sink:   sink,
+		config: config,
+		sizes:  map[int64]int64{},
+		notify: make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if err := q.open(); err != nil {
+		return nil, fmt.Errorf("log disk queue: %w", err)
+	}
+	go q.run()
+	return q, nil
+}
+
+func segmentName(seq int64) string {
+	return fmt.Sprintf("%020d.wal", seq)
+}
+
+func (q *DiskQueue) path(name string) string {
+	return longPath(filepath.Join(q.config.Directory, name))
+}
+
+// open loads the segments and the cursor left in the directory
+func (q *DiskQueue) open() error {
+	if err := os.MkdirAll(q.config.Directory, 0744); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(q.config.Directory)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		seq, err := strconv.ParseInt(strings.TrimSuffix(e.Name(), ".wal"), 10, 64)
+		if err != nil || !strings.HasSuffix(e.Name(), ".wal") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+		q.segments = append(q.segments, seq)
+		q.sizes[seq] = info.Size()
+	}
+	sort.Slice(q.segments, func(i, j int) bool { return q.segments[i] < q.segments[j] })
+
+	if len(q.segments) == 0 {
+		q.segments = []int64{1}
+		q.sizes[1] = 0
+	} else if err := q.truncateTorn(q.segments[len(q.segments)-1]); err != nil {
+		return err
+	}
+	last := q.segments[len(q.segments)-1]
+	q.writeFile, err = os.OpenFile(q.path(segmentName(last)), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	q.readSeq = q.segments[0]
+	if b, err := os.ReadFile(q.path(diskQueueCursor)); err == nil {
+		var seq, off int64
+		if _, err := fmt.Sscan(string(b), &seq, &off); err == nil {
+			if _, ok := q.sizes[seq]; ok && off <= q.sizes[seq] {
+				q.readSeq, q.readOff = seq, off
+			}
+		}
+	}
+	q.removeSent()
+	return nil
+}
+
+// truncateTorn cuts the last segment after its last valid record, the rest
+// was being written when the process or the machine stopped
+func (q *DiskQueue) truncateTorn(seq int64) error {
+	f, err := os.OpenFile(q.path(segmentName(seq)), os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var off int64
+	for off < q.sizes[seq] {
+		_, end, err := readRecord(f, off, q.sizes[seq])
+		if errors.Is(err, errBadRecord) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		off = end
+	}
+	if off == q.sizes[seq] {
+		return nil
+	}
+	q.sizes[seq] = off
+	return f.Truncate(off)
+}
+
+// newRecord returns the record of p on disk: the length of p and the
+// checksum of the length and p before p
+func newRecord(p []byte) []byte {
+	record := make([]byte, recordHeader+len(p))
+	binary.BigEndian.PutUint32(record, uint32(len(p)))
+	copy(record[recordHeader:], p)
+	binary.BigEndian.PutUint32(record[4:], recordChecksum(record[:4], p))
+	return record
+}
+
+// recordChecksum covers the length too, so zeros left by a crash of the
+// machine are no valid empty record
+func recordChecksum(length, p []byte) uint32 {
+	return crc32.Update(crc32.Checksum(length, recordTable), recordTable, p)
+}
+
+// readRecord returns the entry of the record at off in a segment file of
+// size bytes and the end offset of the record
+func readRecord(f *os.File, off, size int64) ([]byte, int64, error) {
+	if off+recordHeader > size {
+		return nil, 0, errBadRecord
+	}
+	var header [recordHeader]byte
+	if _, err := f.ReadAt(header[:], off); err != nil {
+		if err == io.EOF {
+			return nil, 0, errBadRecord
+		}
+		return nil, 0, err
+	}
+	end := off + recordHeader + int64(binary.BigEndian.Uint32(header[:4]))
+	if end > size {
+		return nil, 0, errBadRecord
+	}
+	p := make([]byte, end-off-recordHeader)
+	if n, err := f.ReadAt(p, off+recordHeader); n < len(p) {
+		if err == io.EOF {
+			return nil, 0, errBadRecord
+		}
+		return nil, 0, err
+	}
+	if recordChecksum(header[:4], p) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, 0, errBadRecord
+	}
+	return p, end, nil
+}
+
+// Write appends p to the queue as one entry
+func (q *DiskQueue) Write(p []byte) (int, error) {
+	record := newRecord(p)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return 0, errSinkClosed
+	}
+	last := q.segments[len(q.segments)-1]
+	if q.sizes[last] > 0 && q.sizes[last]+int64(len(record)) > q.config.SegmentSize {
+		if err := q.startSegment(last + 1); err != nil {
+			return 0, err
+		}
+		last++
+	}
+	if _, err := q.writeFile.Write(record); err != nil {
+		// cut what was written of the record, or leave it at the end of a
+		// segment which is not written anymore, where next skips it
+		if terr := q.writeFile.Truncate(q.sizes[last]); terr != nil {
+			err = multierr.Append(err, q.startSegment(last+1))
+		}
+		return 0, err
+	}
+	q.sizes[last] += int64(len(record))
+	if q.config.Fsync {
+		if err := q.writeFile.Sync(); err != nil {
+			return 0, err
+		}
+	}
+	q.enforceMaxSize()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// startSegment closes the segment being written and starts seq, q.mu must
+// be held
+func (q *DiskQueue) startSegment(seq int64) error {
+	f, err := os.OpenFile(q.path(segmentName(seq)), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	q.writeFile.Close()
+	q.writeFile = f
+	q.segments = append(q.segments, seq)
+	q.sizes[seq] = 0
+	return nil
+}
+
+// enforceMaxSize removes the oldest segments beyond MaxSize, never the one
+// being written, q.mu must be held
+func (q *DiskQueue) enforceMaxSize() {
+	for len(q.segments) > 1 && q.size() > q.config.MaxSize {
+		seq := q.segments[0]
+		switch {
+		case seq == q.readSeq:
+			q.droppedBytes.Add(q.sizes[seq] - q.readOff)
+			q.nextReadSegment()
+		case seq > q.readSeq:
+			q.droppedBytes.Add(q.sizes[seq])
+		}
+		q.removeOldest()
+	}
+}
+
+func (q *DiskQueue) size() int64 {
+	var n int64
+	for _, size := range q.sizes {
+		n += size
+	}
+	return n
+}
+
+// nextReadSegment moves the cursor to the start of the segment after the one
+// being read, q.mu must be held
+func (q *DiskQueue) nextReadSegment() {
+	if q.readFile != nil {
+		q.readFile.Close()
+		q.readFile = nil
+	}
+	for _, seq := range q.segments {
+		if seq > q.readSeq {
+			q.readSeq, q.readOff = seq, 0
+			return
+		}
+	}
+}
+
+// removeOldest deletes the oldest segment file, q.mu must be held
+func (q *DiskQueue) removeOldest() {
+	seq := q.segments[0]
+	q.segments = q.segments[1:]
+	delete(q.sizes, seq)
+	_ = os.Remove(q.path(segmentName(seq)))
+}
+
+// next returns the entry at the cursor and its end offset, ok is false when
+// everything is sent. q.mu must be held.
+func (q *DiskQueue) next() (p []byte, end int64, ok bool, err error) {
+	for {
+		last := q.segments[len(q.segments)-1]
+		size := q.sizes[q.readSeq]
+		if q.readOff+recordHeader > size {
+			if q.readSeq == last {
+				return nil, 0, false, nil
+			}
+			// sent or torn, go on with the next segment
+			q.nextReadSegment()
+			q.removeSent()
+			continue
+		}
+		if q.readFile == nil {
+			if q.readFile, err = os.Open(q.path(segmentName(q.readSeq))); err != nil {
+				return nil, 0, false, err
+			}
+		}
+		p, end, err = readRecord(q.readFile, q.readOff, size)
+		if errors.Is(err, errBadRecord) {
+			// the rest of the segment cannot be parsed
+			q.droppedBytes.Add(size - q.readOff)
+			q.readOff = size
+			continue
+		}
+		if err != nil {
+			return nil, 0, false, err
+		}
+		return p, end, true, nil
+	}
+}
+
+// removeSent deletes the segments before the one being read, q.mu must be held
+func (q *DiskQueue) removeSent() {
+	for len(q.segments) > 1 && q.segments[0] < q.readSeq {
+		q.removeOldest()
+	}
+}
+
+// saveCursor writes the position of the next entry to send, q.mu must be held
+func (q *DiskQueue) saveCursor() error {
+	q.unsaved = 0
+	tmp := q.path(diskQueueCursor + ".tmp")
+	if err := os.WriteFile(tmp, []byte(fmt.Sprintf("%d %d\n", q.readSeq, q.readOff)), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, q.path(diskQueueCursor))
+}
+
+// run sends the queued entries to the sink until Close, retrying failed
+// writes every RetryInterval
+func (q *DiskQueue) run() {
+	defer close(q.done)
+	for {
+		q.mu.Lock()
+		p, end, ok, err := q.next()
+		seq := q.readSeq
+		if !ok && q.unsaved > 0 {
+			_ = q.saveCursor()
+		}
+		q.mu.Unlock()
+
+		if err != nil || !ok {
+			wait := q.notify
+			var retry <-chan time.Time
+			if err != nil {
+				retry = time.After(q.config.RetryInterval)
+				wait = nil
+			}
+			select {
+			case <-wait:
+			case <-retry:
+			case <-q.stop:
+				return
+			}
+			continue
+		}
+
+		for {
+			_, err := q.sink.Write(p)
+			if err == nil {
+				break
+			}
+			q.failed.Add(1)
+			select {
+			case <-time.After(q.config.RetryInterval):
+			case <-q.stop:
+				return
+			}
+		}
+		q.sent.Add(1)
+
+		q.mu.Lock()
+		// the segment may have been removed for MaxSize meanwhile
+		if q.readSeq == seq && q.readOff < end {
+			q.readOff = end
+			q.unsaved++
+			if q.unsaved >= cursorEvery {
+				_ = q.saveCursor()
+			}
+		}
+		q.mu.Unlock()
+	}
+}
+
+// Sync syncs the segment file being written
+func (q *DiskQueue) Sync() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil
+	}
+	return q.writeFile.Sync()
+}
+
+// Close stops sending, saves the cursor and closes the files and the sink
+// if it is an io.Closer. The unsent entries are sent by the next queue
+// opened on the directory.
+func (q *DiskQueue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	q.mu.Unlock()
+	close(q.stop)
+	<-q.done
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	err := q.saveCursor()
+	err = multierr.Append(err, q.writeFile.Close())
+	if q.readFile != nil {
+		err = multierr.Append(err, q.readFile.Close())
+	}
+	if c, ok := q.sink.(io.Closer); ok {
+		err = multierr.Append(err, c.Close())
+	}
+	return err
+}
+
+// Stats returns the state of the queue
+func (q *DiskQueue) Stats() DiskQueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return DiskQueueStats{
+		Size:         q.size(),
+		Segments:     len(q.segments),
+		Sent:         q.sent.Load(),
+		DroppedBytes: q.droppedBytes.Load(),
+		Failed:       q.failed.Load(),
+	}
+}
@@ -0,0 +1,187 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// queueSink records the entries written to it, failing while fail is set
+type queueSink struct {
+	mu      sync.Mutex
+	fail    bool
+	entries []string
+}
+
+func (s *queueSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return 0, errors.New("sink down")
+	}
+	s.entries = append(s.entries, string(p))
+	return len(p), nil
+}
+
+func (s *queueSink) Sync() error {
+	return nil
+}
+
+func (s *queueSink) written() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.entries...)
+}
+
+// waitSent waits until the queue sent n entries
+func waitSent(t *testing.T, q *DiskQueue, n int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for q.Stats().Sent < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("sent %d entries, want %d", q.Stats().Sent, n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// fillQueue writes the entries to a queue on dir whose sink is down and
+// closes it, leaving them unsent on disk
+func fillQueue(t *testing.T, dir string, entries ...string) {
+	t.Helper()
+	q, err := WithDiskQueue(&queueSink{fail: true}, DiskQueueConfig{Directory: dir, RetryInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if _, err := q.Write([]byte(e)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiskQueueReplay(t *testing.T) {
+	segment := segmentName(1)
+	tests := []struct {
+		name string
+		// damage changes the segment file left by the first queue
+		damage func(t *testing.T, path string)
+		want   []string
+	}{
+		{
+			name:   "clean shutdown",
+			damage: func(*testing.T, string) {},
+			want:   []string{"one", "two", "three"},
+		},
+		{
+			name: "torn header",
+			damage: func(t *testing.T, path string) {
+				appendFile(t, path, []byte{0, 0, 0})
+			},
+			want: []string{"one", "two", "three"},
+		},
+		{
+			name: "torn entry",
+			damage: func(t *testing.T, path string) {
+				appendFile(t, path, newRecord([]byte("four"))[:recordHeader+2])
+			},
+			want: []string{"one", "two", "three"},
+		},
+		{
+			name: "zero filled tail",
+			damage: func(t *testing.T, path string) {
+				appendFile(t, path, make([]byte, 64))
+			},
+			want: []string{"one", "two", "three"},
+		},
+		{
+			name: "garbled last entry",
+			damage: func(t *testing.T, path string) {
+				b, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				b[len(b)-1] ^= 0xff
+				if err := os.WriteFile(path, b, 0600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{"one", "two"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			fillQueue(t, dir, "one", "two", "three")
+			tt.damage(t, filepath.Join(dir, segment))
+
+			sink := &queueSink{}
+			q, err := WithDiskQueue(sink, DiskQueueConfig{Directory: dir, RetryInterval: time.Millisecond})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer q.Close()
+			waitSent(t, q, int64(len(tt.want)))
+			// entries written after the restart follow the valid ones
+			if _, err := q.Write([]byte("after")); err != nil {
+				t.Fatal(err)
+			}
+			waitSent(t, q, int64(len(tt.want))+1)
+
+			want := append(tt.want[:len(tt.want):len(tt.want)], "after")
+			if got := sink.written(); !reflect.DeepEqual(got, want) {
+				t.Errorf("sent %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestDiskQueueCursor(t *testing.T) {
+	dir := t.TempDir()
+	sink := &queueSink{}
+	q, err := WithDiskQueue(sink, DiskQueueConfig{Directory: dir, RetryInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []string{"one", "two"} {
+		if _, err := q.Write([]byte(e)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitSent(t, q, 2)
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the entries sent before Close are not sent again
+	fillQueue(t, dir, "three")
+	sink = &queueSink{}
+	q, err = WithDiskQueue(sink, DiskQueueConfig{Directory: dir, RetryInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	waitSent(t, q, 1)
+	if got, want := sink.written(), []string{"three"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sent %q, want %q", got, want)
+	}
+}
+
+func appendFile(t *testing.T, path string, b []byte) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Write(b); err != nil {
+		t.Fatal(err)
+	}
+}
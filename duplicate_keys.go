@@ -0,0 +1,126 @@
+package log
+
+import (
+	"maps"
+	"strconv"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DuplicateKeys selects what happens to fields repeating a key of the object
+// they are written to, across With chains, global and call-site fields.
+// Duplicated JSON keys are dropped or rejected by some ingestion pipelines.
+type DuplicateKeys int
+
+const (
+	// DuplicateKeysAllow writes repeated keys as they are
+	DuplicateKeysAllow DuplicateKeys = iota
+	// DuplicateKeysWarn writes repeated keys as they are, followed by a warning
+	// entry naming them
+	DuplicateKeysWarn
+	// DuplicateKeysRename writes repeated keys as key#2, key#3 and so on
+	DuplicateKeysRename
+)
+
+// DuplicateKeysMessage is the message of the warning entries written by DuplicateKeysWarn
+const DuplicateKeysMessage = "duplicate log field keys"
+
+// duplicateKeysCore tracks the keys added with With, as the cores below
+// encode them eagerly, and checks the fields of every entry against them
+type duplicateKeysCore struct {
+	zapcore.Core
+	mode DuplicateKeys
+	// keys of the innermost object the fields are written to
+	keys map[string]bool
+	// dups are the keys repeated by the With fields
+	dups []string
+}
+
+func newDuplicateKeysCore(core zapcore.Core, mode DuplicateKeys) zapcore.Core {
+	if mode == DuplicateKeysAllow {
+		return core
+	}
+	return &duplicateKeysCore{Core: core, mode: mode, keys: map[string]bool{}}
+}
+
+func (c *duplicateKeysCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &duplicateKeysCore{mode: c.mode, keys: maps.Clone(c.keys), dups: c.dups[:len(c.dups):len(c.dups)]}
+	fields, dups := clone.dedupe(fields, clone.keys)
+	clone.dups = append(clone.dups, dups...)
+	clone.Core = c.Core.With(fields)
+	return clone
+}
+
+func (c *duplicateKeysCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *duplicateKeysCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	fields, dups := c.dedupe(fields, maps.Clone(c.keys))
+	err := c.Core.Write(ent, fields)
+	if c.mode != DuplicateKeysWarn || len(c.dups)+len(dups) == 0 {
+		return err
+	}
+	warning := ent
+	warning.Level = WarnLevel
+	warning.Message = DuplicateKeysMessage
+	warning.Stack = ""
+	return multierr.Append(err, c.Core.Write(warning, []zapcore.Field{
+		zap.Strings("keys", append(c.dups[:len(c.dups):len(c.dups)], dups...)),
+		zap.String("entry", ent.Message),
+	}))
+}
+
+// dedupe finds the fields repeating a key of keys or of an earlier field and
+// records the new keys in keys. In rename mode the repeated keys are renamed
+// on a copy of fields.
+func (c *duplicateKeysCore) dedupe(fields []zapcore.Field, keys map[string]bool) ([]zapcore.Field, []string) {
+	var dups []string
+	copied := false
+	for i, f := range fields {
+		switch f.Type {
+		case zapcore.SkipType:
+			continue
+		case zapcore.InlineMarshalerType:
+			lazy, ok := f.Interface.(*lazyField)
+			if !ok {
+				continue
+			}
+			f = lazy.field()
+		}
+
+		key := f.Key
+		if keys[key] {
+			dups = append(dups, key)
+			if c.mode == DuplicateKeysRename {
+				key = renameKey(key, keys)
+				if !copied {
+					fields = append([]zapcore.Field(nil), fields...)
+					copied = true
+				}
+				f.Key = key
+				fields[i] = f
+			}
+		}
+		keys[key] = true
+		if f.Type == zapcore.NamespaceType {
+			clear(keys)
+		}
+	}
+	return fields, dups
+}
+
+// renameKey returns the first of key#2, key#3... which is not taken
+func renameKey(key string, keys map[string]bool) string {
+	for n := 2; ; n++ {
+		renamed := key + "#" + strconv.Itoa(n)
+		if !keys[renamed] {
+			return renamed
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package log
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// Encoding selects the encoding of the console or the files, see
+// Config.ConsoleEncoding and Config.FileEncoding
+type Encoding string
+
+const (
+	// EncodingDefault follows Config.EncodeLogsAsJson
+	EncodingDefault Encoding = ""
+	EncodingJSON    Encoding = "json"
+	EncodingConsole Encoding = "console"
+)
+
+func (e Encoding) valid() bool {
+	return e == EncodingDefault || e == EncodingJSON || e == EncodingConsole
+}
+
+// sinkJSON reports whether the sink of the encoding writes JSON
+func sinkJSON(config Config, encoding Encoding) bool {
+	switch encoding {
+	case EncodingJSON:
+		return true
+	case EncodingConsole:
+		return false
+	}
+	return config.EncodeLogsAsJson
+}
+
+// newSinkEncoder returns the encoder of the console or files encoding
+func newSinkEncoder(config Config, encoding Encoding) zapcore.Encoder {
+	config.EncodeLogsAsJson = sinkJSON(config, encoding)
+	return newEncoder(config)
+}
+
+// consoleDiffers reports whether the console is encoded unlike the files
+func consoleDiffers(config Config) bool {
+	return sinkJSON(config, config.ConsoleEncoding) != sinkJSON(config, config.FileEncoding) ||
+		config.consoleColor && config.LevelEncoder == nil
+}
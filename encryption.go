@@ -0,0 +1,395 @@
+package log
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"go.uber.org/multierr"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// EncryptionConfig encrypts the log files with AES-GCM. Each file starts
+// with a header holding a random salt, from which a subkey of the file is
+// derived with HKDF-SHA256. Every write is then sealed as one chunk, a 4 byte
+// big endian length followed by the ciphertext, with the chunk index as nonce
+// and the index and a final flag as additional data, so chunks cannot be
+// dropped, reordered or moved between files unnoticed. Closing or rotating a
+// file seals an empty final chunk, a file reopened after a restart gets a new
+// header. Rotated and partially written files stay readable with
+// NewDecryptReader.
+type EncryptionConfig struct {
+	// Key is the AES key of 16, 24 or 32 bytes
+	Key []byte
+	// KeyEnv names an environment variable holding the key base64 encoded
+	KeyEnv string
+	// KeyFunc fetches the key, e.g. from a KMS, when the logger is configured.
+	// It takes precedence over Key and KeyEnv.
+	KeyFunc func() ([]byte, error)
+}
+
+// maxEncryptedChunk bounds the chunk length accepted by NewDecryptReader
+const maxEncryptedChunk = 64 << 20
+
+// LoadKey returns the key from KeyFunc, Key or KeyEnv in that order
+func (c EncryptionConfig) LoadKey() ([]byte, error) {
+	switch {
+	case c.KeyFunc != nil:
+		return c.KeyFunc()
+	case len(c.Key) > 0:
+		return c.Key, nil
+	case c.KeyEnv != "":
+		value, ok := os.LookupEnv(c.KeyEnv)
+		if !ok {
+			return nil, fmt.Errorf("log encryption key variable %s is not set", c.KeyEnv)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("log encryption key variable %s: %w", c.KeyEnv, err)
+		}
+		return key, nil
+	default:
+		return nil, errors.New("log encryption has no key")
+	}
+}
+
+// fileCipher holds the key the subkeys of the encrypted files are derived from
+type fileCipher struct {
+	key []byte
+}
+
+// newFileCipher returns the cipher of the config, nil when encryption is disabled
+func newFileCipher(config *EncryptionConfig) (*fileCipher, error) {
+	if config == nil {
+		return nil, nil
+	}
+	key, err := config.LoadKey()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := aes.NewCipher(key); err != nil {
+		return nil, fmt.Errorf("log encryption key: %w", err)
+	}
+	return &fileCipher{key: key}, nil
+}
+
+// sealedSize is the size of the chunk sealing n bytes
+func (c *fileCipher) sealedSize(n int) int64 {
+	return int64(4 + n + gcmTagSize)
+}
+
+const (
+	// encryptedMagic starts the header of an encrypted file, its first byte
+	// is above the first byte of any valid chunk length
+	encryptedMagic = "LGE1"
+	saltSize       = 16
+	headerSize     = len(encryptedMagic) + saltSize
+	gcmTagSize     = 16
+)
+
+// fileAEAD returns the AEAD of the file with the salt, keyed with the subkey
+// derived from key
+func fileAEAD(key, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(hkdfSHA256(key, salt, []byte("log file encryption"), len(key)))
+	if err != nil {
+		return nil, fmt.Errorf("log encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// hkdfSHA256 derives n bytes from the key with HKDF, RFC 5869, and SHA-256
+func hkdfSHA256(key, salt, info []byte, n int) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(key)
+	prk := extract.Sum(nil)
+
+	var out, block []byte
+	for i := byte(1); len(out) < n; i++ {
+		expand := hmac.New(sha256.New, prk)
+		expand.Write(block)
+		expand.Write(info)
+		expand.Write([]byte{i})
+		block = expand.Sum(nil)
+		out = append(out, block...)
+	}
+	return out[:n]
+}
+
+// chunkNonce is the nonce of the chunk at index
+func chunkNonce(aead cipher.AEAD, index uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], index)
+	return nonce
+}
+
+// chunkAAD binds the chunk to its index and tells whether it ends the file
+func chunkAAD(index uint64, final bool) []byte {
+	var aad [9]byte
+	binary.BigEndian.PutUint64(aad[:8], index)
+	if final {
+		aad[8] = 1
+	}
+	return aad[:]
+}
+
+func sealChunk(aead cipher.AEAD, index uint64, final bool, p []byte) []byte {
+	chunk := make([]byte, 4, 4+len(p)+aead.Overhead())
+	chunk = aead.Seal(chunk, chunkNonce(aead, index), p, chunkAAD(index, final))
+	binary.BigEndian.PutUint32(chunk, uint32(len(chunk)-4))
+	return chunk
+}
+
+// encryptedFile is a rolling file writing sealed chunks. It tracks the size
+// of the file to rotate it itself before lumberjack would, so the file ends
+// with its final chunk and the new one gets its header.
+type encryptedFile struct {
+	*lumberjack.Logger
+	cipher *fileCipher
+
+	mu sync.Mutex
+	// aead seals the chunks of the file, nil until its header is written
+	aead  cipher.AEAD
+	index uint64
+	size  int64
+}
+
+func newEncryptedFile(file *lumberjack.Logger, c *fileCipher) *encryptedFile {
+	return &encryptedFile{Logger: file, cipher: c}
+}
+
+func (f *encryptedFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	size := f.cipher.sealedSize(len(p))
+	// room for the final chunk, which must not make lumberjack rotate first
+	reserve := f.cipher.sealedSize(0)
+	if f.aead != nil && f.size+size+reserve > maxFileSize(f.Logger) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	if f.aead == nil {
+		if err := f.start(size + reserve); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := f.Logger.Write(sealChunk(f.aead, f.index, false, p)); err != nil {
+		return 0, err
+	}
+	f.index++
+	f.size += size
+	return len(p), nil
+}
+
+// start writes the header of a new file, or of the next entries appended to
+// an existing one, rotating it first when the write would not fit
+func (f *encryptedFile) start(next int64) error {
+	f.size = 0
+	if info, err := os.Stat(f.Filename); err == nil {
+		f.size = info.Size()
+	}
+	if f.size > 0 && f.size+int64(headerSize)+next > maxFileSize(f.Logger) {
+		if err := f.Logger.Rotate(); err != nil {
+			return err
+		}
+		f.size = 0
+	}
+	header := make([]byte, headerSize)
+	copy(header, encryptedMagic)
+	if _, err := rand.Read(header[len(encryptedMagic):]); err != nil {
+		return err
+	}
+	aead, err := fileAEAD(f.cipher.key, header[len(encryptedMagic):])
+	if err != nil {
+		return err
+	}
+	if _, err := f.Logger.Write(header); err != nil {
+		return err
+	}
+	f.aead, f.index = aead, 0
+	f.size += int64(headerSize)
+	return nil
+}
+
+// finish seals the final chunk of the file, f.mu must be held
+func (f *encryptedFile) finish() error {
+	if f.aead == nil {
+		return nil
+	}
+	_, err := f.Logger.Write(sealChunk(f.aead, f.index, true, nil))
+	f.aead = nil
+	return err
+}
+
+// rotate ends the file with the final chunk and starts a new one, f.mu must be held
+func (f *encryptedFile) rotate() error {
+	if err := f.finish(); err != nil {
+		return err
+	}
+	return f.Logger.Rotate()
+}
+
+// Rotate starts a new file which gets its own header
+func (f *encryptedFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rotate()
+}
+
+// Reopen closes the file after its final chunk, the file moved away by an
+// external rotation, a new one at its path gets its own header
+func (f *encryptedFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.finish(); err != nil {
+		return err
+	}
+	return f.Logger.Close()
+}
+
+func (f *encryptedFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	err := f.finish()
+	return multierr.Append(err, f.Logger.Close())
+}
+
+func (*encryptedFile) Sync() error {
+	return nil
+}
+
+// maxFileSize is the size lumberjack rotates the file at
+func maxFileSize(file *lumberjack.Logger) int64 {
+	if file.MaxSize == 0 {
+		// the default of lumberjack
+		return 100 << 20
+	}
+	return int64(file.MaxSize) << 20
+}
+
+// decryptReader reads the plaintext of a file of sealed chunks
+type decryptReader struct {
+	r   *bufio.Reader
+	key []byte
+	// aead opens the chunks after the last header, nil before the first one
+	aead  cipher.AEAD
+	index uint64
+	final bool
+	buf   []byte
+}
+
+// NewDecryptReader returns the plaintext of a log file written with
+// Config.Encryption and the given key. Reading fails on a chunk which was
+// tampered with, is missing or out of order, and at the end of a file which
+// was truncated or is still written to, after its plaintext so far.
+func NewDecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	if _, err := aes.NewCipher(key); err != nil {
+		return nil, fmt.Errorf("log encryption key: %w", err)
+	}
+	return &decryptReader{r: bufio.NewReader(r), key: key}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+// next opens the next chunk, reading the headers in front of it
+func (d *decryptReader) next() error {
+	for {
+		first, err := d.r.Peek(1)
+		if err == io.EOF && d.aead != nil && !d.final {
+			return errors.New("log decrypt: no final chunk, the file is truncated or still written")
+		}
+		if err != nil {
+			return err
+		}
+		if first[0] != encryptedMagic[0] {
+			break
+		}
+		if err := d.header(); err != nil {
+			return err
+		}
+	}
+	if d.aead == nil {
+		return errors.New("log decrypt: missing file header")
+	}
+	if d.final {
+		return errors.New("log decrypt: chunk after the final chunk")
+	}
+
+	var length [4]byte
+	if _, err := io.ReadFull(d.r, length[:]); err != nil {
+		return errors.New("log decrypt: truncated chunk length")
+	}
+	n := binary.BigEndian.Uint32(length[:])
+	if n < uint32(d.aead.Overhead()) || n > maxEncryptedChunk {
+		return fmt.Errorf("log decrypt: invalid chunk length %d", n)
+	}
+	chunk := make([]byte, n)
+	if _, err := io.ReadFull(d.r, chunk); err != nil {
+		return fmt.Errorf("log decrypt: truncated chunk: %w", err)
+	}
+	nonce := chunkNonce(d.aead, d.index)
+	plain, err := d.aead.Open(chunk[:0], nonce, chunk, chunkAAD(d.index, false))
+	if err != nil {
+		if plain, err = d.aead.Open(chunk[:0], nonce, chunk, chunkAAD(d.index, true)); err != nil {
+			return fmt.Errorf("log decrypt: chunk %d: %w", d.index, err)
+		}
+		d.final = true
+	}
+	d.index++
+	d.buf = plain
+	return nil
+}
+
+// header reads a file header and derives the AEAD of the chunks following it
+func (d *decryptReader) header() error {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(d.r, header); err != nil || string(header[:len(encryptedMagic)]) != encryptedMagic {
+		return errors.New("log decrypt: invalid file header")
+	}
+	if d.aead != nil && !d.final {
+		return errors.New("log decrypt: no final chunk before the next file header")
+	}
+	aead, err := fileAEAD(d.key, header[len(encryptedMagic):])
+	if err != nil {
+		return err
+	}
+	d.aead, d.index, d.final = aead, 0, false
+	return nil
+}
+
+// DecryptFile writes the plaintext of the encrypted log file at path to w,
+// for use by command line tools
+func DecryptFile(w io.Writer, path string, key []byte) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r, err := NewDecryptReader(f, key)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
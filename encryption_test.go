@@ -0,0 +1,198 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+var testEncryptionKey = bytes.Repeat([]byte{7}, 32)
+
+// writeEncrypted writes the lines through an encrypted rolling file, closing
+// it after each batch, and returns the bytes of the file
+func writeEncrypted(t *testing.T, batches ...[]string) []byte {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "enc.log")
+	f := newEncryptedFile(&lumberjack.Logger{Filename: path}, &fileCipher{key: testEncryptionKey})
+	for _, lines := range batches {
+		for _, line := range lines {
+			if _, err := f.Write([]byte(line)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func decrypt(data, key []byte) (string, error) {
+	r, err := NewDecryptReader(bytes.NewReader(data), key)
+	if err != nil {
+		return "", err
+	}
+	plain, err := io.ReadAll(r)
+	return string(plain), err
+}
+
+// chunkBounds returns the offsets of the chunks after the file header
+func chunkBounds(data []byte) [][2]int {
+	var bounds [][2]int
+	for i := headerSize; i+4 <= len(data); {
+		n := int(binary.BigEndian.Uint32(data[i:]))
+		bounds = append(bounds, [2]int{i, i + 4 + n})
+		i += 4 + n
+	}
+	return bounds
+}
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		batches [][]string
+		want    string
+	}{
+		{"one line", [][]string{{"first\n"}}, "first\n"},
+		{"several lines", [][]string{{"first\n", "second\n", "third\n"}}, "first\nsecond\nthird\n"},
+		{"empty write", [][]string{{"first\n", "", "second\n"}}, "first\nsecond\n"},
+		{"reopened file", [][]string{{"first\n"}, {"second\n"}}, "first\nsecond\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decrypt(writeEncrypted(t, tt.batches...), testEncryptionKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncryptionRejectsChangedFiles(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(data []byte) []byte
+		key    []byte
+		want   string
+	}{
+		{
+			name:   "wrong key",
+			change: func(data []byte) []byte { return data },
+			key:    bytes.Repeat([]byte{8}, 32),
+			want:   "chunk 0",
+		},
+		{
+			name: "flipped bit",
+			change: func(data []byte) []byte {
+				data[len(data)/2] ^= 1
+				return data
+			},
+			want: "log decrypt",
+		},
+		{
+			name: "truncated final chunk",
+			change: func(data []byte) []byte {
+				bounds := chunkBounds(data)
+				return data[:bounds[len(bounds)-1][0]]
+			},
+			want: "no final chunk",
+		},
+		{
+			name: "dropped chunk",
+			change: func(data []byte) []byte {
+				bounds := chunkBounds(data)
+				return append(data[:bounds[0][0]:bounds[0][0]], data[bounds[0][1]:]...)
+			},
+			want: "chunk 0",
+		},
+		{
+			name: "swapped chunks",
+			change: func(data []byte) []byte {
+				bounds := chunkBounds(data)
+				first := append([]byte(nil), data[bounds[0][0]:bounds[0][1]]...)
+				second := append([]byte(nil), data[bounds[1][0]:bounds[1][1]]...)
+				out := append([]byte(nil), data[:bounds[0][0]]...)
+				out = append(out, second...)
+				out = append(out, first...)
+				return append(out, data[bounds[1][1]:]...)
+			},
+			want: "chunk 0",
+		},
+		{
+			name: "zero filled tail",
+			change: func(data []byte) []byte {
+				return append(data, make([]byte, 32)...)
+			},
+			want: "chunk after the final chunk",
+		},
+		{
+			name: "missing header",
+			change: func(data []byte) []byte {
+				return data[headerSize:]
+			},
+			want: "missing file header",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := tt.key
+			if key == nil {
+				key = testEncryptionKey
+			}
+			data := tt.change(writeEncrypted(t, []string{"first line\n", "second line\n"}))
+			if _, err := decrypt(data, key); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncryptionSubkeyPerFile(t *testing.T) {
+	a := writeEncrypted(t, []string{"same line\n"})
+	b := writeEncrypted(t, []string{"same line\n"})
+	if bytes.Equal(a[:headerSize], b[:headerSize]) {
+		t.Fatal("files share the header salt")
+	}
+	if bytes.Equal(a[headerSize:], b[headerSize:]) {
+		t.Error("files with the same plaintext have the same ciphertext")
+	}
+}
+
+func TestEncryptionRotate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "enc.log")
+	f := newEncryptedFile(&lumberjack.Logger{Filename: path}, &fileCipher{key: testEncryptionKey})
+	f.Write([]byte("before\n"))
+	if err := f.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("after\n"))
+	f.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	var got []string
+	for _, file := range files {
+		data, _ := os.ReadFile(file)
+		plain, err := decrypt(data, testEncryptionKey)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		got = append(got, plain)
+	}
+	if len(got) != 2 || !(got[0] == "before\n" && got[1] == "after\n" || got[1] == "before\n" && got[0] == "after\n") {
+		t.Errorf("got %q, want the lines before and after the rotation in their own files", got)
+	}
+}
@@ -0,0 +1,73 @@
+//go:build windows
+
+package log
+
+import (
+	"reflect"
+	"testing"
+)
+
+// fakeEventLog records the events written through it
+type fakeEventLog struct {
+	events []string
+}
+
+func (f *fakeEventLog) Info(eid uint32, msg string) error {
+	f.events = append(f.events, "info: "+msg)
+	return nil
+}
+
+func (f *fakeEventLog) Error(eid uint32, msg string) error {
+	f.events = append(f.events, "error: "+msg)
+	return nil
+}
+
+func (f *fakeEventLog) Close() error {
+	return nil
+}
+
+func TestEventLogSinkWrite(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "error entry",
+			input: `{"lvl":"error","msg":"failed"}` + "\n",
+			want:  []string{`error: {"lvl":"error","msg":"failed"}`},
+		},
+		{
+			name:  "entries below error are skipped",
+			input: `{"lvl":"warn","msg":"slow"}` + "\n" + `{"lvl":"info","msg":"ok"}` + "\n",
+		},
+		{
+			name:  "entry without level",
+			input: `{"msg":"no level"}` + "\n",
+			want:  []string{`error: {"msg":"no level"}`},
+		},
+		{
+			name:  "plain line",
+			input: "2024-01-02 10:00:00.000\terror\tplain\n",
+			want:  []string{"info: 2024-01-02 10:00:00.000\terror\tplain"},
+		},
+		{
+			name:  "several lines",
+			input: "plain\n" + `{"lvl":"fatal","msg":"down"}` + "\n\n",
+			want:  []string{"info: plain", `error: {"lvl":"fatal","msg":"down"}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeEventLog{}
+			sink := &EventLogSink{log: fake}
+			n, err := sink.Write([]byte(tt.input))
+			if err != nil || n != len(tt.input) {
+				t.Fatalf("Write() = %d, %v, want %d, nil", n, err, len(tt.input))
+			}
+			if !reflect.DeepEqual(fake.events, tt.want) {
+				t.Errorf("events = %q, want %q", fake.events, tt.want)
+			}
+		})
+	}
+}
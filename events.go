@@ -0,0 +1,103 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// EventIDKey is the field carrying the id of the entries logged by Event
+const EventIDKey = "event_id"
+
+// UnknownEventMessage is the message logged by Event for ids never registered
+const UnknownEventMessage = "unknown log event"
+
+// eventDef is an event registered by RegisterEvent
+type eventDef struct {
+	template string
+	level    Level
+}
+
+var (
+	eventsMu sync.RWMutex
+	events   = map[string]eventDef{}
+)
+
+// RegisterEvent adds the event id to the catalog logged by Event, usually
+// from an init function. The template is the message, {name} placeholders are
+// replaced with the values of the fields of that name. An id registered
+// twice returns an error.
+func RegisterEvent(id, template string, level Level) error {
+	if id == "" {
+		return fmt.Errorf("log event: empty id")
+	}
+	eventsMu.Lock()
+	defer eventsMu.Unlock()
+	if _, ok := events[id]; ok {
+		return fmt.Errorf("log event %q already registered", id)
+	}
+	events[id] = eventDef{template: template, level: level}
+	return nil
+}
+
+// Event logs the registered event id to the logger of ctx at its level with
+// the event_id field, so dashboards key on the id rather than the message.
+// Unregistered ids are logged at warn level with the UnknownEventMessage.
+func Event(ctx context.Context, id string, fields Fields) {
+	l := FromContext(ctx)
+	level, msg, zfields := l.event(ctx, id, fields)
+	newCheckedEntry(l.loggerFor(level).Check(thresholdLevel(level), msg), level).Write(zfields...)
+}
+
+// Event logs the registered event id with the logger of ctx, le if it has none
+func (le *LogEntry) Event(ctx context.Context, id string, fields Fields) {
+	l := le.fromContext(ctx)
+	level, msg, zfields := l.event(ctx, id, fields)
+	newCheckedEntry(l.loggerFor(level).Check(thresholdLevel(level), msg), level).Write(zfields...)
+}
+
+// event returns the level, message and fields of the event id
+func (le *LogEntry) event(ctx context.Context, id string, fields Fields) (Level, string, []zapcore.Field) {
+	eventsMu.RLock()
+	def, ok := events[id]
+	eventsMu.RUnlock()
+	msg := UnknownEventMessage
+	level := WarnLevel
+	if ok {
+		msg, level = renderEvent(def.template, fields), def.level
+	}
+	zfields := appendFields([]zapcore.Field{zap.String(EventIDKey, id)}, fields, le.sortFields)
+	return level, msg, le.contextState(ctx, zfields)
+}
+
+// renderEvent replaces the {name} placeholders of the template with the
+// values of the fields, unknown names are kept
+func renderEvent(template string, fields Fields) string {
+	if !strings.Contains(template, "{") {
+		return template
+	}
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		end := -1
+		if start >= 0 {
+			end = strings.IndexByte(template[start+1:], '}')
+		}
+		if end < 0 {
+			b.WriteString(template)
+			return b.String()
+		}
+		end += start + 1
+		b.WriteString(template[:start])
+		if v, ok := fields[template[start+1:end]]; ok {
+			b.WriteString(fmt.Sprint(v))
+		} else {
+			b.WriteString(template[start : end+1])
+		}
+		template = template[end+1:]
+	}
+}
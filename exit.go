@@ -0,0 +1,49 @@
+package log
+
+import (
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	exitHooksMu sync.Mutex
+	exitHooks   []func()
+)
+
+// RegisterExitHook adds a function run after a Fatal entry is written and
+// before the process exits, e.g. to flush asynchronous sinks or fire an alert.
+// Hooks run in reverse order of registration, like deferred calls.
+func RegisterExitHook(hook func()) {
+	exitHooksMu.Lock()
+	defer exitHooksMu.Unlock()
+	exitHooks = append(exitHooks, hook)
+}
+
+func runExitHooks() {
+	exitHooksMu.Lock()
+	hooks := append([]func(){}, exitHooks...)
+	exitHooksMu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
+
+// fatalHook runs the exit hooks before the action configured by Config.OnFatal
+type fatalHook struct {
+	action zapcore.CheckWriteHook
+}
+
+func newFatalHook(action zapcore.CheckWriteHook) fatalHook {
+	// like zap, Fatal never returns to the caller
+	if action == nil || action == zapcore.WriteThenNoop {
+		action = zapcore.WriteThenFatal
+	}
+	return fatalHook{action: action}
+}
+
+func (h fatalHook) OnWrite(ce *zapcore.CheckedEntry, fields []zapcore.Field) {
+	runExitHooks()
+	h.action.OnWrite(ce, fields)
+}
@@ -0,0 +1,92 @@
+package log
+
+import (
+	"hash/fnv"
+	"sync/atomic"
+)
+
+// Experiment runs a candidate logging config for a percentage of requests
+// alongside the control config, so volume and cost of both can be compared
+// before the candidate is promoted
+type Experiment struct {
+	control         *LogEntry
+	candidate       *LogEntry
+	controlStats    *loggerStats
+	candidateStats  *loggerStats
+	candidateConfig Config
+	percent         atomic.Int32
+	promoted        atomic.Bool
+}
+
+// NewExperiment builds a logger for each config. percent of the keys passed to
+// For are routed to the candidate.
+func NewExperiment(control, candidate Config, percent int) *Experiment {
+	e := &Experiment{
+		controlStats:    &loggerStats{},
+		candidateStats:  &loggerStats{},
+		candidateConfig: candidate,
+	}
+	control.stats = e.controlStats
+	candidate.stats = e.candidateStats
+	e.control = buildLogEntry(control)
+	e.candidate = buildLogEntry(candidate)
+	e.SetPercent(percent)
+	return e
+}
+
+// For returns the logger for the request or logger identified by key. The same
+// key always gets the same logger for a given percentage, every key the
+// candidate once it is promoted.
+func (e *Experiment) For(key string) *LogEntry {
+	if e.promoted.Load() {
+		return e.candidate
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	if int32(h.Sum32()%100) < e.percent.Load() {
+		return e.candidate
+	}
+	return e.control
+}
+
+// SetPercent changes the share of keys routed to the candidate at runtime
+func (e *Experiment) SetPercent(percent int) {
+	if percent < 0 {
+		percent = 0
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	e.percent.Store(int32(percent))
+}
+
+// Percent returns the share of keys routed to the candidate
+func (e *Experiment) Percent() int {
+	return int(e.percent.Load())
+}
+
+// Stats returns what the control and the candidate logged so far
+func (e *Experiment) Stats() (control LoggerStats, candidate LoggerStats) {
+	return e.controlStats.snapshot(), e.candidateStats.snapshot()
+}
+
+// ResetStats clears the collected stats, e.g. after changing the percentage
+func (e *Experiment) ResetStats() {
+	e.controlStats.reset()
+	e.candidateStats.reset()
+}
+
+// Promote routes every key to the candidate and makes the candidate logger
+// the default one, so its files are not opened a second time. The control
+// logger is closed once the candidate replaced it.
+func (e *Experiment) Promote() error {
+	e.SetPercent(100)
+	configureMu.Lock()
+	swapDefault(e.candidate, e.candidate.level, e.candidateConfig)
+	configured = true
+	configureMu.Unlock()
+	if e.promoted.Swap(true) {
+		return nil
+	}
+	return e.control.Close()
+}
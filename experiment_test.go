@@ -0,0 +1,50 @@
+package log
+
+import (
+	"net/url"
+	"os"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPromoteClosesControl(t *testing.T) {
+	var closed atomic.Int32
+	if err := RegisterSink("promotetest", func(*url.URL) (Sink, error) {
+		return closingSink{closed: &closed}, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	console, err := os.CreateTemp(t.TempDir(), "console")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer console.Close()
+	prev := defaults.Load()
+	defer func() {
+		configureMu.Lock()
+		defaults.Store(prev)
+		configured = false
+		configureMu.Unlock()
+	}()
+
+	control := Config{
+		Level:              InfoLevel,
+		ConsoleInfoStream:  console,
+		ConsoleErrorStream: console,
+		InfoSinkURLs:       []string{"promotetest://control"},
+	}
+	candidate := control
+	candidate.InfoSinkURLs = nil
+	e := NewExperiment(control, candidate, 0)
+	for i := 0; i < 2; i++ {
+		if err := e.Promote(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := closed.Load(); n != 1 {
+		t.Errorf("control sink closed %d times, want once", n)
+	}
+	if Default() != e.candidate || e.For("key") != e.candidate {
+		t.Error("the candidate is not used once promoted")
+	}
+}
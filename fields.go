@@ -0,0 +1,160 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Field is a strongly-typed field accepted by the *v methods
+type Field = zapcore.Field
+
+// Str constructs a field with a string value
+func Str(key, val string) Field {
+	return zap.String(key, val)
+}
+
+// Strs constructs a field with a slice of strings
+func Strs(key string, val []string) Field {
+	return zap.Strings(key, val)
+}
+
+// ByteString constructs a field with UTF-8 encoded text held in a byte
+// slice, written as a string without converting it first
+func ByteString(key string, val []byte) Field {
+	return zap.ByteString(key, val)
+}
+
+// Int constructs a field with an int value
+func Int(key string, val int) Field {
+	return zap.Int(key, val)
+}
+
+// Int64 constructs a field with an int64 value
+func Int64(key string, val int64) Field {
+	return zap.Int64(key, val)
+}
+
+// Uint64 constructs a field with an uint64 value
+func Uint64(key string, val uint64) Field {
+	return zap.Uint64(key, val)
+}
+
+// Float64 constructs a field with a float64 value
+func Float64(key string, val float64) Field {
+	return zap.Float64(key, val)
+}
+
+// Bool constructs a field with a bool value
+func Bool(key string, val bool) Field {
+	return zap.Bool(key, val)
+}
+
+// Dur constructs a field with a time.Duration value
+func Dur(key string, val time.Duration) Field {
+	return zap.Duration(key, val)
+}
+
+// Bytes constructs a field with a size in bytes, human readable in console
+// mode and a raw number in JSON
+func Bytes(key string, n int64) Field {
+	return zap.Reflect(key, ByteSize(n))
+}
+
+// Time constructs a field with a time.Time value
+func Time(key string, val time.Time) Field {
+	return zap.Time(key, val)
+}
+
+// Err constructs a field with the "error" key
+func Err(err error) Field {
+	return zap.Error(err)
+}
+
+// NamedErr constructs a field with an error under the given key
+func NamedErr(key string, err error) Field {
+	return zap.NamedError(key, err)
+}
+
+// Stringer constructs a field with the value's String method
+func Stringer(key string, val fmt.Stringer) Field {
+	return zap.Stringer(key, val)
+}
+
+// Namespace nests the fields logged after it, on the entry or on loggers
+// derived with With, under the key
+func Namespace(key string) Field {
+	return zap.Namespace(key)
+}
+
+// ObjectMarshaler and ArrayMarshaler let types encode themselves without
+// reflection, Fields values implementing them are encoded with their methods
+type (
+	ObjectMarshaler = zapcore.ObjectMarshaler
+	ArrayMarshaler  = zapcore.ArrayMarshaler
+	ObjectEncoder   = zapcore.ObjectEncoder
+	ArrayEncoder    = zapcore.ArrayEncoder
+)
+
+// ObjectFunc adapts a function to ObjectMarshaler
+type ObjectFunc func(enc ObjectEncoder) error
+
+func (f ObjectFunc) MarshalLogObject(enc ObjectEncoder) error {
+	return f(enc)
+}
+
+// ArrayFunc adapts a function to ArrayMarshaler
+type ArrayFunc func(enc ArrayEncoder) error
+
+func (f ArrayFunc) MarshalLogArray(enc ArrayEncoder) error {
+	return f(enc)
+}
+
+// Object constructs a field with the object nested under the key, e.g.
+// Object("http", Fields{"method": "GET"}) or a type implementing ObjectMarshaler
+func Object(key string, val ObjectMarshaler) Field {
+	return zap.Object(key, val)
+}
+
+// Array constructs a field with an array encoded by val
+func Array(key string, val ArrayMarshaler) Field {
+	return zap.Array(key, val)
+}
+
+// Objects constructs a field with an array of objects
+func Objects[T ObjectMarshaler](key string, vals []T) Field {
+	return zap.Objects(key, vals)
+}
+
+// Lazy constructs a field whose value is computed by fn only when the entry
+// is written, after level checks and sampling. fn is called at most once;
+// with With it is called when the logger is derived.
+func Lazy(key string, fn func() interface{}) Field {
+	return zap.Inline(&lazyField{key: key, fn: fn})
+}
+
+type lazyField struct {
+	key  string
+	fn   func() interface{}
+	once sync.Once
+	val  interface{}
+}
+
+func (f *lazyField) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	f.field().AddTo(enc)
+	return nil
+}
+
+// field evaluates the value once and returns it as a regular field
+func (f *lazyField) field() zapcore.Field {
+	f.once.Do(func() { f.val = f.fn() })
+	return zap.Any(f.key, f.val)
+}
+
+// Any constructs a field choosing the encoding by the value's type
+func Any(key string, val interface{}) Field {
+	return zap.Any(key, val)
+}
@@ -0,0 +1,105 @@
+package forward
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/olee12/log"
+	"google.golang.org/grpc"
+)
+
+// Sink streams JSON encoded entries to a Forwarder service. It is meant to
+// be used in log.Config.ExtraInfoSinks and ExtraErrorSinks of a logger with
+// EncodeLogsAsJson enabled; other lines are forwarded as plain messages.
+type Sink struct {
+	mu     sync.Mutex
+	client ForwarderClient
+	stream grpc.ClientStreamingClient[Record, PushResponse]
+	cancel context.CancelFunc
+}
+
+// NewSink returns a sink opening its stream on the connection lazily and
+// reopening it after errors
+func NewSink(cc grpc.ClientConnInterface) *Sink {
+	return &Sink{client: NewForwarderClient(cc)}
+}
+
+func (s *Sink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stream == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		stream, err := s.client.Push(ctx)
+		if err != nil {
+			cancel()
+			return 0, err
+		}
+		s.stream, s.cancel = stream, cancel
+	}
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\n"), []byte("\n")) {
+		if err := s.stream.Send(newRecord(line)); err != nil {
+			s.cancel()
+			s.stream = nil
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (s *Sink) Sync() error {
+	return nil
+}
+
+// Close ends the stream and waits for the server to acknowledge it
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stream == nil {
+		return nil
+	}
+	_, err := s.stream.CloseAndRecv()
+	s.cancel()
+	s.stream = nil
+	return err
+}
+
+// newRecord splits a JSON encoded entry into the entry metadata and the fields
+func newRecord(line []byte) *Record {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return &Record{TimeUnixNano: time.Now().UnixNano(), Level: log.InfoLevel.String(), Message: string(line)}
+	}
+	record := &Record{
+		TimeUnixNano: time.Now().UnixNano(),
+		Level:        takeString(fields, log.LevelKey),
+		LoggerName:   takeString(fields, log.NameKey),
+		Message:      takeString(fields, log.MessageKey),
+		Caller:       takeString(fields, log.CallerKey),
+		Stack:        takeString(fields, log.StacktraceKey),
+	}
+	if t, err := log.ParseEntryTime(takeString(fields, log.TimeKey)); err == nil {
+		record.TimeUnixNano = t.UnixNano()
+	}
+	if len(fields) > 0 {
+		record.FieldsJson, _ = json.Marshal(fields)
+	}
+	return record
+}
+
+func takeString(fields map[string]json.RawMessage, key string) string {
+	raw, ok := fields[key]
+	if !ok {
+		return ""
+	}
+	delete(fields, key)
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return string(raw)
+	}
+	return s
+}
@@ -0,0 +1,83 @@
+package forward
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/olee12/log"
+	"google.golang.org/grpc"
+)
+
+// Server writes the records received from other services into a local logger
+type Server struct {
+	UnimplementedForwarderServer
+	target *log.LogEntry
+}
+
+// NewServer returns a Forwarder writing into target, which usually is a logger
+// created with log.NewLogEntry for the collected files
+func NewServer(target *log.LogEntry) *Server {
+	return &Server{target: target}
+}
+
+// Register registers a Server writing into target on s
+func Register(s grpc.ServiceRegistrar, target *log.LogEntry) {
+	RegisterForwarderServer(s, NewServer(target))
+}
+
+func (s *Server) Push(stream grpc.ClientStreamingServer[Record, PushResponse]) error {
+	var accepted uint64
+	for {
+		record, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&PushResponse{Accepted: accepted})
+		}
+		if err != nil {
+			return err
+		}
+		s.write(record)
+		accepted++
+	}
+}
+
+// write logs the record at its level. Panic and fatal records are written at
+// error level so a remote service can't bring down the collector.
+func (s *Server) write(record *Record) {
+	fields := log.Fields{}
+	if len(record.FieldsJson) > 0 {
+		_ = json.Unmarshal(record.FieldsJson, &fields)
+	}
+	fields["remote_time"] = time.Unix(0, record.TimeUnixNano).Format(time.RFC3339Nano)
+	if record.LoggerName != "" {
+		fields["remote_logger"] = record.LoggerName
+	}
+	if record.Caller != "" {
+		fields["remote_caller"] = record.Caller
+	}
+	if record.Stack != "" {
+		fields["remote_stacktrace"] = record.Stack
+	}
+
+	level, err := log.ParseLevel(record.Level)
+	if err != nil {
+		level = log.InfoLevel
+	}
+	switch level {
+	case log.TraceLevel:
+		s.target.TraceWith(record.Message, fields)
+	case log.DebugLevel:
+		s.target.DebugWith(record.Message, fields)
+	case log.InfoLevel:
+		s.target.InfoWith(record.Message, fields)
+	case log.NoticeLevel:
+		s.target.NoticeWith(record.Message, fields)
+	case log.WarnLevel:
+		s.target.WarnWith(record.Message, fields)
+	default:
+		if level != log.ErrorLevel {
+			fields["remote_level"] = record.Level
+		}
+		s.target.ErrorWith(record.Message, fields)
+	}
+}
@@ -0,0 +1,67 @@
+package log
+
+import (
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+var globalFields atomic.Pointer[[]zapcore.Field]
+
+// SetGlobalFields replaces the fields stamped on every entry written by any
+// logger of this package, including entries derived before the call.
+// Passing nil or empty Fields clears them.
+func SetGlobalFields(fields Fields) {
+	zfields := convertFields(fields)
+	globalFields.Store(&zfields)
+}
+
+// GetGlobalFields returns the fields currently set by SetGlobalFields
+func GetGlobalFields() Fields {
+	zfields := loadGlobalFields()
+	if len(zfields) == 0 {
+		return nil
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range zfields {
+		f.AddTo(enc)
+	}
+	return Fields(enc.Fields)
+}
+
+func loadGlobalFields() []zapcore.Field {
+	if p := globalFields.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// globalFieldsCore appends the global fields at write time so that a call to
+// SetGlobalFields is visible to loggers that were already derived with With
+type globalFieldsCore struct {
+	zapcore.Core
+}
+
+func newGlobalFieldsCore(core zapcore.Core) zapcore.Core {
+	return &globalFieldsCore{Core: core}
+}
+
+func (c *globalFieldsCore) With(fields []zapcore.Field) zapcore.Core {
+	return &globalFieldsCore{Core: c.Core.With(fields)}
+}
+
+func (c *globalFieldsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *globalFieldsCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if globals := loadGlobalFields(); len(globals) > 0 {
+		all := make([]zapcore.Field, 0, len(globals)+len(fields))
+		all = append(all, globals...)
+		fields = append(all, fields...)
+	}
+	return c.Core.Write(ent, fields)
+}
@@ -3,8 +3,11 @@ module github.com/olee12/log
 go 1.21
 
 require (
+	go.uber.org/multierr v1.11.0
 	go.uber.org/zap v1.26.0
+	golang.org/x/sys v0.21.0
+	golang.org/x/term v0.21.0
 	gopkg.in/natefinch/lumberjack.v2 v2.2.1
 )
 
-require go.uber.org/multierr v1.11.0 // indirect
+require github.com/stretchr/testify v1.9.0 // indirect
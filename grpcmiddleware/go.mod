@@ -0,0 +1,22 @@
+module github.com/olee12/log/grpcmiddleware
+
+go 1.21
+
+replace github.com/olee12/log => ../
+
+require (
+	github.com/olee12/log v0.0.0-00010101000000-000000000000
+	go.uber.org/zap v1.26.0
+	google.golang.org/grpc v1.64.1
+)
+
+require (
+	go.uber.org/multierr v1.11.0 // indirect
+	golang.org/x/net v0.26.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	golang.org/x/term v0.21.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
+	google.golang.org/protobuf v1.34.1 // indirect
+	gopkg.in/natefinch/lumberjack.v2 v2.2.1 // indirect
+)
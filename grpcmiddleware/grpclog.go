@@ -0,0 +1,130 @@
+package grpcmiddleware
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/olee12/log"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/grpclog"
+)
+
+// GRPCLogger implements grpclog.LoggerV2 with the default logger of package
+// log, info entries going to the info output and warnings and errors to the
+// error output:
+//
+//	grpclog.SetLoggerV2(grpcmiddleware.NewGRPCLogger(0))
+type GRPCLogger struct {
+	severity  log.Level
+	verbosity int
+}
+
+var _ grpclog.LoggerV2 = (*GRPCLogger)(nil)
+
+// NewGRPCLogger follows the conventions of the gRPC logger: only errors are
+// written unless GRPC_GO_LOG_SEVERITY_LEVEL is warning or info, and
+// verbosity is the level V reports against. A negative verbosity is read from
+// GRPC_GO_LOG_VERBOSITY_LEVEL.
+func NewGRPCLogger(verbosity int) *GRPCLogger {
+	severity := log.ErrorLevel
+	switch strings.ToLower(os.Getenv("GRPC_GO_LOG_SEVERITY_LEVEL")) {
+	case "warning":
+		severity = log.WarnLevel
+	case "info":
+		severity = log.InfoLevel
+	}
+	if verbosity < 0 {
+		verbosity, _ = strconv.Atoi(os.Getenv("GRPC_GO_LOG_VERBOSITY_LEVEL"))
+	}
+	return &GRPCLogger{severity: severity, verbosity: verbosity}
+}
+
+// callerDepth skips write, the LoggerV2 method and the grpclog function
+// calling it to report the caller of grpclog, like the glog logger of gRPC
+const callerDepth = 3
+
+// write logs the message of gRPC, depth frames above callerDepth
+func (g *GRPCLogger) write(level log.Level, depth int, msg string) {
+	if level < g.severity {
+		return
+	}
+	msg = strings.TrimRight(msg, "\n")
+	if ce := log.Default().WithCallerSkip(callerDepth+depth).Check(level, msg); ce != nil {
+		ce.Write(zap.String("component", "grpc"))
+	}
+}
+
+func (g *GRPCLogger) Info(args ...interface{}) {
+	g.write(log.InfoLevel, 0, fmt.Sprint(args...))
+}
+
+func (g *GRPCLogger) Infoln(args ...interface{}) {
+	g.write(log.InfoLevel, 0, fmt.Sprintln(args...))
+}
+
+func (g *GRPCLogger) Infof(format string, args ...interface{}) {
+	g.write(log.InfoLevel, 0, fmt.Sprintf(format, args...))
+}
+
+func (g *GRPCLogger) Warning(args ...interface{}) {
+	g.write(log.WarnLevel, 0, fmt.Sprint(args...))
+}
+
+func (g *GRPCLogger) Warningln(args ...interface{}) {
+	g.write(log.WarnLevel, 0, fmt.Sprintln(args...))
+}
+
+func (g *GRPCLogger) Warningf(format string, args ...interface{}) {
+	g.write(log.WarnLevel, 0, fmt.Sprintf(format, args...))
+}
+
+func (g *GRPCLogger) Error(args ...interface{}) {
+	g.write(log.ErrorLevel, 0, fmt.Sprint(args...))
+}
+
+func (g *GRPCLogger) Errorln(args ...interface{}) {
+	g.write(log.ErrorLevel, 0, fmt.Sprintln(args...))
+}
+
+func (g *GRPCLogger) Errorf(format string, args ...interface{}) {
+	g.write(log.ErrorLevel, 0, fmt.Sprintf(format, args...))
+}
+
+// Fatal logs at fatal level, which exits like gRPC expects
+func (g *GRPCLogger) Fatal(args ...interface{}) {
+	g.write(log.FatalLevel, 0, fmt.Sprint(args...))
+}
+
+func (g *GRPCLogger) Fatalln(args ...interface{}) {
+	g.write(log.FatalLevel, 0, fmt.Sprintln(args...))
+}
+
+func (g *GRPCLogger) Fatalf(format string, args ...interface{}) {
+	g.write(log.FatalLevel, 0, fmt.Sprintf(format, args...))
+}
+
+// InfoDepth, WarningDepth, ErrorDepth and FatalDepth implement the
+// DepthLoggerV2 interface of gRPC to report the callers of its component loggers
+
+func (g *GRPCLogger) InfoDepth(depth int, args ...interface{}) {
+	g.write(log.InfoLevel, depth, fmt.Sprintln(args...))
+}
+
+func (g *GRPCLogger) WarningDepth(depth int, args ...interface{}) {
+	g.write(log.WarnLevel, depth, fmt.Sprintln(args...))
+}
+
+func (g *GRPCLogger) ErrorDepth(depth int, args ...interface{}) {
+	g.write(log.ErrorLevel, depth, fmt.Sprintln(args...))
+}
+
+func (g *GRPCLogger) FatalDepth(depth int, args ...interface{}) {
+	g.write(log.FatalLevel, depth, fmt.Sprintln(args...))
+}
+
+// V reports whether the verbosity is at least l
+func (g *GRPCLogger) V(l int) bool {
+	return l <= g.verbosity
+}
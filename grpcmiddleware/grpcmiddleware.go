@@ -0,0 +1,73 @@
+// Package grpcmiddleware propagates request ids through gRPC metadata using
+// the IDGenerator of package log
+package grpcmiddleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/olee12/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// requestIDMetadataKey is the metadata key equivalent of log.RequestIDHeader
+var requestIDMetadataKey = strings.ToLower(log.RequestIDHeader)
+
+// serverContext keeps a valid request id sent by the caller or creates one
+func serverContext(ctx context.Context) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDMetadataKey); len(values) > 0 {
+			id = values[0]
+		}
+	}
+	ctx, id = log.EnsureRequestID(ctx, id)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadataKey, id))
+	return ctx
+}
+
+// clientContext adds the request id of the context to the outgoing metadata
+func clientContext(ctx context.Context) context.Context {
+	if id, ok := log.RequestIDFromContext(ctx); ok {
+		return metadata.AppendToOutgoingContext(ctx, requestIDMetadataKey, id)
+	}
+	return ctx
+}
+
+// UnaryServerInterceptor stores the request id in the handler context
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(serverContext(ctx), req)
+	}
+}
+
+// StreamServerInterceptor stores the request id in the stream context
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{ServerStream: ss, ctx: serverContext(ss.Context())})
+	}
+}
+
+// UnaryClientInterceptor sends the request id of the context to the server
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(clientContext(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor sends the request id of the context to the server
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(clientContext(ctx), desc, cc, method, opts...)
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
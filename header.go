@@ -0,0 +1,207 @@
+package log
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// FormatVersion is the version of the record layout written by this package.
+// It is bumped whenever keys or encodings change in a way parsers must know about.
+const FormatVersion = 1
+
+// FormatHeaderMessage is the message of the header record written at the top
+// of a log file when Config.FormatHeader is enabled
+const FormatHeaderMessage = "log format header"
+
+// FormatFooterMessage is the message of the record ending a log file which
+// is rotated when Config.FormatHeader is enabled
+const FormatFooterMessage = "log file rotated"
+
+// footerReserve is the room left for the footer when rotating for the size,
+// so it does not make lumberjack rotate first
+const footerReserve = 512
+
+// processStart is reported as the start time in the header records
+var processStart = time.Now()
+
+// headerWriter writes a header record before the first entry of a new file
+// and a footer record after the last one of a rotated file. It tracks the
+// size of the file to rotate it itself before lumberjack would, so the new
+// file gets its header.
+type headerWriter struct {
+	mu   sync.Mutex
+	file *lumberjack.Logger
+	// out writes to file, encrypting when Config.Encryption is set
+	out     logFile
+	config  Config
+	checked bool
+	size    int64
+}
+
+// logFile is the rolling file written by headerWriter, rotating and closing
+// it ends an encrypted file with its final chunk
+type logFile interface {
+	io.Writer
+	Rotate() error
+	Close() error
+}
+
+func newHeaderWriter(file *lumberjack.Logger, out logFile, config Config) *headerWriter {
+	return &headerWriter{file: file, out: out, config: config}
+}
+
+// written returns the bytes out writes to the file for n bytes
+func (hw *headerWriter) written(n int) int64 {
+	if c := hw.config.fileCipher; c != nil {
+		return c.sealedSize(n)
+	}
+	return int64(n)
+}
+
+func (hw *headerWriter) Write(p []byte) (int, error) {
+	hw.mu.Lock()
+	defer hw.mu.Unlock()
+
+	if hw.checked && hw.size+hw.written(len(p))+footerReserve > maxFileSize(hw.file) {
+		if err := hw.rotate("size"); err != nil {
+			return 0, err
+		}
+	}
+	if !hw.checked {
+		hw.checked = true
+		hw.size = 0
+		if info, err := os.Stat(hw.file.Filename); err == nil {
+			hw.size = info.Size()
+		}
+		if hw.size == 0 {
+			if err := hw.writeRecord(formatHeader(hw.config)); err != nil {
+				return 0, err
+			}
+		}
+	}
+	n, err := hw.out.Write(p)
+	hw.size += hw.written(n)
+	return n, err
+}
+
+// writeRecord writes a header or footer record, encoding errors drop it
+func (hw *headerWriter) writeRecord(record []byte, err error) error {
+	if err != nil {
+		return nil
+	}
+	if _, err := hw.out.Write(record); err != nil {
+		return err
+	}
+	hw.size += hw.written(len(record))
+	return nil
+}
+
+// rotate ends the file with the footer and starts a new one, hw.mu must be held
+func (hw *headerWriter) rotate(reason string) error {
+	if hw.checked {
+		if err := hw.writeRecord(formatFooter(hw.config, reason)); err != nil {
+			return err
+		}
+	}
+	hw.checked = false
+	return hw.out.Rotate()
+}
+
+// Rotate starts a new file which gets its own header
+func (hw *headerWriter) Rotate() error {
+	hw.mu.Lock()
+	defer hw.mu.Unlock()
+	return hw.rotate("rotate")
+}
+
+// Reopen closes the file after its footer, the file moved away by an
+// external rotation, a new one at its path gets its own header
+func (hw *headerWriter) Reopen() error {
+	hw.mu.Lock()
+	defer hw.mu.Unlock()
+	if hw.checked {
+		if err := hw.writeRecord(formatFooter(hw.config, "reopen")); err != nil {
+			return err
+		}
+	}
+	hw.checked = false
+	if r, ok := hw.out.(interface{ Reopen() error }); ok {
+		return r.Reopen()
+	}
+	return hw.file.Close()
+}
+
+func (hw *headerWriter) Sync() error {
+	return nil
+}
+
+func (hw *headerWriter) Close() error {
+	return hw.out.Close()
+}
+
+// formatHeader encodes the header record with the same encoder as regular
+// entries so it can be read by any parser that understands the file
+func formatHeader(config Config) ([]byte, error) {
+	config.EncodeLogsAsJson = sinkJSON(config, config.FileEncoding)
+	encCfg := newEncoderConfig(config)
+	encoding := "console"
+	if config.EncodeLogsAsJson {
+		encoding = "json"
+	}
+	hostname, _ := os.Hostname()
+	executable, _ := os.Executable()
+
+	fields := []zapcore.Field{
+		zap.Int("format_version", FormatVersion),
+		zap.String("encoding", encoding),
+		zap.Object("schema", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("time_key", encCfg.TimeKey)
+			enc.AddString("level_key", encCfg.LevelKey)
+			enc.AddString("name_key", encCfg.NameKey)
+			enc.AddString("caller_key", encCfg.CallerKey)
+			enc.AddString("message_key", encCfg.MessageKey)
+			enc.AddString("stacktrace_key", encCfg.StacktraceKey)
+			enc.AddString("console_separator", encCfg.ConsoleSeparator)
+			return nil
+		})),
+		zap.Int("pid", os.Getpid()),
+		zap.String("hostname", hostname),
+		zap.String("executable", executable),
+		zap.Time("started", processStart),
+	}
+	fields = append(fields, buildInfoFields()...)
+	fields = appendFields(fields, config.HeaderFields, true)
+	if config.TTLClass != "" {
+		fields = append(fields, TTL(config.TTLClass))
+	}
+	return formatRecord(config, FormatHeaderMessage, fields)
+}
+
+// formatFooter encodes the footer record of a file rotated for the reason
+func formatFooter(config Config, reason string) ([]byte, error) {
+	config.EncodeLogsAsJson = sinkJSON(config, config.FileEncoding)
+	return formatRecord(config, FormatFooterMessage, []zapcore.Field{
+		zap.Int("format_version", FormatVersion),
+		zap.String("reason", reason),
+		zap.Int("pid", os.Getpid()),
+	})
+}
+
+func formatRecord(config Config, msg string, fields []zapcore.Field) ([]byte, error) {
+	buf, err := newEncoder(config).EncodeEntry(zapcore.Entry{
+		Level:   InfoLevel,
+		Time:    time.Now(),
+		Message: msg,
+	}, fields)
+	if err != nil {
+		return nil, err
+	}
+	defer buf.Free()
+	return append([]byte(nil), buf.Bytes()...), nil
+}
@@ -0,0 +1,45 @@
+package log
+
+import (
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	hostInfoOnce sync.Once
+	hostInfo     []zapcore.Field
+)
+
+// hostInfoFields returns the host and process metadata added by
+// Config.IncludeHostInfo. It is computed once per process.
+func hostInfoFields() []zapcore.Field {
+	hostInfoOnce.Do(func() {
+		hostname, _ := os.Hostname()
+		hostInfo = []zapcore.Field{
+			zap.String("hostname", hostname),
+			zap.Int("pid", os.Getpid()),
+			zap.String("go_version", runtime.Version()),
+		}
+		if info, ok := debug.ReadBuildInfo(); ok {
+			hostInfo = append(hostInfo,
+				zap.String("module", info.Main.Path),
+				zap.String("module_version", info.Main.Version))
+			for _, setting := range info.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					hostInfo = append(hostInfo, zap.String("vcs_revision", setting.Value))
+				case "vcs.time":
+					hostInfo = append(hostInfo, zap.String("vcs_time", setting.Value))
+				case "vcs.modified":
+					hostInfo = append(hostInfo, zap.String("vcs_modified", setting.Value))
+				}
+			}
+		}
+	})
+	return hostInfo
+}
@@ -0,0 +1,37 @@
+package log
+
+import (
+	"net/http"
+)
+
+// RequestIDHeader carries the request id between services
+const RequestIDHeader = "X-Request-ID"
+
+// HTTPMiddleware propagates the request id: it keeps a valid id sent by the
+// caller, creates one with the current IDGenerator otherwise, stores it in the
+// request context and echoes it in the response header
+func HTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, id := EnsureRequestID(r.Context(), r.Header.Get(RequestIDHeader))
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestIDTransport adds the request id of the request context to outgoing
+// requests. A nil Base uses http.DefaultTransport.
+type RequestIDTransport struct {
+	Base http.RoundTripper
+}
+
+func (t *RequestIDTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if id, ok := RequestIDFromContext(r.Context()); ok && r.Header.Get(RequestIDHeader) == "" {
+		r = r.Clone(r.Context())
+		r.Header.Set(RequestIDHeader, id)
+	}
+	return base.RoundTrip(r)
+}
@@ -0,0 +1,213 @@
+package log
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RequestIDKey is the field name of the request id stamped by FromContext
+const RequestIDKey = "request_id"
+
+var requestIDKey = key(4)
+
+// IDGenerator creates and validates the ids propagated between services, so an
+// org-wide id scheme can be kept by the middleware and the context helpers
+type IDGenerator interface {
+	// NewID returns a new unique id
+	NewID() string
+	// Valid reports whether an id received from a caller is well formed
+	Valid(id string) bool
+}
+
+var idGenerator atomic.Pointer[IDGenerator]
+
+// SetIDGenerator sets the generator used by NewRequestID and the middleware.
+// The default is UUIDGenerator.
+func SetIDGenerator(g IDGenerator) {
+	idGenerator.Store(&g)
+}
+
+// GetIDGenerator returns the generator set by SetIDGenerator
+func GetIDGenerator() IDGenerator {
+	if g := idGenerator.Load(); g != nil {
+		return *g
+	}
+	return UUIDGenerator{}
+}
+
+// NewRequestID returns a new id from the current generator
+func NewRequestID() string {
+	return GetIDGenerator().NewID()
+}
+
+// ContextWithRequestID stores the request id in the context, FromContext and
+// the *Ctx methods stamp it on records
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFromContext returns the request id stored by ContextWithRequestID
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey).(string)
+	return id, ok
+}
+
+// EnsureRequestID returns the context with a request id, keeping id when it is
+// valid for the current generator and creating a new one otherwise
+func EnsureRequestID(ctx context.Context, id string) (context.Context, string) {
+	g := GetIDGenerator()
+	if id == "" || !g.Valid(id) {
+		id = g.NewID()
+	}
+	return ContextWithRequestID(ctx, id), id
+}
+
+// IDGeneratorFunc adapts a function to IDGenerator, accepting any non-empty id
+type IDGeneratorFunc func() string
+
+func (f IDGeneratorFunc) NewID() string {
+	return f()
+}
+
+func (f IDGeneratorFunc) Valid(id string) bool {
+	return id != ""
+}
+
+// UUIDGenerator creates random (version 4) UUIDs
+type UUIDGenerator struct{}
+
+func (UUIDGenerator) NewID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:])
+	return string(buf)
+}
+
+func (UUIDGenerator) Valid(id string) bool {
+	if len(id) != 36 {
+		return false
+	}
+	for i, c := range id {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !isHex(c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isHex(c rune) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// crockford is the base32 alphabet of ULIDs
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULIDGenerator creates lexicographically sortable ULIDs
+type ULIDGenerator struct{}
+
+func (ULIDGenerator) NewID() string {
+	var b [16]byte
+	ms := uint64(time.Now().UnixMilli())
+	b[0], b[1], b[2] = byte(ms>>40), byte(ms>>32), byte(ms>>24)
+	b[3], b[4], b[5] = byte(ms>>16), byte(ms>>8), byte(ms)
+	_, _ = rand.Read(b[6:])
+
+	// 128 bits encode to 26 characters of 5 bits, the first one holding 3 bits
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out)
+}
+
+func (ULIDGenerator) Valid(id string) bool {
+	if len(id) != 26 || id[0] > '7' {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if !containsByte(crockford, id[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsByte(s string, c byte) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] == c {
+			return true
+		}
+	}
+	return false
+}
+
+// snowflakeEpoch is the start of the snowflake timestamps, 2020-01-01 UTC
+var snowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// SnowflakeGenerator creates 63 bit ids made of a 41 bit millisecond
+// timestamp, a 10 bit node id and a 12 bit sequence, rendered in decimal
+type SnowflakeGenerator struct {
+	node     int64
+	mu       sync.Mutex
+	lastMS   int64
+	sequence int64
+}
+
+// NewSnowflakeGenerator returns a generator for the node, which must be unique
+// among the processes sharing the id space (0-1023)
+func NewSnowflakeGenerator(node int64) *SnowflakeGenerator {
+	return &SnowflakeGenerator{node: node & 0x3ff}
+}
+
+func (g *SnowflakeGenerator) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := time.Since(snowflakeEpoch).Milliseconds()
+	if ms <= g.lastMS {
+		ms = g.lastMS
+		g.sequence = (g.sequence + 1) & 0xfff
+		if g.sequence == 0 {
+			// sequence exhausted for this millisecond, borrow the next one
+			ms++
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.lastMS = ms
+	return strconv.FormatInt(ms<<22|g.node<<12|g.sequence, 10)
+}
+
+func (g *SnowflakeGenerator) Valid(id string) bool {
+	n, err := strconv.ParseInt(id, 10, 64)
+	return err == nil && n > 0
+}
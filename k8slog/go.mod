@@ -0,0 +1,19 @@
+module github.com/olee12/log/k8slog
+
+go 1.21
+
+replace github.com/olee12/log => ../
+
+require (
+	github.com/go-logr/logr v1.4.2
+	github.com/olee12/log v0.0.0-00010101000000-000000000000
+	go.uber.org/zap v1.26.0
+	k8s.io/klog/v2 v2.130.1
+)
+
+require (
+	go.uber.org/multierr v1.11.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	golang.org/x/term v0.21.0 // indirect
+	gopkg.in/natefinch/lumberjack.v2 v2.2.1 // indirect
+)
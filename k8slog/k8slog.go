@@ -0,0 +1,128 @@
+// Package k8slog connects Kubernetes libraries to package log: a logr.Logger
+// writing to a LogEntry and RedirectKlog installing it as the klog backend,
+// so operators and controllers have a single log stream and one rotation
+// policy.
+package k8slog
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/olee12/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"k8s.io/klog/v2"
+)
+
+// NewLogr returns a logr.Logger writing to le, nil writes to the default
+// logger at the time of every entry. V-levels map to levels below info like
+// zapr does: V(1) is debug, V(2) trace. Names are joined with dots into the
+// logger field.
+func NewLogr(le *log.LogEntry) logr.Logger {
+	return logr.New(&sink{le: le})
+}
+
+// RedirectKlog makes klog write through le, nil meaning the default logger,
+// and flush it with klog.Flush
+func RedirectKlog(le *log.LogEntry) {
+	klog.SetLoggerWithOptions(NewLogr(le), klog.FlushLogger(func() {
+		_ = logger(le).Sync()
+	}))
+}
+
+func logger(le *log.LogEntry) *log.LogEntry {
+	if le != nil {
+		return le
+	}
+	return log.Default()
+}
+
+// sink implements logr.LogSink and logr.CallDepthLogSink
+type sink struct {
+	le     *log.LogEntry
+	name   string
+	fields []zapcore.Field
+	// depth is the number of frames between the caller and the sink methods
+	depth int
+}
+
+func (s *sink) Init(info logr.RuntimeInfo) {
+	s.depth += info.CallDepth
+}
+
+func vLevel(level int) log.Level {
+	return log.Level(-level)
+}
+
+func (s *sink) Enabled(level int) bool {
+	return logger(s.le).Enabled(vLevel(level))
+}
+
+func (s *sink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.write(vLevel(level), msg, nil, keysAndValues)
+}
+
+func (s *sink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.write(log.ErrorLevel, msg, err, keysAndValues)
+}
+
+// write logs the entry, skipping write and the sink method besides the logr frames
+func (s *sink) write(level log.Level, msg string, err error, keysAndValues []interface{}) {
+	ce := logger(s.le).WithCallerSkip(2+s.depth).Check(level, msg)
+	if ce == nil {
+		return
+	}
+	fields := make([]zapcore.Field, 0, 2+len(s.fields)+len(keysAndValues)/2)
+	if s.name != "" {
+		fields = append(fields, zap.String(log.NameKey, s.name))
+	}
+	fields = append(fields, s.fields...)
+	fields = appendKeysAndValues(fields, keysAndValues)
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+	ce.Write(fields...)
+}
+
+// appendKeysAndValues converts logr's pairs, a dangling key gets a nil value
+func appendKeysAndValues(fields []zapcore.Field, keysAndValues []interface{}) []zapcore.Field {
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+		var value interface{}
+		if i+1 < len(keysAndValues) {
+			value = keysAndValues[i+1]
+		}
+		fields = append(fields, zap.Any(key, value))
+	}
+	return fields
+}
+
+func (s *sink) clone() *sink {
+	clone := *s
+	clone.fields = append([]zapcore.Field(nil), s.fields...)
+	return &clone
+}
+
+func (s *sink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	clone := s.clone()
+	clone.fields = appendKeysAndValues(clone.fields, keysAndValues)
+	return clone
+}
+
+func (s *sink) WithName(name string) logr.LogSink {
+	clone := s.clone()
+	if clone.name != "" {
+		name = clone.name + "." + name
+	}
+	clone.name = name
+	return clone
+}
+
+func (s *sink) WithCallDepth(depth int) logr.LogSink {
+	clone := s.clone()
+	clone.depth += depth
+	return clone
+}
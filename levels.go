@@ -0,0 +1,201 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// TraceLevel logs are finer grained than debug ones
+	TraceLevel = DebugLevel - 1
+	// NoticeLevel names the entries logged with the Notice functions. zap has no
+	// level between info and warn, so notice entries are enabled, sampled and
+	// routed as info ones and only written as "notice". As a threshold, in
+	// SetLevel or Config.Level, it is the same as InfoLevel. zapcore levels are
+	// consecutive integers, info being 0 and warn 1, so there is no value
+	// between them; 10 is away from the zap levels and from the ones other
+	// packages add below debug, it is never compared to them.
+	NoticeLevel = zapcore.Level(10)
+)
+
+// levelAliases are the names accepted by ParseLevel besides the level names
+var levelAliases = map[string]Level{
+	"warning": WarnLevel,
+	"err":     ErrorLevel,
+}
+
+// ParseLevel parses a level name, including trace, notice and the aliases
+// "warning" and "err", in any case
+func ParseLevel(text string) (Level, error) {
+	name := strings.ToLower(strings.TrimSpace(text))
+	switch name {
+	case "trace":
+		return TraceLevel, nil
+	case "notice":
+		return NoticeLevel, nil
+	}
+	if l, ok := levelAliases[name]; ok {
+		return l, nil
+	}
+	return zapcore.ParseLevel(name)
+}
+
+// LevelName returns the lowercase name of the level, the inverse of ParseLevel
+func LevelName(l Level) string {
+	switch l {
+	case TraceLevel:
+		return "trace"
+	case NoticeLevel:
+		return "notice"
+	}
+	return l.String()
+}
+
+// LevelText is a Level read and written as text with ParseLevel and
+// LevelName, for flags and config or env loaders, e.g.
+// flag.TextVar(&level, "level", log.LevelText(log.InfoLevel), "log level")
+type LevelText Level
+
+func (l LevelText) MarshalText() ([]byte, error) {
+	return []byte(LevelName(Level(l))), nil
+}
+
+func (l *LevelText) UnmarshalText(text []byte) error {
+	level, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = LevelText(level)
+	return nil
+}
+
+func (l LevelText) String() string {
+	return LevelName(Level(l))
+}
+
+// thresholdLevel returns the level to enable entries from
+func thresholdLevel(l Level) Level {
+	if l == NoticeLevel {
+		return InfoLevel
+	}
+	return l
+}
+
+// noticeMarker is added to the fields of notice entries, it is skipped by
+// the encoders and makes noticeEncoder write the entry as notice
+type noticeMarker struct{}
+
+var noticeField = zapcore.Field{Type: zapcore.SkipType, Interface: noticeMarker{}}
+
+func noticeFields(fields []zapcore.Field) []zapcore.Field {
+	return append(fields[:len(fields):len(fields)], noticeField)
+}
+
+// noticeEncoder writes the entries carrying the notice marker at NoticeLevel
+type noticeEncoder struct {
+	zapcore.Encoder
+}
+
+func (e noticeEncoder) Clone() zapcore.Encoder {
+	return noticeEncoder{e.Encoder.Clone()}
+}
+
+func (e noticeEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	if hasNoticeMarker(fields) {
+		ent.Level = NoticeLevel
+	}
+	return e.Encoder.EncodeEntry(ent, fields)
+}
+
+// hasNoticeMarker reports whether the fields carry the notice marker, which
+// is appended last
+func hasNoticeMarker(fields []zapcore.Field) bool {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if _, ok := fields[i].Interface.(noticeMarker); ok && fields[i].Type == zapcore.SkipType {
+			return true
+		}
+	}
+	return false
+}
+
+// extendLevelEncoder makes the level encoder write trace and notice. Encoders
+// knowing these levels are kept, others get the names in the case and
+// decoration they use for debug and info.
+func extendLevelEncoder(encode zapcore.LevelEncoder) zapcore.LevelEncoder {
+	trace := extraLevelName(encode, TraceLevel, DebugLevel, "trace")
+	notice := extraLevelName(encode, NoticeLevel, InfoLevel, "notice")
+	return func(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+		switch l {
+		case TraceLevel:
+			enc.AppendString(trace)
+		case NoticeLevel:
+			enc.AppendString(notice)
+		default:
+			encode(l, enc)
+		}
+	}
+}
+
+func extraLevelName(encode zapcore.LevelEncoder, level, like zapcore.Level, name string) string {
+	// zap writes unknown levels as Level(n), possibly colored
+	if s := encodeLevel(encode, level); s != "" && !strings.Contains(s, level.String()) && !strings.Contains(s, level.CapitalString()) {
+		return s
+	}
+	s := encodeLevel(encode, like)
+	replaced := strings.NewReplacer(like.String(), name, like.CapitalString(), strings.ToUpper(name)).Replace(s)
+	if replaced == s {
+		return name
+	}
+	return replaced
+}
+
+// encodeLevel returns what the encoder writes for the level
+func encodeLevel(encode zapcore.LevelEncoder, level zapcore.Level) string {
+	enc := zapcore.NewMapObjectEncoder()
+	_ = enc.AddArray("level", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		encode(level, arr)
+		return nil
+	}))
+	if values, ok := enc.Fields["level"].([]interface{}); ok && len(values) == 1 {
+		s, _ := values[0].(string)
+		return s
+	}
+	return ""
+}
+
+// sweeten converts loosely typed key-value pairs like the sugared logger does
+func sweeten(keysAndValues []interface{}) []zapcore.Field {
+	return appendSweetened(make([]zapcore.Field, 0, len(keysAndValues)/2), keysAndValues)
+}
+
+// appendSweetened appends the fields of the key-value pairs to fields
+func appendSweetened(fields []zapcore.Field, keysAndValues []interface{}) []zapcore.Field {
+	for i := 0; i < len(keysAndValues); {
+		if f, ok := keysAndValues[i].(zapcore.Field); ok {
+			fields = append(fields, f)
+			i++
+			continue
+		}
+		if i == len(keysAndValues)-1 {
+			fields = append(fields, zap.Any("ignored", keysAndValues[i]))
+			break
+		}
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+		fields = append(fields, zap.Any(key, keysAndValues[i+1]))
+		i += 2
+	}
+	return fields
+}
+
+// sprintln formats like the sugared logger's ln methods
+func sprintln(args []interface{}) string {
+	msg := fmt.Sprintln(args...)
+	return msg[:len(msg)-1]
+}
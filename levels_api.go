@@ -0,0 +1,198 @@
+package log
+
+import (
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// The trace and notice functions check the entry themselves, zap has no
+// methods for these levels. Like for the other levels, messages are formatted
+// only when the level is enabled and before the entry is checked. Notice
+// entries are info entries carrying the notice marker, see NoticeLevel.
+
+func Trace(msg string) {
+	if ce := Default().infoLogger.Check(TraceLevel, msg); ce != nil {
+		ce.Write()
+	}
+}
+
+func Tracef(template string, args ...interface{}) {
+	logger := Default().infoLogger
+	if !logger.Core().Enabled(TraceLevel) {
+		return
+	}
+	if ce := logger.Check(TraceLevel, fmt.Sprintf(template, args...)); ce != nil {
+		ce.Write()
+	}
+}
+
+func Traceln(args ...interface{}) {
+	logger := Default().infoLogger
+	if !logger.Core().Enabled(TraceLevel) {
+		return
+	}
+	if ce := logger.Check(TraceLevel, sprintln(args)); ce != nil {
+		ce.Write()
+	}
+}
+
+func Tracew(msg string, keysAndValues ...interface{}) {
+	if ce := Default().infoLogger.Check(TraceLevel, msg); ce != nil {
+		writePairs(ce, keysAndValues)
+	}
+}
+
+func Tracev(msg string, fields ...zapcore.Field) {
+	if ce := Default().infoLogger.Check(TraceLevel, msg); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+func TraceWith(msg string, fields Fields) {
+	le := Default()
+	if ce := le.infoLogger.Check(TraceLevel, msg); ce != nil {
+		zfields := getFieldSlice(fields, le.sortFields)
+		ce.Write(*zfields...)
+		putFieldSlice(zfields)
+	}
+}
+
+func Notice(msg string) {
+	if ce := Default().infoLogger.Check(InfoLevel, msg); ce != nil {
+		ce.Write(noticeField)
+	}
+}
+
+func Noticef(template string, args ...interface{}) {
+	logger := Default().infoLogger
+	if !logger.Core().Enabled(InfoLevel) {
+		return
+	}
+	if ce := logger.Check(InfoLevel, fmt.Sprintf(template, args...)); ce != nil {
+		ce.Write(noticeField)
+	}
+}
+
+func Noticeln(args ...interface{}) {
+	logger := Default().infoLogger
+	if !logger.Core().Enabled(InfoLevel) {
+		return
+	}
+	if ce := logger.Check(InfoLevel, sprintln(args)); ce != nil {
+		ce.Write(noticeField)
+	}
+}
+
+func Noticew(msg string, keysAndValues ...interface{}) {
+	if ce := Default().infoLogger.Check(InfoLevel, msg); ce != nil {
+		ce.Write(noticeFields(sweeten(keysAndValues))...)
+	}
+}
+
+func Noticev(msg string, fields ...zapcore.Field) {
+	if ce := Default().infoLogger.Check(InfoLevel, msg); ce != nil {
+		ce.Write(noticeFields(fields)...)
+	}
+}
+
+func NoticeWith(msg string, fields Fields) {
+	le := Default()
+	if ce := le.infoLogger.Check(InfoLevel, msg); ce != nil {
+		zfields := getFieldSlice(fields, le.sortFields)
+		ce.Write(noticeFields(*zfields)...)
+		putFieldSlice(zfields)
+	}
+}
+
+func (le *LogEntry) Trace(msg string) {
+	if ce := le.infoLogger.Check(TraceLevel, msg); ce != nil {
+		ce.Write()
+	}
+}
+
+func (le *LogEntry) Tracef(template string, args ...interface{}) {
+	logger := le.infoLogger
+	if !logger.Core().Enabled(TraceLevel) {
+		return
+	}
+	if ce := logger.Check(TraceLevel, fmt.Sprintf(template, args...)); ce != nil {
+		ce.Write()
+	}
+}
+
+func (le *LogEntry) Traceln(args ...interface{}) {
+	logger := le.infoLogger
+	if !logger.Core().Enabled(TraceLevel) {
+		return
+	}
+	if ce := logger.Check(TraceLevel, sprintln(args)); ce != nil {
+		ce.Write()
+	}
+}
+
+func (le *LogEntry) Tracew(msg string, keysAndValues ...interface{}) {
+	if ce := le.infoLogger.Check(TraceLevel, msg); ce != nil {
+		writePairs(ce, keysAndValues)
+	}
+}
+
+func (le *LogEntry) Tracev(msg string, fields ...zapcore.Field) {
+	if ce := le.infoLogger.Check(TraceLevel, msg); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+func (le *LogEntry) TraceWith(msg string, fields Fields) {
+	if ce := le.infoLogger.Check(TraceLevel, msg); ce != nil {
+		zfields := getFieldSlice(fields, le.sortFields)
+		ce.Write(*zfields...)
+		putFieldSlice(zfields)
+	}
+}
+
+func (le *LogEntry) Notice(msg string) {
+	if ce := le.infoLogger.Check(InfoLevel, msg); ce != nil {
+		ce.Write(noticeField)
+	}
+}
+
+func (le *LogEntry) Noticef(template string, args ...interface{}) {
+	logger := le.infoLogger
+	if !logger.Core().Enabled(InfoLevel) {
+		return
+	}
+	if ce := logger.Check(InfoLevel, fmt.Sprintf(template, args...)); ce != nil {
+		ce.Write(noticeField)
+	}
+}
+
+func (le *LogEntry) Noticeln(args ...interface{}) {
+	logger := le.infoLogger
+	if !logger.Core().Enabled(InfoLevel) {
+		return
+	}
+	if ce := logger.Check(InfoLevel, sprintln(args)); ce != nil {
+		ce.Write(noticeField)
+	}
+}
+
+func (le *LogEntry) Noticew(msg string, keysAndValues ...interface{}) {
+	if ce := le.infoLogger.Check(InfoLevel, msg); ce != nil {
+		ce.Write(noticeFields(sweeten(keysAndValues))...)
+	}
+}
+
+func (le *LogEntry) Noticev(msg string, fields ...zapcore.Field) {
+	if ce := le.infoLogger.Check(InfoLevel, msg); ce != nil {
+		ce.Write(noticeFields(fields)...)
+	}
+}
+
+func (le *LogEntry) NoticeWith(msg string, fields Fields) {
+	if ce := le.infoLogger.Check(InfoLevel, msg); ce != nil {
+		zfields := getFieldSlice(fields, le.sortFields)
+		ce.Write(noticeFields(*zfields)...)
+		putFieldSlice(zfields)
+	}
+}
@@ -2,11 +2,18 @@ package log
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"os"
-	"path"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -18,9 +25,112 @@ var loggerKey = key(1)
 
 type Level = zapcore.Level
 
-// DefaultZapLogger is the default logger instance that should be used to log
-// It's assigned a default value here for tests (which do not call log.Configure())
-var DefaultZapLogger = newZapLogger(defaultConfig, os.Stdout, os.Stderr, true)
+// defaultState is the default logger with its level and config, swapped as
+// a whole so readers never see a half-initialized logger
+type defaultState struct {
+	logger *LogEntry
+	level  zap.AtomicLevel
+	config Config
+}
+
+var (
+	// defaults holds the default logger instance that should be used to log.
+	// It has a value from the start for tests, which do not call Configure.
+	defaults = initDefault()
+	// configureMu serializes the replacements of the default logger
+	configureMu sync.Mutex
+	// configured is set by Configure, ConfigureOnce, Reconfigure and SetDefault
+	configured bool
+)
+
+// ErrAlreadyConfigured is returned by ConfigureOnce once the default logger is configured
+var ErrAlreadyConfigured = errors.New("log: default logger already configured")
+
+// ErrFileFallback is wrapped by the error FileFallback returns when the log
+// files cannot be created. The logger works nonetheless, writing to stderr
+// instead of the files, and Configure succeeds.
+var ErrFileFallback = errors.New("log: log files unavailable, logging to stderr")
+
+func initDefault() *atomic.Pointer[defaultState] {
+	level := zap.NewAtomicLevelAt(thresholdLevel(defaultConfig.Level))
+	p := &atomic.Pointer[defaultState]{}
+	p.Store(&defaultState{logger: newZapLogger(defaultConfig, consoleFile(os.Stdout), consoleFile(os.Stderr), level), level: level, config: defaultConfig})
+	return p
+}
+
+// Default returns the default logger used by the package level functions.
+// It is safe to call while another goroutine replaces the logger.
+func Default() *LogEntry {
+	return defaults.Load().logger
+}
+
+// DefaultZapLogger returns the default logger as a zap logger, writing the
+// debug and info entries to its info output and the others to its error
+// output. It does not follow later Configure calls.
+//
+// Deprecated: use Default. DefaultZapLogger was the variable holding the
+// default logger, which raced with Configure.
+func DefaultZapLogger() *zap.Logger {
+	le := Default()
+	errCore := le.errorLogger.Core()
+	// the callers skipped for the LogEntry methods are not there for zap's
+	return le.infoLogger.WithOptions(zap.AddCallerSkip(-1), zap.WrapCore(func(info zapcore.Core) zapcore.Core {
+		return &routeCore{info: info, err: errCore}
+	}))
+}
+
+// routeCore writes the entries up to info level to info and the others to err
+type routeCore struct {
+	info, err zapcore.Core
+}
+
+func (c *routeCore) coreFor(lvl zapcore.Level) zapcore.Core {
+	if lvl <= InfoLevel {
+		return c.info
+	}
+	return c.err
+}
+
+func (c *routeCore) Enabled(lvl zapcore.Level) bool {
+	return c.coreFor(lvl).Enabled(lvl)
+}
+
+func (c *routeCore) With(fields []zapcore.Field) zapcore.Core {
+	return &routeCore{info: c.info.With(fields), err: c.err.With(fields)}
+}
+
+func (c *routeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return c.coreFor(ent.Level).Check(ent, ce)
+}
+
+func (c *routeCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.coreFor(ent.Level).Write(ent, fields)
+}
+
+func (c *routeCore) Sync() error {
+	return multierr.Append(c.info.Sync(), c.err.Sync())
+}
+
+// SetDefault makes le the default logger, e.g. one built with NewLogEntry.
+// SetLevel then changes the level of le and the loggers derived from it,
+// SetOutput keeps the config of the previous default logger.
+func SetDefault(le *LogEntry) {
+	configureMu.Lock()
+	defer configureMu.Unlock()
+
+	level := le.level
+	if level == (zap.AtomicLevel{}) {
+		// loggers not built by this package, like NewNop, have no level to change
+		level = zap.NewAtomicLevel()
+	}
+	swapDefault(le, level, defaults.Load().config)
+	configured = true
+}
+
+// swapDefault replaces the default logger, configureMu must be held
+func swapDefault(logger *LogEntry, level zap.AtomicLevel, config Config) {
+	defaults.Store(&defaultState{logger: logger, level: level, config: config})
+}
 
 const (
 	DebugLevel  = zapcore.DebugLevel
@@ -32,6 +142,16 @@ const (
 	FatalLevel  = zapcore.FatalLevel
 )
 
+// Keys of the entry metadata in encoded records
+const (
+	TimeKey       = "@t"
+	LevelKey      = "lvl"
+	NameKey       = "logger"
+	CallerKey     = "caller"
+	MessageKey    = "msg"
+	StacktraceKey = "stacktrace"
+)
+
 // Config for logging
 type Config struct {
 	// Level set log level
@@ -44,10 +164,19 @@ type Config struct {
 	ConsoleLoggingEnabled bool
 	// CallerEnabled makes the caller log to a file
 	CallerEnabled bool
+	// CallerWithFunc adds the function name to the caller, see CallerFunc
+	CallerWithFunc CallerFunc
 	// CallerSkip increases the number of callers skipped by caller
 	CallerSkip int
-	// Directory to log to when file logging is enabled
+	// Directory to log to when file logging is enabled. A relative directory
+	// is resolved against BaseDir.
 	Directory string
+	// BaseDir is the directory relative Directory values are resolved against.
+	// It defaults to the directory of the executable, not the working directory.
+	BaseDir string
+	// ConfineToBaseDir makes configuring fail if Directory resolves to a
+	// path outside of BaseDir
+	ConfineToBaseDir bool
 	// Filename is the name of the log file which will be placed inside the directory
 	Filename string
 	// MaxSize is the maximum size in megabytes of the log file before it gets
@@ -57,39 +186,215 @@ type Config struct {
 	MaxBackups int
 	// MaxAge the max age in days to keep a log file
 	MaxAge int
+	// ExternalRotation leaves rotating the log files to another tool, e.g.
+	// logrotate: MaxSize, MaxBackups and MaxAge are ignored and the files are
+	// reopened by LogEntry.Reopen, see ReopenOnSignal for SIGHUP
+	ExternalRotation bool
+	// CoalesceWindow holds the entries written to the log files for up to the
+	// window and writes them at once, for spinning disks and network file
+	// systems. Sync and entries above error level write them right away.
+	CoalesceWindow time.Duration
 	// ConsoleInfoStream
 	ConsoleInfoStream *os.File
 	// ConsoleErrorStream
 	ConsoleErrorStream *os.File
+	// ContainerMode writes the entries of every level as JSON to the info
+	// console stream only, stdout by default, so container log drivers keep
+	// their order. The error file is still split when file logging is enabled.
+	ContainerMode bool
+	// AutoDetectTTY encodes the entries for the console, with colored levels
+	// on the console, when the info console stream, stdout by default, is a
+	// terminal and as JSON otherwise, overriding EncodeLogsAsJson. It does not
+	// apply with ContainerMode or CLIOutput.
+	AutoDetectTTY bool
+	// ConsoleEncoding and FileEncoding override EncodeLogsAsJson for the
+	// console and for the log files, e.g. readable console output next to
+	// JSON files. The extra sinks are encoded like the files.
+	ConsoleEncoding Encoding
+	FileEncoding    Encoding
+	// ConsoleLevel and FileLevel are the lowest levels written to the console
+	// and to the log files, nil follows Level. They apply next to the levels
+	// of the outputs, debug and info entries go to the info output only. See
+	// LeveledSink for the extra sinks.
+	ConsoleLevel *Level
+	FileLevel    *Level
+	// CLIOutput writes single human lines for command line tools, the level,
+	// the message and the fields as key=value, without time and caller and
+	// without the "logging configured" entries, see CLIMode
+	CLIOutput bool
 	// ConsoleSeparator the separator of fields of the log record
 	ConsoleSeparator string
 	// LevelEncoder use lowercase or capital case encoder
 	LevelEncoder zapcore.LevelEncoder
+	// DurationEncoder writes durations, e.g. zapcore.StringDurationEncoder for "1.2s".
+	// It defaults to nanoseconds.
+	DurationEncoder zapcore.DurationEncoder
+	// TimeFormat is a time.Format layout or one of the TimeFormat presets.
+	// It defaults to a short layout with milliseconds.
+	TimeFormat string
+	// TimeZone the entry times are written in, e.g. "UTC". It defaults to local time.
+	TimeZone string
+	// ExtraInfoSinks receive the debug and info entries in addition to
+	// the file and console
+	ExtraInfoSinks []zapcore.WriteSyncer
+	// ExtraErrorSinks receive the warn and error entries in addition to
+	// the file and console
+	ExtraErrorSinks []zapcore.WriteSyncer
+	// InfoSinkURLs are opened like ExtraInfoSinks, see RegisterSink
+	InfoSinkURLs []string
+	// ErrorSinkURLs are opened like ExtraErrorSinks, see RegisterSink
+	ErrorSinkURLs []string
+	// DeadLetter receives the records permanently rejected by the extra sinks
+	// together with the rejection reason, nil drops them. Its Directory is
+	// resolved like Directory.
+	DeadLetter *DeadLetterConfig
+	// Journald writes every entry to the systemd journal with priority and
+	// fields mapped to journal fields. JSON encoding is required for the fields.
+	Journald bool
+	// JournaldIdentifier is the SYSLOG_IDENTIFIER, it defaults to the executable name
+	JournaldIdentifier string
+	// WindowsEventLogSource writes error and more severe entries to the
+	// Windows Event Log under this source name. JSON encoding is recommended.
+	WindowsEventLogSource string
+	// InitialFields are added to every entry written by the logger
+	InitialFields Fields
+	// IncludeGoroutineID adds the id of the logging goroutine to every entry,
+	// to tell interleaved concurrent entries apart while debugging. WithWorker
+	// is a cheaper alternative.
+	IncludeGoroutineID bool
+	// MDC adds the fields of PushFields of the goroutine writing the entry,
+	// experimental
+	MDC bool
+	// IncludeHostInfo adds hostname, pid, go version and build info to every entry
+	IncludeHostInfo bool
+	// Startup selects the details of the "logging configured" entries and of
+	// LogStartup, such as the redacted config, for support tickets
+	Startup StartupDetails
+	// Sampling caps the volume of repeated entries, nil disables sampling
+	Sampling *SamplingConfig
+	// NearDeadline makes the Ctx methods add the time left as
+	// deadline_remaining when the deadline of the context is closer, it
+	// defaults to DefaultNearDeadline and a negative value disables it. Done
+	// contexts add their error as ctx_err.
+	NearDeadline time.Duration
+	// TraceCorrelation makes FromContext stamp the trace stored with
+	// ContextWithTrace, or else the span found by the extractors registered
+	// with RegisterTraceExtractor, (trace_id, span_id, sampled) on the
+	// returned logger. Entries of sampled traces are never dropped by
+	// Sampling.
+	TraceCorrelation bool
+	// SampledTraces makes the loggers of sampled traces write entries at every
+	// level, below Level too, so traced requests have complete logs. It
+	// implies TraceCorrelation.
+	//
+	// The sampler decides how much is logged then: the default sampler of the
+	// OpenTelemetry SDK, parent based and always on, samples every request,
+	// which turns on debug and trace logging for all the production traffic.
+	// Use it with a ratio based sampler.
+	SampledTraces bool
+	// PipelineStats records encode and write latencies, see PipelineStats()
+	PipelineStats bool
+	// FormatHeader writes a self-describing header record as the first entry
+	// of every new log file, rotated ones included, with the host, process,
+	// start time and build info, and a footer record ending rotated files
+	FormatHeader bool
+	// HeaderFields are added to the header records, e.g. the service and
+	// the environment, for files shipped individually
+	HeaderFields Fields
+	// ErrorReporters receive the entries at error level and above, see ErrorReporter
+	ErrorReporters []ErrorReporter
+	// SentryDSN sends the entries at error level and above to Sentry with their
+	// fields and stack. LogEntry.Sync and Close wait for queued events to be sent.
+	SentryDSN string
+	// SentrySampleRate is the fraction of the entries sent to Sentry, it defaults to 1
+	SentrySampleRate float64
+	// ErrorAlerts calls back or posts a webhook on bursts of error entries,
+	// nil disables alerting
+	ErrorAlerts *ErrorAlertConfig
+	// SortFields converts Fields maps, including nested ones, in key order so
+	// the output is reproducible. Fields given as Field values keep their order.
+	SortFields bool
+	// Schema checks every entry for required keys and value kinds, nil
+	// disables the check
+	Schema *SchemaConfig
+	// DuplicateKeys detects fields repeating a key, meant for development,
+	// see DuplicateKeysWarn and DuplicateKeysRename
+	DuplicateKeys DuplicateKeys
+	// MaxFieldBytes truncates longer string, binary, error and reflected
+	// values with an ellipsis, marking the entry with truncated=true. Zero means no limit.
+	MaxFieldBytes int
+	// MaxMessageBytes truncates longer messages the same way. Zero means no limit.
+	MaxMessageBytes int
+	// MessageRenderer rewrites the messages before they are encoded, e.g. to
+	// translate them, nil keeps them
+	MessageRenderer MessageRenderer
+	// ScanMessagesForSecrets masks AWS keys, bearer tokens, JWTs and card
+	// numbers, Luhn-valid with the prefix of a card network, found in the
+	// message text, reporters included
+	ScanMessagesForSecrets bool
+	// Encryption encrypts the log files, see NewDecryptReader. Configure fails
+	// when the key cannot be loaded, NewLogEntry falls back to the console.
+	Encryption *EncryptionConfig
+	// CrashBuffer keeps the last entries of every level in memory, including
+	// the ones below Level, see DumpRecent. They are written to the error output
+	// after Panic and Fatal entries. The logger reports every level as enabled.
+	CrashBuffer int
+	// Clock provides the entry times, e.g. logtest.FakeClock in tests and
+	// replay tools. It defaults to the system clock.
+	Clock zapcore.Clock
+	// ZapOptions are applied to both zap loggers after the options built from
+	// this config, e.g. zap.Hooks, zap.WrapCore or zap.WithClock
+	ZapOptions []zap.Option
+	// OnFatal is run after Fatal entries instead of os.Exit(1), e.g.
+	// zapcore.WriteThenPanic in tests. The exit hooks run first, see RegisterExitHook.
+	OnFatal zapcore.CheckWriteHook
+	// TTLClass tags every entry of the logger with a retention class, see TTL.
+	// The rolled files are kept for the retention of the class unless MaxAge is set.
+	TTLClass string
+
+	// stats collects volume and timing of the logger, used by experiments
+	stats *loggerStats
+	// fileCipher seals the file output, loaded from Encryption
+	fileCipher *fileCipher
+	// consoleInfo and consoleError replace the console streams with writers
+	// which are not files, see WithConsole
+	consoleInfo  zapcore.WriteSyncer
+	consoleError zapcore.WriteSyncer
+	// consoleColor colors the levels on the console, set by AutoDetectTTY
+	consoleColor bool
+	// files are the files of the logger cloned, see Clone
+	files *logFiles
+	// reporters is the core of ErrorReporters and SentryDSN, built once per
+	// logger so SetOutput and the derived loggers share its queues
+	reporters *reporterCore
 }
 
-var (
-	loglv zap.AtomicLevel
-)
-
 func SetLevel(l Level) {
-	loglv.SetLevel(l)
+	defaults.Load().level.SetLevel(thresholdLevel(l))
 }
 
 func GetLevel() Level {
-	return loglv.Level()
+	return defaults.Load().level.Level()
 }
 
+// The layout encoders of zap format into the JSON buffer, without the string
+// allocated by time.Format
+var (
+	shortTimeEncoder      = zapcore.TimeEncoderOfLayout("2006-01-02T15:04:05.000")
+	consoleLogTimeEncoder = zapcore.TimeEncoderOfLayout("2006-01-02 15:04:05.000")
+)
+
 // ShortTimeEncoder serializes a time.Time to an short-formatted string
 func ShortTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
-	enc.AppendString(t.Format("2006-01-02T15:04:05.000"))
+	shortTimeEncoder(t, enc)
 }
 
 // ConsoleLogTimeEncoder serializes a time.Time to an short-formatted string
 func ConsoleLogTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
-	enc.AppendString(t.Format("2006-01-02 15:04:05.000"))
+	consoleLogTimeEncoder(t, enc)
 }
 
-// defaultConfig is used for DefaultZapLogger below only
+// defaultConfig is used for the initial default logger only
 var defaultConfig = Config{
 	Level:            DebugLevel,
 	EncodeLogsAsJson: false,
@@ -99,75 +404,267 @@ var defaultConfig = Config{
 	LevelEncoder:     zapcore.LowercaseLevelEncoder,
 }
 
-// Configure sets up the logging framework
+// Configure sets up the logging framework, replacing the default logger like Reconfigure
 func Configure(config Config) error {
+	return Reconfigure(config)
+}
+
+// ConfigureOnce configures the default logger unless Configure, ConfigureOnce
+// or Reconfigure did before, returning ErrAlreadyConfigured then. Libraries
+// and init code use it not to override the configuration of the application.
+func ConfigureOnce(config Config) error {
+	configureMu.Lock()
+	defer configureMu.Unlock()
+	if configured {
+		return ErrAlreadyConfigured
+	}
+	return configure(config)
+}
+
+// Reconfigure replaces the default logger. The new logger is completely set
+// up before it is swapped in, goroutines logging meanwhile use the old one.
+func Reconfigure(config Config) error {
+	configureMu.Lock()
+	defer configureMu.Unlock()
+	return configure(config)
+}
+
+// configure builds and installs the default logger, configureMu must be held
+func configure(config Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	given := DeriveConfig(config)
+	config = outputModes(config)
+
 	infoWriters := []zapcore.WriteSyncer{}
 	errWriters := []zapcore.WriteSyncer{}
+	var fallbackErr error
+	var files *logFiles
 
 	if config.FileLoggingEnabled {
-		infoLog := newRollingFile(config.Directory, getNameByLogLevel(config.Filename, InfoLevel), config.MaxSize, config.MaxAge, config.MaxBackups)
-		errLog := newRollingFile(config.Directory, getNameByLogLevel(config.Filename, ErrorLevel), config.MaxSize, config.MaxAge, config.MaxBackups)
-		infoWriters = append(infoWriters, infoLog)
-		errWriters = append(errWriters, errLog)
+		dir, err := resolveDirectory(config)
+		if err != nil {
+			return err
+		}
+		config.Directory = dir
+		if config.fileCipher, err = newFileCipher(config.Encryption); err != nil {
+			return err
+		}
+		infoLog, errLog, err := newLogFiles(config)
+		if err != nil {
+			fallbackErr = fmt.Errorf("%w: %w", ErrFileFallback, err)
+			if !config.ConsoleLoggingEnabled {
+				infoWriters = append(infoWriters, os.Stderr)
+				errWriters = append(errWriters, os.Stderr)
+			}
+		} else {
+			infoWriters = append(infoWriters, infoLog)
+			errWriters = append(errWriters, errLog)
+			files = &logFiles{dir: config.Directory, filename: config.Filename, info: infoLog, err: errLog}
+		}
 	} else {
 		config.ConsoleLoggingEnabled = true
 	}
 
+	var infoConsoles, errConsoles []zapcore.WriteSyncer
 	if config.ConsoleLoggingEnabled {
-		if config.ConsoleInfoStream != nil {
-			infoWriters = append(infoWriters, config.ConsoleInfoStream)
-		} else {
-			infoWriters = append(infoWriters, os.Stdout)
-		}
-		if config.ConsoleErrorStream != nil {
-			errWriters = append(errWriters, config.ConsoleErrorStream)
-		} else {
-			errWriters = append(errWriters, os.Stderr)
-		}
+		infoConsole, errConsole := consoleWriters(config)
+		infoConsoles = append(infoConsoles, infoConsole)
+		errConsoles = append(errConsoles, errConsole)
 	}
 
-	DefaultZapLogger = newZapLogger(config, zapcore.NewMultiWriteSyncer(infoWriters...), zapcore.NewMultiWriteSyncer(errWriters...), true)
-
-	DeclareLogger(config, Infov)
-	DeclareLogger(config, Errorv)
+	extraInfo, extraErr, sinkClosers, err := openExtraSinks(config)
+	if err != nil {
+		for _, c := range append(fileClosers(infoWriters...), fileClosers(errWriters...)...) {
+			c.Close()
+		}
+		return err
+	}
+	infoWriters = append(infoWriters, extraInfo...)
+	errWriters = append(errWriters, extraErr...)
+
+	config.reporters = newReporterCore(config)
+	level := zap.NewAtomicLevelAt(thresholdLevel(config.Level))
+	logger := newZapLogger(config, newOutput(config, infoWriters, infoConsoles), newOutput(config, errWriters, errConsoles), level)
+	logger.closers = append(fileClosers(infoWriters...), fileClosers(errWriters...)...)
+	logger.closers = append(logger.closers, sinkClosers...)
+	if config.reporters != nil {
+		logger.closers = append(logger.closers, config.reporters)
+	}
+	logger.config = &given
+	logger.fileFallback = fallbackErr
+	logger.files = files
+	swapDefault(logger, level, config)
+	configured = true
+
+	if fallbackErr != nil {
+		Errorv(fallbackErr.Error(), zap.String("logDirectory", config.Directory))
+	}
+	if !config.CLIOutput {
+		DeclareLogger(config, Infov)
+		DeclareLogger(config, Errorv)
+	}
 
 	return nil
 }
 
+// FileFallback returns the error wrapping ErrFileFallback when the default
+// logger could not create its log files and writes to stderr instead, nil
+// otherwise
+func FileFallback() error {
+	return Default().FileFallback()
+}
+
 // NewLogEntry create a new logentry instead of override defaultzaplogger
 func NewLogEntry(config Config) *LogEntry {
+	logEntry := buildLogEntry(config)
+
+	if !config.CLIOutput {
+		DeclareLogger(config, logEntry.Infov)
+		DeclareLogger(config, logEntry.Errorv)
+	}
+	return logEntry
+}
+
+// NewLogEntryWithOptions creates a new logentry like NewLogEntry, applying
+// the zap options after Config.ZapOptions
+func NewLogEntryWithOptions(config Config, opts ...zap.Option) *LogEntry {
+	config.ZapOptions = append(config.ZapOptions[:len(config.ZapOptions):len(config.ZapOptions)], opts...)
+	return NewLogEntry(config)
+}
+
+func buildLogEntry(config Config) *LogEntry {
+	given := DeriveConfig(config)
 	infoWriters := []zapcore.WriteSyncer{}
 	errWriters := []zapcore.WriteSyncer{}
 
+	if err := config.Validate(); err != nil {
+		Errorv("invalid log config", zap.Error(err))
+	}
+	config = outputModes(config)
+
 	if config.FileLoggingEnabled {
-		infoLog := newRollingFile(config.Directory, getNameByLogLevel(config.Filename, InfoLevel), config.MaxSize, config.MaxAge, config.MaxBackups)
-		errLog := newRollingFile(config.Directory, getNameByLogLevel(config.Filename, ErrorLevel), config.MaxSize, config.MaxAge, config.MaxBackups)
-		infoWriters = append(infoWriters, infoLog)
-		errWriters = append(errWriters, errLog)
+		if dir, err := resolveDirectory(config); err != nil {
+			Errorv("failed resolve log directory", zap.Error(err), zap.String("path", config.Directory))
+			config.FileLoggingEnabled = false
+		} else {
+			config.Directory = dir
+		}
+	}
+
+	// the files of the logger cloned are shared when they are the same, see
+	// Clone, instead of writing them with a second lumberjack
+	files := config.files
+	var sharedInfo, sharedErr []zapcore.WriteSyncer
+	if config.FileLoggingEnabled && files.at(config.Directory, config.Filename) {
+		sharedInfo = append(sharedInfo, files.info)
+		sharedErr = append(sharedErr, files.err)
 	} else {
+		files = nil
+	}
+
+	if config.FileLoggingEnabled && files == nil {
+		aead, err := newFileCipher(config.Encryption)
+		if err != nil {
+			Errorv("failed set up log encryption", zap.Error(err))
+			config.FileLoggingEnabled = false
+		}
+		config.fileCipher = aead
+	}
+
+	var fallbackErr error
+	if config.FileLoggingEnabled && files == nil {
+		infoLog, errLog, err := newLogFiles(config)
+		if err != nil {
+			fallbackErr = fmt.Errorf("%w: %w", ErrFileFallback, err)
+			if !config.ConsoleLoggingEnabled {
+				infoWriters = append(infoWriters, os.Stderr)
+				errWriters = append(errWriters, os.Stderr)
+			}
+		} else {
+			infoWriters = append(infoWriters, infoLog)
+			errWriters = append(errWriters, errLog)
+			files = &logFiles{dir: config.Directory, filename: config.Filename, info: infoLog, err: errLog}
+		}
+	} else if !config.FileLoggingEnabled {
 		config.ConsoleLoggingEnabled = true
-		infoWriters = append(infoWriters, os.Stdout)
-		errWriters = append(errWriters, os.Stderr)
 	}
 
-	logEntry := newZapLogger(config, zapcore.NewMultiWriteSyncer(infoWriters...), zapcore.NewMultiWriteSyncer(errWriters...), false)
+	var infoConsoles, errConsoles []zapcore.WriteSyncer
+	if config.ConsoleLoggingEnabled {
+		infoConsole, errConsole := consoleWriters(config)
+		infoConsoles = append(infoConsoles, infoConsole)
+		errConsoles = append(errConsoles, errConsole)
+	}
 
-	DeclareLogger(config, logEntry.Infov)
-	DeclareLogger(config, logEntry.Errorv)
+	extraInfo, extraErr, sinkClosers, err := openExtraSinks(config)
+	if err != nil {
+		Errorv("failed open log sinks", zap.Error(err))
+	}
+	infoWriters = append(infoWriters, extraInfo...)
+	errWriters = append(errWriters, extraErr...)
+
+	config.reporters = newReporterCore(config)
+	level := zap.NewAtomicLevelAt(thresholdLevel(config.Level))
+	logEntry := newZapLogger(config, newOutput(config, append(sharedInfo, infoWriters...), infoConsoles), newOutput(config, append(sharedErr, errWriters...), errConsoles), level)
+	logEntry.closers = append(fileClosers(infoWriters...), fileClosers(errWriters...)...)
+	logEntry.closers = append(logEntry.closers, sinkClosers...)
+	if config.reporters != nil {
+		logEntry.closers = append(logEntry.closers, config.reporters)
+	}
+	logEntry.config = &given
+	logEntry.fileFallback = fallbackErr
+	logEntry.files = files
+	if fallbackErr != nil {
+		logEntry.Errorv(fallbackErr.Error(), zap.String("logDirectory", config.Directory))
+	}
 	return logEntry
 }
 
+// consoleWriters returns the console outputs of the config, stdout and stderr by default
+func consoleWriters(config Config) (zapcore.WriteSyncer, zapcore.WriteSyncer) {
+	infoFile, errFile := os.Stdout, os.Stderr
+	if config.ConsoleInfoStream != nil {
+		infoFile = config.ConsoleInfoStream
+	}
+	if config.ConsoleErrorStream != nil {
+		errFile = config.ConsoleErrorStream
+	}
+	infoConsole, errConsole := consoleFile(infoFile), consoleFile(errFile)
+	if config.consoleInfo != nil {
+		infoConsole = config.consoleInfo
+	}
+	if config.consoleError != nil {
+		errConsole = config.consoleError
+	}
+	if config.ContainerMode {
+		errConsole = infoConsole
+	}
+	return infoConsole, errConsole
+}
+
+// outputModes applies Config.ContainerMode, JSON to the console besides the
+// files if any, and Config.AutoDetectTTY
+func outputModes(config Config) Config {
+	if config.ContainerMode {
+		config.EncodeLogsAsJson = true
+		config.ConsoleLoggingEnabled = true
+		config.ConsoleEncoding = EncodingJSON
+	} else if config.AutoDetectTTY && !config.CLIOutput {
+		infoConsole, _ := consoleWriters(config)
+		_, tty := infoConsole.(ttyWriter)
+		config.EncodeLogsAsJson = !tty
+		config.consoleColor = tty
+	}
+	return config
+}
+
+// DeclareLogger logs the "logging configured" entry with the output settings
+// of the config and the details selected by Config.Startup, see LogStartup.
+// The entry is not sent to the ErrorReporters, even when logged at error.
 func DeclareLogger(config Config, logv func(msg string, fields ...zapcore.Field)) {
-	logv("logging configured",
-		zap.Bool("fileLogging", config.FileLoggingEnabled),
-		zap.Bool("consoleLogging", config.ConsoleLoggingEnabled),
-		zap.Bool("caller", config.CallerEnabled),
-		zap.Int("callerSkip", config.CallerSkip),
-		zap.Bool("jsonLogOutput", config.EncodeLogsAsJson),
-		zap.String("logDirectory", config.Directory),
-		zap.Int("maxSizeMB", config.MaxSize),
-		zap.Int("maxBackups", config.MaxBackups),
-		zap.Int("maxAgeInDays", config.MaxAge))
+	logv(ConfiguredMessage, append(startupFields(config), notReportedField)...)
 }
 
 func getNameByLogLevel(filename string, level zapcore.Level) string {
@@ -185,70 +682,182 @@ func getNameByLogLevel(filename string, level zapcore.Level) string {
 	return name
 }
 
-func newRollingFile(dir, filename string, maxSize, maxAge, maxBackups int) zapcore.WriteSyncer {
-	if err := os.MkdirAll(dir, 0744); err != nil {
-		Errorv("failed create log directory", zap.Error(err), zap.String("path", dir))
-		return nil
+// newLogFiles opens the info and error files of the config
+func newLogFiles(config Config) (zapcore.WriteSyncer, zapcore.WriteSyncer, error) {
+	infoLog, err := newRollingFile(config, getNameByLogLevel(config.Filename, InfoLevel))
+	if err != nil {
+		return nil, nil, err
+	}
+	errLog, err := newRollingFile(config, getNameByLogLevel(config.Filename, ErrorLevel))
+	if err != nil {
+		return nil, nil, err
 	}
+	return infoLog, errLog, nil
+}
 
-	return zapcore.AddSync(&lumberjack.Logger{
-		Filename:   path.Join(dir, filename),
-		MaxSize:    maxSize,    //megabytes
-		MaxAge:     maxAge,     //days
-		MaxBackups: maxBackups, //files
+func newRollingFile(config Config, filename string) (zapcore.WriteSyncer, error) {
+	if err := os.MkdirAll(config.Directory, 0744); err != nil {
+		return nil, fmt.Errorf("create log directory: %w", err)
+	}
+	// lumberjack opens the file on the first write, open it now to find out
+	// whether it can be written at all
+	path := longPath(filepath.Join(config.Directory, filename))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("open log file: %w", err)
+	}
+	f.Close()
+
+	lj := &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    config.MaxSize,    //megabytes
+		MaxAge:     ttlMaxAge(config), //days
+		MaxBackups: config.MaxBackups, //files
 		LocalTime:  true,
-	})
+	}
+	if config.ExternalRotation {
+		// lumberjack takes 0 for its default size, this one is never reached
+		lj.MaxSize, lj.MaxAge, lj.MaxBackups = math.MaxInt32, 0, 0
+	}
+	var rolling logFile = rollingFile{lj}
+	if config.fileCipher != nil {
+		rolling = newEncryptedFile(lj, config.fileCipher)
+	}
+	file := rolling.(zapcore.WriteSyncer)
+	if config.FormatHeader {
+		file = newHeaderWriter(lj, rolling, config)
+	}
+	if config.CoalesceWindow > 0 {
+		file = newCoalescingFile(file, config.CoalesceWindow)
+	}
+	return file, nil
+}
+
+// rollingFile is a lumberjack file which can be closed through LogEntry.Close
+type rollingFile struct {
+	*lumberjack.Logger
+}
+
+func (rollingFile) Sync() error {
+	return nil
+}
+
+// fileClosers returns the writers which LogEntry.Close should close
+func fileClosers(writers ...zapcore.WriteSyncer) []io.Closer {
+	closers := []io.Closer{}
+	for _, w := range writers {
+		switch c := w.(type) {
+		case rollingFile, *encryptedFile, *headerWriter, *coalescingFile:
+			closers = append(closers, c.(io.Closer))
+		}
+	}
+	return closers
 }
 
-func newZapLogger(config Config, infoOutput zapcore.WriteSyncer, errOutput zapcore.WriteSyncer, isDefaultLogger bool) *LogEntry {
+func newEncoderConfig(config Config) zapcore.EncoderConfig {
 	encCfg := zapcore.EncoderConfig{
-		TimeKey:          "@t",
-		LevelKey:         "lvl",
-		NameKey:          "logger",
-		CallerKey:        "caller",
-		MessageKey:       "msg",
-		StacktraceKey:    "stacktrace",
+		TimeKey:          TimeKey,
+		LevelKey:         LevelKey,
+		NameKey:          NameKey,
+		CallerKey:        CallerKey,
+		MessageKey:       MessageKey,
+		StacktraceKey:    StacktraceKey,
 		ConsoleSeparator: config.ConsoleSeparator,
 		EncodeLevel:      config.LevelEncoder,
-		EncodeDuration:   zapcore.NanosDurationEncoder,
-		EncodeCaller:     zapcore.ShortCallerEncoder,
+		EncodeDuration:   config.DurationEncoder,
+		EncodeCaller:     callerEncoder(config.CallerWithFunc),
 	}
 	if encCfg.EncodeLevel == nil {
 		encCfg.EncodeLevel = zapcore.LowercaseLevelEncoder
 	}
+	encCfg.EncodeLevel = extendLevelEncoder(encCfg.EncodeLevel)
+	if encCfg.EncodeDuration == nil {
+		encCfg.EncodeDuration = zapcore.NanosDurationEncoder
+	}
 	if !config.EncodeLogsAsJson {
 		encCfg.ConsoleSeparator = config.ConsoleSeparator
-		encCfg.EncodeTime = ConsoleLogTimeEncoder
-		config.EncodeLogsAsJson = false
-	} else {
-		encCfg.EncodeTime = ShortTimeEncoder
 	}
+	timeEncoder, err := newTimeEncoder(config)
+	if err != nil {
+		// an unknown zone is reported by Configure, fall back to local time
+		config.TimeZone = ""
+		timeEncoder, _ = newTimeEncoder(config)
+	}
+	encCfg.EncodeTime = timeEncoder
+	return encCfg
+}
 
-	encoder := zapcore.NewConsoleEncoder(encCfg)
+func newEncoder(config Config) zapcore.Encoder {
+	encCfg := newEncoderConfig(config)
+	if config.CLIOutput {
+		return noticeEncoder{newCLIEncoder(encCfg)}
+	}
 	if config.EncodeLogsAsJson {
-		encoder = zapcore.NewJSONEncoder(encCfg)
+		return noticeEncoder{zapcore.NewJSONEncoder(encCfg)}
 	}
+	return noticeEncoder{humanEncoder{zapcore.NewConsoleEncoder(encCfg)}}
+}
 
-	// gloval var `loglv` is reserved for changing log level of defaultLogger
-	localLoglv := zap.NewAtomicLevelAt(config.Level)
-	if isDefaultLogger {
-		loglv = localLoglv
-	}
+func newZapLogger(config Config, infoOutput zapcore.WriteSyncer, errOutput zapcore.WriteSyncer, level zap.AtomicLevel) *LogEntry {
+	encoder := newSinkEncoder(config, config.FileEncoding)
 
-	if config.CallerEnabled {
-		return getLogEntry(
-			zap.New(zapcore.NewCore(encoder, infoOutput, localLoglv),
-				zap.AddCaller(), zap.AddCallerSkip(config.CallerSkip)),
+	infoCore := newCore(config, encoder, infoOutput, level, nil)
+	errCore := newCore(config, encoder, errOutput, level, newErrorAggregator(config.ErrorAlerts))
 
-			zap.New(zapcore.NewCore(encoder, errOutput, localLoglv),
-				zap.AddCaller(), zap.AddCallerSkip(config.CallerSkip)))
+	opts := []zap.Option{zap.WithFatalHook(newFatalHook(config.OnFatal))}
+	if config.CallerEnabled {
+		opts = append(opts, zap.AddCaller(), zap.AddCallerSkip(config.CallerSkip))
+	}
+	if config.IncludeHostInfo {
+		opts = append(opts, zap.Fields(hostInfoFields()...))
+	}
+	if len(config.InitialFields) > 0 {
+		opts = append(opts, zap.Fields(appendFields(nil, config.InitialFields, config.SortFields)...))
+	}
+	if config.TTLClass != "" {
+		opts = append(opts, zap.Fields(TTL(config.TTLClass)))
+	}
+	if config.Clock != nil {
+		opts = append(opts, zap.WithClock(config.Clock))
 	}
-	return getLogEntry(zap.New(zapcore.NewCore(encoder, infoOutput, localLoglv)),
-		zap.New(zapcore.NewCore(encoder, errOutput, localLoglv)))
+	opts = append(opts, config.ZapOptions...)
+	if config.reporters != nil {
+		errCore = zapcore.NewTee(errCore, config.reporters)
+	}
+	infoCore = newCrashCore(infoCore, config.CrashBuffer, encoder, errOutput)
+	errCore = newCrashCore(errCore, config.CrashBuffer, encoder, errOutput)
+	infoCore = newSecretsCore(infoCore, config.ScanMessagesForSecrets)
+	errCore = newSecretsCore(errCore, config.ScanMessagesForSecrets)
+
+	logEntry := newScopedEntry(zap.New(infoCore, opts...), zap.New(errCore, opts...))
+	logEntry.level = level
+	logEntry.traceCorrelation = config.TraceCorrelation || config.SampledTraces
+	logEntry.sortFields = config.SortFields
+	logEntry.nearDeadline = config.NearDeadline
+	return logEntry
+}
+
+// newCore builds the core pipeline writing to output. The innermost core
+// accepts every level, levelCore on top applies the level and debug filters.
+func newCore(config Config, encoder zapcore.Encoder, output zapcore.WriteSyncer, level zap.AtomicLevel, alerts *errorAggregator) zapcore.Core {
+	core, floor := newOutputCore(config, encoder, output)
+	core = newStatsCore(core, config.stats)
+	core = newTailCore(core, encoder)
+	core = newWriterCore(core)
+	core = newTruncateCore(core, config.MaxFieldBytes, config.MaxMessageBytes)
+	core = newRenderCore(core, config.MessageRenderer)
+	core = newDuplicateKeysCore(core, config.DuplicateKeys)
+	core = newSchemaCore(core, config.Schema)
+	core = newGlobalFieldsCore(core)
+	core = newGoroutineCore(core, config.IncludeGoroutineID)
+	core = newMDCCore(core, config.MDC)
+	core = newSamplingCore(core, config.Sampling, config.stats)
+	core = newAlertCore(core, alerts)
+	return newLevelCore(core, level, floor, config.SampledTraces)
 }
 
 func newRotateWriter(dir, fileName string) *lumberjack.Logger {
-	logFilePath := path.Join(dir, fileName+".log")
+	logFilePath := longPath(filepath.Join(dir, fileName+".log"))
 	return &lumberjack.Logger{
 		Filename:   logFilePath,
 		MaxSize:    500, // megabytes
@@ -261,196 +870,282 @@ func newRotateWriter(dir, fileName string) *lumberjack.Logger {
 // AtLevel logs the message at a specific log level
 func AtLevel(level zapcore.Level, msg string, fields ...zapcore.Field) {
 	switch level {
-	case zapcore.DebugLevel:
-		Debugv(msg, fields...)
-	case zapcore.PanicLevel:
-		Panicv(msg, fields...)
-	case zapcore.ErrorLevel:
-		Errorv(msg, fields...)
-	case zapcore.WarnLevel:
-		Warnv(msg, fields...)
-	case zapcore.InfoLevel:
-		Infov(msg, fields...)
-	case zapcore.FatalLevel:
-		Fatalv(msg, fields...)
+	case TraceLevel, zapcore.DebugLevel, zapcore.InfoLevel:
+		Default().infoLogger.Log(level, msg, fields...)
+	case NoticeLevel:
+		if ce := Default().infoLogger.Check(InfoLevel, msg); ce != nil {
+			ce.Write(noticeFields(fields)...)
+		}
+	case zapcore.WarnLevel, zapcore.ErrorLevel, zapcore.PanicLevel, zapcore.FatalLevel:
+		Default().errorLogger.Log(level, msg, fields...)
 	default:
-		Warnv("Logging at unkown level", zap.Any("level", level))
-		Warnv(msg, fields...)
+		logger := Default().errorLogger
+		logger.Warn("Logging at unkown level", zap.Any("level", level))
+		logger.Warn(msg, fields...)
 	}
 }
 
 func Debugv(msg string, fields ...zapcore.Field) {
-	DefaultZapLogger.infoLogger.Debug(msg, fields...)
+	Default().infoLogger.Debug(msg, fields...)
 }
 
 func Debugw(msg string, keysAndValues ...interface{}) {
-	DefaultZapLogger.Debugw(msg, keysAndValues...)
+	l := Default()
+	if !plainPairs(keysAndValues) {
+		l.infoSugared().Debugw(msg, keysAndValues...)
+	} else if ce := l.infoLogger.Check(DebugLevel, msg); ce != nil {
+		writePairs(ce, keysAndValues)
+	}
 }
 
 // Debugf Log a format message at the debug level
 func Debugf(template string, args ...interface{}) {
-	DefaultZapLogger.infoSugared.Debugf(template, args...)
+	Default().infoSugared().Debugf(template, args...)
+}
+
+func Debugln(args ...interface{}) {
+	Default().infoSugared().Debugln(args...)
 }
 
 // Debug Log a message at the debug level
 func Debug(msg string) {
-	DefaultZapLogger.infoLogger.Debug(msg)
+	Default().infoLogger.Debug(msg)
 }
 
 // DebugWith Log a message with fields at the debug level
 func DebugWith(msg string, fields Fields) {
+	l := Default()
 	if len(fields) > 0 {
-		DefaultZapLogger.infoLogger.Debug(msg, convertFields(fields)...)
+		zfields := getFieldSlice(fields, l.sortFields)
+		l.infoLogger.Debug(msg, *zfields...)
+		putFieldSlice(zfields)
 	} else {
-		DefaultZapLogger.infoLogger.Debug(msg)
+		l.infoLogger.Debug(msg)
 	}
 }
 
 func Infov(msg string, fields ...zapcore.Field) {
-	DefaultZapLogger.infoLogger.Info(msg, fields...)
+	Default().infoLogger.Info(msg, fields...)
 }
 
 func Infof(template string, args ...interface{}) {
-	DefaultZapLogger.infoSugared.Infof(template, args...)
+	Default().infoSugared().Infof(template, args...)
+}
+
+func Infoln(args ...interface{}) {
+	Default().infoSugared().Infoln(args...)
 }
 
 func Info(msg string) {
-	DefaultZapLogger.infoLogger.Info(msg)
+	Default().infoLogger.Info(msg)
 }
 
 func InfoWith(msg string, fields Fields) {
+	l := Default()
 	if len(fields) > 0 {
-		DefaultZapLogger.infoLogger.Info(msg, convertFields(fields)...)
+		zfields := getFieldSlice(fields, l.sortFields)
+		l.infoLogger.Info(msg, *zfields...)
+		putFieldSlice(zfields)
 	} else {
-		DefaultZapLogger.infoLogger.Info(msg)
+		l.infoLogger.Info(msg)
 	}
 }
 
 func Infow(msg string, keysAndValues ...interface{}) {
-	DefaultZapLogger.Infow(msg, keysAndValues...)
+	l := Default()
+	if !plainPairs(keysAndValues) {
+		l.infoSugared().Infow(msg, keysAndValues...)
+	} else if ce := l.infoLogger.Check(InfoLevel, msg); ce != nil {
+		writePairs(ce, keysAndValues)
+	}
 }
 
 func Warnv(msg string, fields ...zapcore.Field) {
-	DefaultZapLogger.errorLogger.Warn(msg, fields...)
+	Default().errorLogger.Warn(msg, fields...)
 }
 
 func Warnf(template string, args ...interface{}) {
-	DefaultZapLogger.errorSugared.Warnf(template, args...)
+	Default().errorSugared().Warnf(template, args...)
+}
+
+func Warnln(args ...interface{}) {
+	Default().errorSugared().Warnln(args...)
 }
 
 func Warn(msg string) {
-	DefaultZapLogger.errorLogger.Warn(msg)
+	Default().errorLogger.Warn(msg)
 }
 func Warnw(msg string, keysAndValues ...interface{}) {
-	DefaultZapLogger.Warnw(msg, keysAndValues...)
+	l := Default()
+	if !plainPairs(keysAndValues) {
+		l.errorSugared().Warnw(msg, keysAndValues...)
+	} else if ce := l.errorLogger.Check(WarnLevel, msg); ce != nil {
+		writePairs(ce, keysAndValues)
+	}
 }
 
 func WarnWith(msg string, fields Fields) {
+	l := Default()
 	if len(fields) > 0 {
-		DefaultZapLogger.errorLogger.Warn(msg, convertFields(fields)...)
+		zfields := getFieldSlice(fields, l.sortFields)
+		l.errorLogger.Warn(msg, *zfields...)
+		putFieldSlice(zfields)
 	} else {
-		DefaultZapLogger.errorLogger.Warn(msg)
+		l.errorLogger.Warn(msg)
 	}
 }
 
 func Errorv(msg string, fields ...zapcore.Field) {
-	DefaultZapLogger.errorLogger.Error(msg, fields...)
+	Default().errorLogger.Error(msg, fields...)
 }
 
 func Errorw(msg string, keysAndValues ...interface{}) {
-	DefaultZapLogger.Errorw(msg, keysAndValues...)
+	l := Default()
+	if !plainPairs(keysAndValues) {
+		l.errorSugared().Errorw(msg, keysAndValues...)
+	} else if ce := l.errorLogger.Check(ErrorLevel, msg); ce != nil {
+		writePairs(ce, keysAndValues)
+	}
 }
 
 func Errorf(template string, args ...interface{}) {
-	DefaultZapLogger.errorSugared.Errorf(template, args...)
+	Default().errorSugared().Errorf(template, args...)
+}
+
+func Errorln(args ...interface{}) {
+	Default().errorSugared().Errorln(args...)
 }
 
 func Error(msg string) {
-	DefaultZapLogger.errorLogger.Error(msg)
+	Default().errorLogger.Error(msg)
 }
 
 func ErrorWith(msg string, fields Fields) {
+	l := Default()
 	if len(fields) > 0 {
-		DefaultZapLogger.errorLogger.Error(msg, convertFields(fields)...)
+		zfields := getFieldSlice(fields, l.sortFields)
+		l.errorLogger.Error(msg, *zfields...)
+		putFieldSlice(zfields)
 	} else {
-		DefaultZapLogger.errorLogger.Error(msg)
+		l.errorLogger.Error(msg)
 	}
 }
 
 func Panicv(msg string, fields ...zapcore.Field) {
-	DefaultZapLogger.errorLogger.Panic(msg, fields...)
+	Default().errorLogger.Panic(msg, fields...)
 }
 
 func Panicw(msg string, keysAndValues ...interface{}) {
-	DefaultZapLogger.Panicw(msg, keysAndValues...)
+	l := Default()
+	if !plainPairs(keysAndValues) {
+		l.errorSugared().Panicw(msg, keysAndValues...)
+	} else if ce := l.errorLogger.Check(PanicLevel, msg); ce != nil {
+		writePairs(ce, keysAndValues)
+	}
 }
 
 func Panicf(template string, args ...interface{}) {
-	DefaultZapLogger.errorSugared.Panicf(template, args...)
+	Default().errorSugared().Panicf(template, args...)
+}
+
+func Panicln(args ...interface{}) {
+	Default().errorSugared().Panicln(args...)
 }
 
 func Panic(msg string) {
-	DefaultZapLogger.errorLogger.Panic(msg)
+	Default().errorLogger.Panic(msg)
 }
 
 func PanicWith(msg string, fields Fields) {
+	l := Default()
 	if len(fields) > 0 {
-		DefaultZapLogger.errorLogger.Panic(msg, convertFields(fields)...)
+		zfields := getFieldSlice(fields, l.sortFields)
+		l.errorLogger.Panic(msg, *zfields...)
+		putFieldSlice(zfields)
 	} else {
-		DefaultZapLogger.errorLogger.Panic(msg)
+		l.errorLogger.Panic(msg)
 	}
 }
 
 func Fatalv(msg string, fields ...zapcore.Field) {
-	DefaultZapLogger.errorLogger.Fatal(msg, fields...)
+	Default().errorLogger.Fatal(msg, fields...)
 }
 
 func Fatalw(msg string, keysAndValues ...interface{}) {
-	DefaultZapLogger.Fatalw(msg, keysAndValues...)
+	l := Default()
+	if !plainPairs(keysAndValues) {
+		l.errorSugared().Fatalw(msg, keysAndValues...)
+	} else if ce := l.errorLogger.Check(FatalLevel, msg); ce != nil {
+		writePairs(ce, keysAndValues)
+	}
 }
 
 func Fatalf(template string, args ...interface{}) {
-	DefaultZapLogger.errorSugared.Fatalf(template, args...)
+	Default().errorSugared().Fatalf(template, args...)
+}
+
+func Fatalln(args ...interface{}) {
+	Default().errorSugared().Fatalln(args...)
 }
 
 func Fatal(msg string) {
-	DefaultZapLogger.errorLogger.Fatal(msg)
+	Default().errorLogger.Fatal(msg)
 }
 
 func FatalWith(msg string, fields Fields) {
+	l := Default()
 	if len(fields) > 0 {
-		DefaultZapLogger.errorLogger.Fatal(msg, convertFields(fields)...)
+		zfields := getFieldSlice(fields, l.sortFields)
+		l.errorLogger.Fatal(msg, *zfields...)
+		putFieldSlice(zfields)
 	} else {
-		DefaultZapLogger.errorLogger.Fatal(msg)
+		l.errorLogger.Fatal(msg)
 	}
 }
 
 func DPanicv(msg string, fields ...zapcore.Field) {
-	DefaultZapLogger.errorLogger.DPanic(msg, fields...)
+	Default().errorLogger.DPanic(msg, fields...)
 }
 
 func DPanicw(msg string, keysAndValues ...interface{}) {
-	DefaultZapLogger.DPanicw(msg, keysAndValues...)
+	l := Default()
+	if !plainPairs(keysAndValues) {
+		l.errorSugared().DPanicw(msg, keysAndValues...)
+	} else if ce := l.errorLogger.Check(DPanicLevel, msg); ce != nil {
+		writePairs(ce, keysAndValues)
+	}
 }
 
 func DPanicf(template string, args ...interface{}) {
-	DefaultZapLogger.errorSugared.DPanicf(template, args...)
+	Default().errorSugared().DPanicf(template, args...)
+}
+
+func DPanicln(args ...interface{}) {
+	Default().errorSugared().DPanicln(args...)
 }
 
 func DPanic(msg string) {
-	DefaultZapLogger.errorLogger.DPanic(msg)
+	Default().errorLogger.DPanic(msg)
 }
 
 func DPanicWith(msg string, fields Fields) {
+	l := Default()
 	if len(fields) > 0 {
-		DefaultZapLogger.errorLogger.DPanic(msg, convertFields(fields)...)
+		zfields := getFieldSlice(fields, l.sortFields)
+		l.errorLogger.DPanic(msg, *zfields...)
+		putFieldSlice(zfields)
 	} else {
-		DefaultZapLogger.errorLogger.DPanic(msg)
+		l.errorLogger.DPanic(msg)
 	}
 }
 
 func WithFields(fields Fields) *LogEntry {
-	return newLogEntry(DefaultZapLogger, fields)
+	return newLogEntry(Default(), fields)
+}
+
+// WithLazy derives a logger from the default logger like LogEntry.WithLazy
+func WithLazy(fields Fields) *LogEntry {
+	return Default().WithLazy(fields)
 }
 
 func With(data string) *LogEntry {
@@ -458,19 +1153,25 @@ func With(data string) *LogEntry {
 }
 
 func WithField(k, v string) *LogEntry {
-	return newLogEntry(DefaultZapLogger, Fields{k: v})
+	return Default().with([]zapcore.Field{zap.String(k, v)})
 }
 
 func FromContext(ctx context.Context) *LogEntry {
-	logger, ok := ctx.Value(loggerKey).(*LogEntry)
-	if !ok {
-		return DefaultZapLogger
+	return LoggerFromContext(ctx).fromContext(ctx)
+}
+
+// LoggerFromContext returns the logger put in the context with
+// ContextWithLogger, or the default logger, without the fields FromContext
+// adds from the context
+func LoggerFromContext(ctx context.Context) *LogEntry {
+	if logger, ok := ctx.Value(loggerKey).(*LogEntry); ok {
+		return logger
 	}
-	return logger
+	return Default()
 }
 
 func ContextWithLogger(ctx context.Context) context.Context {
-	return DefaultZapLogger.ContextWithLogger(ctx)
+	return Default().ContextWithLogger(ctx)
 }
 
 func ContextWithCustomizedLogger(ctx context.Context, logEntry *LogEntry) context.Context {
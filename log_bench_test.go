@@ -0,0 +1,78 @@
+package log
+
+import (
+	"io"
+	"testing"
+)
+
+// newBenchLogger writes JSON to io.Discard with the caller disabled, like the
+// numbers of the README
+func newBenchLogger() *LogEntry {
+	return NewWithWriter(Config{Level: InfoLevel, EncodeLogsAsJson: true}, io.Discard)
+}
+
+func BenchmarkInfo(b *testing.B) {
+	le := newBenchLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		le.Info("hello")
+	}
+}
+
+func BenchmarkInfoWith(b *testing.B) {
+	le := newBenchLogger()
+	fields := Fields{"a": "b", "n": 1}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		le.InfoWith("hello", fields)
+	}
+}
+
+func BenchmarkInfov(b *testing.B) {
+	le := newBenchLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		le.Infov("hello", Str("a", "b"), Int("n", 1))
+	}
+}
+
+func BenchmarkInfow(b *testing.B) {
+	le := newBenchLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		le.Infow("hello", "a", "b", "n", 1)
+	}
+}
+
+func BenchmarkWithFieldsInfow(b *testing.B) {
+	le := newBenchLogger()
+	fields := Fields{"req": "r1", "user": 42}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		le.WithFields(fields).Infow("hello", "a", "b")
+	}
+}
+
+func BenchmarkWithLazyInfow(b *testing.B) {
+	le := newBenchLogger()
+	fields := Fields{"req": "r1", "user": 42}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		le.WithLazy(fields).Infow("hello", "a", "b")
+	}
+}
+
+func BenchmarkDebugDisabled(b *testing.B) {
+	le := newBenchLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		le.Debugw("hello", "a", "b")
+	}
+}
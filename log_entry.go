@@ -2,7 +2,13 @@ package log
 
 import (
 	"context"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -12,10 +18,32 @@ const DefaultFieldName = "-"
 type Fields map[string]interface{}
 
 type LogEntry struct {
-	infoSugared  *zap.SugaredLogger
-	errorSugared *zap.SugaredLogger
-	infoLogger   *zap.Logger
-	errorLogger  *zap.Logger
+	infoLogger  *zap.Logger
+	errorLogger *zap.Logger
+	// infoSugar and errorSugar are built on first use of the sugared methods
+	infoSugar  atomic.Pointer[zap.SugaredLogger]
+	errorSugar atomic.Pointer[zap.SugaredLogger]
+	// level is shared by the loggers derived from the same config
+	level zap.AtomicLevel
+	// traceCorrelation adds the trace from the context in FromContext
+	traceCorrelation bool
+	// sortFields converts Fields in key order, see Config.SortFields
+	sortFields bool
+	// nearDeadline is Config.NearDeadline
+	nearDeadline time.Duration
+	// closers are the files opened for the logger, shared with derived loggers
+	closers []io.Closer
+	// config is the config the logger was built from, see Clone
+	config *Config
+	// scope holds the fields of Scope, nil when the loggers have no scopeCore
+	scope *fieldScope
+	// files are the files opened for the logger, shared with its clones
+	files *logFiles
+	// fileFallback wraps ErrFileFallback when the log files could not be created
+	fileFallback error
+	// ctxFields are the context fields fromContext added to the logger or the
+	// loggers it derives from, which are not added again
+	ctxFields []zapcore.Field
 }
 
 func (le *LogEntry) ContextWithLogger(ctx context.Context) context.Context {
@@ -24,48 +52,273 @@ func (le *LogEntry) ContextWithLogger(ctx context.Context) context.Context {
 
 func getLogEntry(infoLogger *zap.Logger, errorLogger *zap.Logger) *LogEntry {
 	return &LogEntry{
-		infoLogger:   infoLogger,
-		errorLogger:  errorLogger,
-		infoSugared:  infoLogger.Sugar(),
-		errorSugared: errorLogger.Sugar(),
+		infoLogger:  infoLogger,
+		errorLogger: errorLogger,
 	}
 }
 
+func (le *LogEntry) infoSugared() *zap.SugaredLogger {
+	return loadSugar(&le.infoSugar, le.infoLogger)
+}
+
+func (le *LogEntry) errorSugared() *zap.SugaredLogger {
+	return loadSugar(&le.errorSugar, le.errorLogger)
+}
+
+// loadSugar returns the sugared logger of l, building it on first use. Racing
+// callers may each build one, which is harmless.
+func loadSugar(p *atomic.Pointer[zap.SugaredLogger], l *zap.Logger) *zap.SugaredLogger {
+	if s := p.Load(); s != nil {
+		return s
+	}
+	s := l.Sugar()
+	p.Store(s)
+	return s
+}
+
 func newLogEntry(logEntry *LogEntry, fields Fields) *LogEntry {
-	args := convertFields(fields)
+	zfields := getFieldSlice(fields, logEntry.sortFields)
+	l := logEntry.with(*zfields)
+	putFieldSlice(zfields)
+	return l
+}
 
-	le := &LogEntry{
-		infoLogger:  logEntry.infoLogger.With(args...),
-		errorLogger: logEntry.errorLogger.With(args...),
+// with creates a child logger carrying the fields and the parent's settings
+func (le *LogEntry) with(fields []zapcore.Field) *LogEntry {
+	return le.scoped(le.infoLogger.With(fields...), le.errorLogger.With(fields...))
+}
+
+// scoped derives a LogEntry from the given loggers with a scope of its own,
+// see Scope
+func (le *LogEntry) scoped(infoLogger *zap.Logger, errorLogger *zap.Logger) *LogEntry {
+	if le.scope == nil {
+		return le.derive(infoLogger, errorLogger)
 	}
+	scope := &fieldScope{}
+	wrap := withScope(scope)
+	l := le.derive(infoLogger.WithOptions(wrap), errorLogger.WithOptions(wrap))
+	l.scope = scope
+	return l
+}
 
-	le.infoSugared = le.infoLogger.Sugar()
-	le.errorSugared = le.errorLogger.Sugar()
+// derive creates a LogEntry from the given loggers keeping le's settings
+func (le *LogEntry) derive(infoLogger *zap.Logger, errorLogger *zap.Logger) *LogEntry {
+	l := getLogEntry(infoLogger, errorLogger)
+	l.level = le.level
+	l.traceCorrelation = le.traceCorrelation
+	l.sortFields = le.sortFields
+	l.nearDeadline = le.nearDeadline
+	l.closers = le.closers
+	l.config = le.config
+	l.scope = le.scope
+	l.fileFallback = le.fileFallback
+	l.files = le.files
+	l.ctxFields = le.ctxFields
+	return l
+}
+
+// FileFallback returns the error wrapping ErrFileFallback when the logger
+// could not create its log files and writes to stderr instead, nil otherwise
+func (le *LogEntry) FileFallback() error {
+	return le.fileFallback
+}
 
-	return le
+// WithCallerSkip returns a logger reporting the call site delta frames further
+// up the stack, for helpers wrapping the logger. A negative delta skips fewer frames.
+func (le *LogEntry) WithCallerSkip(delta int) *LogEntry {
+	skip := zap.AddCallerSkip(delta)
+	return le.derive(le.infoLogger.WithOptions(skip), le.errorLogger.WithOptions(skip))
+}
+
+// Sync flushes buffered entries of both the info and the error output
+func (le *LogEntry) Sync() error {
+	return multierr.Append(le.infoLogger.Sync(), le.errorLogger.Sync())
+}
+
+// Close syncs the logger and closes the files it opened. Loggers derived from
+// it share the files; a file written to after Close is reopened.
+func (le *LogEntry) Close() error {
+	err := le.Sync()
+	for _, c := range le.closers {
+		err = multierr.Append(err, c.Close())
+	}
+	return err
+}
+
+// Rotate moves the files opened for the logger aside and starts new ones, as
+// the rolling files do when they reach MaxSize
+func (le *LogEntry) Rotate() error {
+	var err error
+	for _, c := range le.closers {
+		if r, ok := c.(interface{ Rotate() error }); ok {
+			err = multierr.Append(err, r.Rotate())
+		}
+	}
+	return err
+}
+
+// Reopen closes the files opened for the logger so the next entries open
+// their paths again, after an external tool moved or truncated them
+func (le *LogEntry) Reopen() error {
+	var err error
+	for _, c := range le.closers {
+		if r, ok := c.(interface{ Reopen() error }); ok {
+			err = multierr.Append(err, r.Reopen())
+		} else {
+			err = multierr.Append(err, c.Close())
+		}
+	}
+	return err
 }
 
 func convertFields(fields Fields) []zapcore.Field {
-	zfields := make([]zapcore.Field, 0, len(fields))
-	for k, v := range fields {
-		zfields = append(zfields, zap.Any(k, v))
+	return appendFields(make([]zapcore.Field, 0, len(fields)), fields, false)
+}
+
+// appendFields converts the fields, in key order when sorted
+func appendFields(zfields []zapcore.Field, fields Fields, sorted bool) []zapcore.Field {
+	if !sorted {
+		for k, v := range fields {
+			zfields = append(zfields, convertField(k, v, false))
+		}
+		return zfields
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		zfields = append(zfields, convertField(k, fields[k], true))
 	}
 	return zfields
 }
 
-func (le *LogEntry) WithFields(f Fields) *LogEntry {
-	args := convertFields(f)
-	l := &LogEntry{
-		infoLogger:  le.infoLogger.With(args...),
-		errorLogger: le.errorLogger.With(args...),
+// fieldSlices pools the slices of converted fields used for a single call.
+// The cores encode or copy the fields they keep, none holds on to the slice.
+var fieldSlices = sync.Pool{
+	New: func() interface{} {
+		s := make([]zapcore.Field, 0, 8)
+		return &s
+	},
+}
+
+// maxPooledFields keeps unusually large slices out of the pool
+const maxPooledFields = 64
+
+func getFieldSlice(fields Fields, sorted bool) *[]zapcore.Field {
+	p := fieldSlices.Get().(*[]zapcore.Field)
+	*p = appendFields((*p)[:0], fields, sorted)
+	return p
+}
+
+func putFieldSlice(p *[]zapcore.Field) {
+	if cap(*p) > maxPooledFields {
+		return
 	}
-	l.infoSugared = l.infoLogger.Sugar()
-	l.errorSugared = l.errorLogger.Sugar()
-	return l
+	clear(*p)
+	*p = (*p)[:0]
+	fieldSlices.Put(p)
+}
+
+// plainPairs reports whether the key-value pairs are string keys each with a
+// value, which the *w methods convert into pooled fields. Others go through the
+// sugared logger, which reports the invalid pairs.
+func plainPairs(keysAndValues []interface{}) bool {
+	if len(keysAndValues)%2 != 0 {
+		return false
+	}
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if _, ok := keysAndValues[i].(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// writePairs writes the checked entry with the fields of the key-value pairs
+func writePairs(ce *zapcore.CheckedEntry, keysAndValues []interface{}) {
+	p := fieldSlices.Get().(*[]zapcore.Field)
+	*p = appendSweetened((*p)[:0], keysAndValues)
+	ce.Write(*p...)
+	putFieldSlice(p)
+}
+
+// convertField encodes marshalers with their own methods and nested Fields and
+// maps as objects, so aggregators can index their keys. Other values go
+// through zap.Any.
+func convertField(k string, v interface{}, sorted bool) zapcore.Field {
+	switch v := v.(type) {
+	case Fields:
+		if sorted {
+			return zap.Object(k, sortedFields(v))
+		}
+		return zap.Object(k, v)
+	case zapcore.ObjectMarshaler:
+		return zap.Object(k, v)
+	case zapcore.ArrayMarshaler:
+		return zap.Array(k, v)
+	case map[string]interface{}:
+		return convertField(k, Fields(v), sorted)
+	case []Fields:
+		return zap.Array(k, fieldsArray{v, sorted})
+	}
+	return zap.Any(k, v)
+}
+
+// fieldsArray encodes a list of Fields as an array of objects
+type fieldsArray struct {
+	list   []Fields
+	sorted bool
+}
+
+func (a fieldsArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, f := range a.list {
+		var obj zapcore.ObjectMarshaler = f
+		if a.sorted {
+			obj = sortedFields(f)
+		}
+		if err := enc.AppendObject(obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedFields encodes nested Fields in key order
+type sortedFields Fields
+
+func (f sortedFields) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, field := range appendFields(nil, Fields(f), true) {
+		field.AddTo(enc)
+	}
+	return nil
+}
+
+// MarshalLogObject encodes the fields as a nested object
+func (f Fields) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for k, v := range f {
+		convertField(k, v, false).AddTo(enc)
+	}
+	return nil
+}
+
+func (le *LogEntry) WithFields(f Fields) *LogEntry {
+	return newLogEntry(le, f)
+}
+
+// WithLazy is like WithFields but encodes the fields only when the returned
+// logger first writes, for request-scoped loggers which are often not used.
+// The fields are converted right away, later changes to f are not seen.
+func (le *LogEntry) WithLazy(f Fields) *LogEntry {
+	zfields := appendFields(make([]zapcore.Field, 0, len(f)), f, le.sortFields)
+	return le.scoped(le.infoLogger.WithLazy(zfields...), le.errorLogger.WithLazy(zfields...))
 }
 
 func (le *LogEntry) DebugWith(msg string, fields Fields) {
-	le.infoLogger.Debug(msg, convertFields(fields)...)
+	zfields := getFieldSlice(fields, le.sortFields)
+	le.infoLogger.Debug(msg, *zfields...)
+	putFieldSlice(zfields)
 }
 
 func (le *LogEntry) Debug(msg string) {
@@ -74,15 +327,19 @@ func (le *LogEntry) Debug(msg string) {
 }
 
 func (le *LogEntry) Debugf(template string, args ...interface{}) {
-	le.infoSugared.Debugf(template, args)
+	le.infoSugared().Debugf(template, args...)
 }
 
 func (le *LogEntry) Debugln(args ...interface{}) {
-	le.infoSugared.Debugln(args)
+	le.infoSugared().Debugln(args...)
 }
 
 func (le *LogEntry) Debugw(msg string, keysAndValues ...interface{}) {
-	le.infoSugared.Debugw(msg, keysAndValues...)
+	if !plainPairs(keysAndValues) {
+		le.infoSugared().Debugw(msg, keysAndValues...)
+	} else if ce := le.infoLogger.Check(DebugLevel, msg); ce != nil {
+		writePairs(ce, keysAndValues)
+	}
 }
 
 func (le *LogEntry) Debugv(msg string, fields ...zapcore.Field) {
@@ -90,7 +347,9 @@ func (le *LogEntry) Debugv(msg string, fields ...zapcore.Field) {
 }
 
 func (le *LogEntry) InfoWith(msg string, fields Fields) {
-	le.infoLogger.Info(msg, convertFields(fields)...)
+	zfields := getFieldSlice(fields, le.sortFields)
+	le.infoLogger.Info(msg, *zfields...)
+	putFieldSlice(zfields)
 }
 
 func (le *LogEntry) Info(msg string) {
@@ -99,15 +358,19 @@ func (le *LogEntry) Info(msg string) {
 }
 
 func (le *LogEntry) Infof(template string, args ...interface{}) {
-	le.infoSugared.Infof(template, args)
+	le.infoSugared().Infof(template, args...)
 }
 
 func (le *LogEntry) Infoln(args ...interface{}) {
-	le.infoSugared.Infoln(args)
+	le.infoSugared().Infoln(args...)
 }
 
 func (le *LogEntry) Infow(msg string, keysAndValues ...interface{}) {
-	le.infoSugared.Infow(msg, keysAndValues...)
+	if !plainPairs(keysAndValues) {
+		le.infoSugared().Infow(msg, keysAndValues...)
+	} else if ce := le.infoLogger.Check(InfoLevel, msg); ce != nil {
+		writePairs(ce, keysAndValues)
+	}
 }
 
 func (le *LogEntry) Infov(msg string, fields ...zapcore.Field) {
@@ -115,7 +378,9 @@ func (le *LogEntry) Infov(msg string, fields ...zapcore.Field) {
 }
 
 func (le *LogEntry) WarnWith(msg string, fields Fields) {
-	le.errorLogger.Warn(msg, convertFields(fields)...)
+	zfields := getFieldSlice(fields, le.sortFields)
+	le.errorLogger.Warn(msg, *zfields...)
+	putFieldSlice(zfields)
 }
 
 func (le *LogEntry) Warn(msg string) {
@@ -124,15 +389,19 @@ func (le *LogEntry) Warn(msg string) {
 }
 
 func (le *LogEntry) Warnf(template string, args ...interface{}) {
-	le.errorSugared.Warnf(template, args)
+	le.errorSugared().Warnf(template, args...)
 }
 
 func (le *LogEntry) Warnln(args ...interface{}) {
-	le.errorSugared.Warnln(args)
+	le.errorSugared().Warnln(args...)
 }
 
 func (le *LogEntry) Warnw(msg string, keysAndValues ...interface{}) {
-	le.errorSugared.Warnw(msg, keysAndValues...)
+	if !plainPairs(keysAndValues) {
+		le.errorSugared().Warnw(msg, keysAndValues...)
+	} else if ce := le.errorLogger.Check(WarnLevel, msg); ce != nil {
+		writePairs(ce, keysAndValues)
+	}
 }
 
 func (le *LogEntry) Warnv(msg string, fields ...zapcore.Field) {
@@ -140,7 +409,9 @@ func (le *LogEntry) Warnv(msg string, fields ...zapcore.Field) {
 }
 
 func (le *LogEntry) ErrorWith(msg string, fields Fields) {
-	le.errorLogger.Error(msg, convertFields(fields)...)
+	zfields := getFieldSlice(fields, le.sortFields)
+	le.errorLogger.Error(msg, *zfields...)
+	putFieldSlice(zfields)
 }
 
 func (le *LogEntry) Error(msg string) {
@@ -149,15 +420,19 @@ func (le *LogEntry) Error(msg string) {
 }
 
 func (le *LogEntry) Errorf(template string, args ...interface{}) {
-	le.errorSugared.Errorf(template, args)
+	le.errorSugared().Errorf(template, args...)
 }
 
 func (le *LogEntry) Errorln(args ...interface{}) {
-	le.errorSugared.Errorln(args)
+	le.errorSugared().Errorln(args...)
 }
 
 func (le *LogEntry) Errorw(msg string, keysAndValues ...interface{}) {
-	le.errorSugared.Errorw(msg, keysAndValues...)
+	if !plainPairs(keysAndValues) {
+		le.errorSugared().Errorw(msg, keysAndValues...)
+	} else if ce := le.errorLogger.Check(ErrorLevel, msg); ce != nil {
+		writePairs(ce, keysAndValues)
+	}
 }
 
 func (le *LogEntry) Errorv(msg string, fields ...zapcore.Field) {
@@ -165,7 +440,9 @@ func (le *LogEntry) Errorv(msg string, fields ...zapcore.Field) {
 }
 
 func (le *LogEntry) FatalWith(msg string, fields Fields) {
-	le.errorLogger.Fatal(msg, convertFields(fields)...)
+	zfields := getFieldSlice(fields, le.sortFields)
+	le.errorLogger.Fatal(msg, *zfields...)
+	putFieldSlice(zfields)
 }
 
 func (le *LogEntry) Fatal(msg string) {
@@ -174,15 +451,19 @@ func (le *LogEntry) Fatal(msg string) {
 }
 
 func (le *LogEntry) Fatalf(template string, args ...interface{}) {
-	le.errorSugared.Fatalf(template, args)
+	le.errorSugared().Fatalf(template, args...)
 }
 
 func (le *LogEntry) Fatalln(args ...interface{}) {
-	le.errorSugared.Fatalln(args)
+	le.errorSugared().Fatalln(args...)
 }
 
 func (le *LogEntry) Fatalw(msg string, keysAndValues ...interface{}) {
-	le.errorSugared.Fatalw(msg, keysAndValues...)
+	if !plainPairs(keysAndValues) {
+		le.errorSugared().Fatalw(msg, keysAndValues...)
+	} else if ce := le.errorLogger.Check(FatalLevel, msg); ce != nil {
+		writePairs(ce, keysAndValues)
+	}
 }
 
 func (le *LogEntry) Fatalv(msg string, fields ...zapcore.Field) {
@@ -190,7 +471,9 @@ func (le *LogEntry) Fatalv(msg string, fields ...zapcore.Field) {
 }
 
 func (le *LogEntry) PanicWith(msg string, fields Fields) {
-	le.errorLogger.Panic(msg, convertFields(fields)...)
+	zfields := getFieldSlice(fields, le.sortFields)
+	le.errorLogger.Panic(msg, *zfields...)
+	putFieldSlice(zfields)
 }
 
 func (le *LogEntry) Panic(msg string) {
@@ -199,15 +482,19 @@ func (le *LogEntry) Panic(msg string) {
 }
 
 func (le *LogEntry) Panicf(template string, args ...interface{}) {
-	le.errorSugared.Panicf(template, args)
+	le.errorSugared().Panicf(template, args...)
 }
 
 func (le *LogEntry) Panicln(args ...interface{}) {
-	le.errorSugared.Panicln(args)
+	le.errorSugared().Panicln(args...)
 }
 
 func (le *LogEntry) Panicw(msg string, keysAndValues ...interface{}) {
-	le.errorSugared.Panicw(msg, keysAndValues...)
+	if !plainPairs(keysAndValues) {
+		le.errorSugared().Panicw(msg, keysAndValues...)
+	} else if ce := le.errorLogger.Check(PanicLevel, msg); ce != nil {
+		writePairs(ce, keysAndValues)
+	}
 }
 
 func (le *LogEntry) Panicv(msg string, fields ...zapcore.Field) {
@@ -215,7 +502,9 @@ func (le *LogEntry) Panicv(msg string, fields ...zapcore.Field) {
 }
 
 func (le *LogEntry) DPanicWith(msg string, fields Fields) {
-	le.errorLogger.DPanic(msg, convertFields(fields)...)
+	zfields := getFieldSlice(fields, le.sortFields)
+	le.errorLogger.DPanic(msg, *zfields...)
+	putFieldSlice(zfields)
 }
 
 func (le *LogEntry) DPanic(msg string) {
@@ -224,15 +513,19 @@ func (le *LogEntry) DPanic(msg string) {
 }
 
 func (le *LogEntry) DPanicf(template string, args ...interface{}) {
-	le.errorSugared.DPanicf(template, args)
+	le.errorSugared().DPanicf(template, args...)
 }
 
 func (le *LogEntry) DPanicln(args ...interface{}) {
-	le.errorSugared.DPanicln(args)
+	le.errorSugared().DPanicln(args...)
 }
 
 func (le *LogEntry) DPanicw(msg string, keysAndValues ...interface{}) {
-	le.errorSugared.DPanicw(msg, keysAndValues...)
+	if !plainPairs(keysAndValues) {
+		le.errorSugared().DPanicw(msg, keysAndValues...)
+	} else if ce := le.errorLogger.Check(DPanicLevel, msg); ce != nil {
+		writePairs(ce, keysAndValues)
+	}
 }
 
 func (le *LogEntry) DPanicv(msg string, fields ...zapcore.Field) {
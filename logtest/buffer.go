@@ -0,0 +1,122 @@
+// Package logtest provides test loggers, in-memory sinks, a fake clock and
+// golden file helpers for tests of code logging with package log. The sinks
+// are safe to share between goroutines under -race.
+package logtest
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Record is one write received by a ConcurrentBuffer
+type Record struct {
+	// Seq is the position of the record in the order the writes were received
+	Seq int
+	// Goroutine is the id of the goroutine which wrote the record
+	Goroutine uint64
+	// Data is the encoded record without the trailing newline
+	Data []byte
+}
+
+func (r Record) String() string {
+	return string(r.Data)
+}
+
+// ConcurrentBuffer is a race-safe zapcore.WriteSyncer keeping every record in
+// the order it was written together with the goroutine which wrote it. It can
+// be given to log.SetOutput, Config.ExtraInfoSinks or any other sink option.
+type ConcurrentBuffer struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+// NewConcurrentBuffer returns an empty buffer
+func NewConcurrentBuffer() *ConcurrentBuffer {
+	return &ConcurrentBuffer{}
+}
+
+func (b *ConcurrentBuffer) Write(p []byte) (int, error) {
+	gid := goroutineID()
+	data := append([]byte(nil), bytes.TrimRight(p, "\n")...)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.records = append(b.records, Record{Seq: len(b.records), Goroutine: gid, Data: data})
+	return len(p), nil
+}
+
+func (b *ConcurrentBuffer) Sync() error {
+	return nil
+}
+
+// Records returns a copy of the records in write order
+func (b *ConcurrentBuffer) Records() []Record {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Record(nil), b.records...)
+}
+
+// Lines returns the records as strings in write order
+func (b *ConcurrentBuffer) Lines() []string {
+	records := b.Records()
+	lines := make([]string, len(records))
+	for i, r := range records {
+		lines[i] = r.String()
+	}
+	return lines
+}
+
+// ByGoroutine returns the records in write order grouped by the goroutine
+// which wrote them
+func (b *ConcurrentBuffer) ByGoroutine() map[uint64][]Record {
+	grouped := map[uint64][]Record{}
+	for _, r := range b.Records() {
+		grouped[r.Goroutine] = append(grouped[r.Goroutine], r)
+	}
+	return grouped
+}
+
+// Len returns the number of records
+func (b *ConcurrentBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.records)
+}
+
+// String returns every record followed by a newline, like a log file
+func (b *ConcurrentBuffer) String() string {
+	var sb strings.Builder
+	for _, r := range b.Records() {
+		sb.Write(r.Data)
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// Reset drops the records
+func (b *ConcurrentBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.records = nil
+}
+
+// GoroutineID returns the id of the calling goroutine, to match the
+// Record.Goroutine of records written by a goroutine
+func GoroutineID() uint64 {
+	return goroutineID()
+}
+
+// goroutineID parses the id from the "goroutine N [running]:" stack header
+func goroutineID() uint64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	header := bytes.TrimPrefix(buf[:n], []byte("goroutine "))
+	if i := bytes.IndexByte(header, ' '); i > 0 {
+		header = header[:i]
+	}
+	id, _ := strconv.ParseUint(string(header), 10, 64)
+	return id
+}
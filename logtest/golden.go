@@ -0,0 +1,171 @@
+package logtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olee12/log"
+)
+
+// UpdateEnv names the environment variable which makes MatchJSONLines
+// rewrite the golden files with the output when set to 1, e.g.
+// LOGTEST_UPDATE=1 go test ./...
+const UpdateEnv = "LOGTEST_UPDATE"
+
+// Placeholders replacing the values which change between runs
+const (
+	TimePlaceholder   = "<time>"
+	CallerPlaceholder = "<caller>"
+	StackPlaceholder  = "<stack>"
+)
+
+// NormalizeJSONLines decodes JSON encoded entries, one per line, replacing
+// the time, caller and stacktrace values with placeholders. Numbers are
+// kept as json.Number.
+func NormalizeJSONLines(data []byte) ([]map[string]interface{}, error) {
+	var records []map[string]interface{}
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.UseNumber()
+		record := map[string]interface{}{}
+		if err := dec.Decode(&record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if _, ok := record[log.TimeKey]; ok {
+			record[log.TimeKey] = TimePlaceholder
+		}
+		// fields of the same names with other values are kept, like the
+		// boolean caller of the "logging configured" and "starting" entries
+		for key, placeholder := range map[string]string{
+			log.CallerKey:     CallerPlaceholder,
+			log.StacktraceKey: StackPlaceholder,
+		} {
+			if _, ok := record[key].(string); ok {
+				record[key] = placeholder
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// normalizedLines encodes the normalized records with sorted keys, one per line
+func normalizedLines(data []byte) ([]string, error) {
+	records, err := NormalizeJSONLines(data)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, len(records))
+	for i, record := range records {
+		var b bytes.Buffer
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(record); err != nil {
+			return nil, err
+		}
+		lines[i] = strings.TrimSuffix(b.String(), "\n")
+	}
+	return lines, nil
+}
+
+// MatchJSONLines fails the test unless the JSON entries in got equal the ones
+// of the golden file after normalizing both, ignoring the key order. With
+// LOGTEST_UPDATE=1 it writes the normalized output to the golden file instead.
+func MatchJSONLines(t testing.TB, got []byte, goldenPath string) {
+	t.Helper()
+	gotLines, err := normalizedLines(got)
+	if err != nil {
+		t.Fatalf("log output: %v", err)
+	}
+	if os.Getenv(UpdateEnv) == "1" {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		content := strings.Join(gotLines, "\n")
+		if len(gotLines) > 0 {
+			content += "\n"
+		}
+		if err := os.WriteFile(goldenPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	golden, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("golden file: %v, run with %s=1 to create it", err, UpdateEnv)
+	}
+	wantLines, err := normalizedLines(golden)
+	if err != nil {
+		t.Fatalf("golden file %s: %v", goldenPath, err)
+	}
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var gotLine, wantLine string
+		if i < len(gotLines) {
+			gotLine = gotLines[i]
+		}
+		if i < len(wantLines) {
+			wantLine = wantLines[i]
+		}
+		if gotLine != wantLine {
+			t.Fatalf("log entry %d differs from %s\n got: %s\nwant: %s", i+1, goldenPath, gotLine, wantLine)
+		}
+	}
+}
+
+// HasFields reports whether the record holds the fields with the values,
+// compared as JSON so 1 matches the decoded json.Number 1. Nested objects
+// match when they hold the nested fields, other fields of the record are
+// ignored.
+func HasFields(record map[string]interface{}, fields map[string]interface{}) bool {
+	for key, want := range fields {
+		got, ok := record[key]
+		if !ok {
+			return false
+		}
+		wantObj, wantIsObj := want.(map[string]interface{})
+		gotObj, gotIsObj := got.(map[string]interface{})
+		if wantIsObj && gotIsObj {
+			if !HasFields(gotObj, wantObj) {
+				return false
+			}
+			continue
+		}
+		if !sameJSON(got, want) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameJSON(a, b interface{}) bool {
+	aj, aErr := json.Marshal(a)
+	bj, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && bytes.Equal(aj, bj)
+}
+
+// ContainsEntry fails the test unless one of the JSON entries in got holds
+// the fields, see HasFields
+func ContainsEntry(t testing.TB, got []byte, fields map[string]interface{}) {
+	t.Helper()
+	records, err := NormalizeJSONLines(got)
+	if err != nil {
+		t.Fatalf("log output: %v", err)
+	}
+	for _, record := range records {
+		if HasFields(record, fields) {
+			return
+		}
+	}
+	want, _ := json.Marshal(fields)
+	t.Fatalf("no log entry with %s in %d entries:\n%s", want, len(records), got)
+}
@@ -0,0 +1,60 @@
+package logtest
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/olee12/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// NewTestLogger returns a LogEntry which records every entry in memory instead
+// of writing it, so tests can assert on what was logged. When the test fails
+// the recorded entries are printed with t.Log. Like with the loggers built
+// from a Config, the caller is the code calling the LogEntry methods.
+func NewTestLogger(t testing.TB) (*log.LogEntry, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	t.Cleanup(func() {
+		if !t.Failed() {
+			return
+		}
+		for _, entry := range logs.All() {
+			t.Logf("%s %s %v", entry.Level, entry.Message, entry.ContextMap())
+		}
+	})
+	return log.NewWithCore(core, zap.AddCaller(), zap.AddCallerSkip(1)), logs
+}
+
+// NewTBLogger returns a LogEntry writing every entry at debug level and above
+// with tb.Log, so the output is attributed to the test and shown only for
+// failing tests or with -v. Entries written after the test ended are dropped.
+func NewTBLogger(tb testing.TB) *log.LogEntry {
+	w := &tbWriter{tb: tb}
+	tb.Cleanup(func() { w.done.Store(true) })
+	return log.NewWithWriter(log.Config{
+		Level:         log.DebugLevel,
+		CallerEnabled: true,
+		CallerSkip:    1,
+		LevelEncoder:  zapcore.LowercaseLevelEncoder,
+	}, w)
+}
+
+// tbWriter writes the encoded entries with tb.Log, which panics once the test ended
+type tbWriter struct {
+	tb   testing.TB
+	done atomic.Bool
+}
+
+func (w *tbWriter) Write(p []byte) (int, error) {
+	if !w.done.Load() {
+		w.tb.Log(strings.TrimSuffix(string(p), "\n"))
+	}
+	return len(p), nil
+}
+
+func (w *tbWriter) Sync() error {
+	return nil
+}
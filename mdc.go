@@ -0,0 +1,109 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// The mapped diagnostic context holds fields per goroutine, pushed and popped
+// by the code logging, for codebases migrating from Java style MDC logging.
+// The loggers configured with Config.MDC add the fields of the goroutine
+// writing the entry. It is experimental: the fields stay with the goroutine
+// which pushed them, goroutines it starts do not see them, and every entry
+// costs a lookup of the goroutine id while fields are pushed. Passing a
+// logger or a context is preferred.
+var (
+	mdcMu sync.RWMutex
+	mdc   = map[uint64][][]zapcore.Field{}
+	// mdcPushed counts the goroutines with fields, the lookup is skipped at 0
+	mdcPushed atomic.Int64
+)
+
+// MDCToken undoes a PushFields, see PopFields
+type MDCToken struct {
+	goroutine uint64
+	depth     int
+}
+
+// PushFields adds the fields to the mapped diagnostic context of the calling
+// goroutine until PopFields is called with the returned token:
+//
+//	defer log.PopFields(log.PushFields(log.Fields{"order_id": id}))
+//
+// It is experimental, see Config.MDC.
+func PushFields(fields Fields) MDCToken {
+	id := goroutineID()
+	zfields := appendFields(nil, fields, true)
+	mdcMu.Lock()
+	defer mdcMu.Unlock()
+	stack := mdc[id]
+	if len(stack) == 0 {
+		mdcPushed.Add(1)
+	}
+	mdc[id] = append(stack, zfields)
+	return MDCToken{goroutine: id, depth: len(stack)}
+}
+
+// PopFields removes the fields pushed with the token and the ones pushed
+// after them but not popped. It must be called by the goroutine which pushed
+// the fields.
+func PopFields(token MDCToken) {
+	mdcMu.Lock()
+	defer mdcMu.Unlock()
+	stack, ok := mdc[token.goroutine]
+	if !ok || token.depth >= len(stack) {
+		return
+	}
+	if token.depth == 0 {
+		delete(mdc, token.goroutine)
+		mdcPushed.Add(-1)
+		return
+	}
+	mdc[token.goroutine] = stack[:token.depth]
+}
+
+// mdcFields returns the fields pushed by the goroutine, oldest first
+func mdcFields(id uint64) []zapcore.Field {
+	mdcMu.RLock()
+	defer mdcMu.RUnlock()
+	var fields []zapcore.Field
+	for _, pushed := range mdc[id] {
+		fields = append(fields, pushed...)
+	}
+	return fields
+}
+
+// mdcCore adds the fields of the mapped diagnostic context of the writing
+// goroutine, entries are written by the goroutine logging them
+type mdcCore struct {
+	zapcore.Core
+}
+
+func newMDCCore(core zapcore.Core, enabled bool) zapcore.Core {
+	if !enabled {
+		return core
+	}
+	return &mdcCore{Core: core}
+}
+
+func (c *mdcCore) With(fields []zapcore.Field) zapcore.Core {
+	return &mdcCore{Core: c.Core.With(fields)}
+}
+
+func (c *mdcCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *mdcCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if mdcPushed.Load() > 0 {
+		if pushed := mdcFields(goroutineID()); len(pushed) > 0 {
+			fields = append(pushed, fields...)
+		}
+	}
+	return c.Core.Write(ent, fields)
+}
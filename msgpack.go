@@ -0,0 +1,296 @@
+package log
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// msgpackWriter is the small subset of msgpack needed by the network sinks
+type msgpackWriter struct {
+	buf []byte
+}
+
+func (w *msgpackWriter) reset() {
+	w.buf = w.buf[:0]
+}
+
+func (w *msgpackWriter) writeNil() {
+	w.buf = append(w.buf, 0xc0)
+}
+
+func (w *msgpackWriter) writeBool(b bool) {
+	if b {
+		w.buf = append(w.buf, 0xc3)
+	} else {
+		w.buf = append(w.buf, 0xc2)
+	}
+}
+
+func (w *msgpackWriter) writeInt(i int64) {
+	switch {
+	case i >= 0 && i < 128:
+		w.buf = append(w.buf, byte(i))
+	case i < 0 && i >= -32:
+		w.buf = append(w.buf, byte(i))
+	default:
+		w.buf = append(w.buf, 0xd3)
+		w.buf = binary.BigEndian.AppendUint64(w.buf, uint64(i))
+	}
+}
+
+func (w *msgpackWriter) writeFloat(f float64) {
+	w.buf = append(w.buf, 0xcb)
+	w.buf = binary.BigEndian.AppendUint64(w.buf, math.Float64bits(f))
+}
+
+func (w *msgpackWriter) writeString(s string) {
+	switch n := len(s); {
+	case n < 32:
+		w.buf = append(w.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		w.buf = append(w.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		w.buf = append(w.buf, 0xda)
+		w.buf = binary.BigEndian.AppendUint16(w.buf, uint16(n))
+	default:
+		w.buf = append(w.buf, 0xdb)
+		w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(n))
+	}
+	w.buf = append(w.buf, s...)
+}
+
+func (w *msgpackWriter) writeArrayHeader(n int) {
+	switch {
+	case n < 16:
+		w.buf = append(w.buf, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		w.buf = append(w.buf, 0xdc)
+		w.buf = binary.BigEndian.AppendUint16(w.buf, uint16(n))
+	default:
+		w.buf = append(w.buf, 0xdd)
+		w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(n))
+	}
+}
+
+func (w *msgpackWriter) writeMapHeader(n int) {
+	switch {
+	case n < 16:
+		w.buf = append(w.buf, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		w.buf = append(w.buf, 0xde)
+		w.buf = binary.BigEndian.AppendUint16(w.buf, uint16(n))
+	default:
+		w.buf = append(w.buf, 0xdf)
+		w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(n))
+	}
+}
+
+// writeEventTime writes the fluentd EventTime extension (type 0)
+func (w *msgpackWriter) writeEventTime(t time.Time) {
+	w.buf = append(w.buf, 0xd7, 0x00)
+	w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(t.Unix()))
+	w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(t.Nanosecond()))
+}
+
+// writeValue writes values as produced by decoding JSON with UseNumber
+func (w *msgpackWriter) writeValue(v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		w.writeNil()
+	case bool:
+		w.writeBool(v)
+	case string:
+		w.writeString(v)
+	case int:
+		w.writeInt(int64(v))
+	case int64:
+		w.writeInt(v)
+	case float64:
+		w.writeFloat(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			w.writeInt(i)
+		} else if f, err := v.Float64(); err == nil {
+			w.writeFloat(f)
+		} else {
+			w.writeString(v.String())
+		}
+	case []interface{}:
+		w.writeArrayHeader(len(v))
+		for _, e := range v {
+			w.writeValue(e)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		w.writeMapHeader(len(v))
+		for _, k := range keys {
+			w.writeString(k)
+			w.writeValue(v[k])
+		}
+	default:
+		w.writeString(fmt.Sprint(v))
+	}
+}
+
+// msgpackReader decodes msgpack values into nil, bool, int64, uint64,
+// float64, string, []byte, time.Time, []interface{} and map[string]interface{}
+type msgpackReader struct {
+	r *bufio.Reader
+}
+
+func (r *msgpackReader) readValue() (interface{}, error) {
+	b, err := r.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case b <= 0x7f:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b&0xe0 == 0xa0:
+		return r.readString(int(b & 0x1f))
+	case b&0xf0 == 0x90:
+		return r.readArray(int(b & 0x0f))
+	case b&0xf0 == 0x80:
+		return r.readMap(int(b & 0x0f))
+	}
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := r.readLength(b - 0xc4)
+		if err != nil {
+			return nil, err
+		}
+		return r.readBytes(n)
+	case 0xca:
+		u, err := r.readUint(4)
+		return float64(math.Float32frombits(uint32(u))), err
+	case 0xcb:
+		u, err := r.readUint(8)
+		return math.Float64frombits(u), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return r.readUint(1 << (b - 0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (b - 0xd0)
+		u, err := r.readUint(size)
+		shift := 64 - 8*size
+		return int64(u<<shift) >> shift, err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		ext, err := r.readBytes(1 + 1<<(b-0xd4))
+		if err != nil {
+			return nil, err
+		}
+		if ext[0] == 0 && len(ext) == 9 {
+			sec := binary.BigEndian.Uint32(ext[1:5])
+			nsec := binary.BigEndian.Uint32(ext[5:9])
+			return time.Unix(int64(sec), int64(nsec)), nil
+		}
+		return ext[1:], nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := r.readLength(b - 0xd9)
+		if err != nil {
+			return nil, err
+		}
+		return r.readString(n)
+	case 0xdc, 0xdd:
+		n, err := r.readLength(b - 0xdc + 1)
+		if err != nil {
+			return nil, err
+		}
+		return r.readArray(n)
+	case 0xde, 0xdf:
+		n, err := r.readLength(b - 0xde + 1)
+		if err != nil {
+			return nil, err
+		}
+		return r.readMap(n)
+	}
+	return nil, fmt.Errorf("unsupported msgpack type 0x%x", b)
+}
+
+// readLength reads a 1, 2 or 4 byte length for class 0, 1 or 2
+func (r *msgpackReader) readLength(class byte) (int, error) {
+	u, err := r.readUint(1 << class)
+	return int(u), err
+}
+
+func (r *msgpackReader) readUint(size int) (uint64, error) {
+	b, err := r.readBytes(size)
+	if err != nil {
+		return 0, err
+	}
+	var u uint64
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return u, nil
+}
+
+func (r *msgpackReader) readBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(r.r, b)
+	return b, err
+}
+
+func (r *msgpackReader) readString(n int) (interface{}, error) {
+	b, err := r.readBytes(n)
+	return string(b), err
+}
+
+func (r *msgpackReader) readArray(n int) (interface{}, error) {
+	arr := make([]interface{}, n)
+	for i := range arr {
+		v, err := r.readValue()
+		if err != nil {
+			return nil, err
+		}
+		arr[i] = v
+	}
+	return arr, nil
+}
+
+func (r *msgpackReader) readMap(n int) (interface{}, error) {
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := r.readValue()
+		if err != nil {
+			return nil, err
+		}
+		v, err := r.readValue()
+		if err != nil {
+			return nil, err
+		}
+		m[msgpackString(k)] = v
+	}
+	return m, nil
+}
+
+// msgpackString returns string and bin values as string
+func msgpackString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprint(v)
+}
+
+var errMsgpackShape = errors.New("unexpected msgpack message shape")
@@ -0,0 +1,43 @@
+package log
+
+import (
+	"io"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// NewNop returns a LogEntry which discards everything
+func NewNop() *LogEntry {
+	return getLogEntry(zap.NewNop(), zap.NewNop())
+}
+
+// NewWithCore returns a LogEntry writing every level to core, without the
+// pipeline built from a Config, e.g. for the in-memory observer of
+// logtest.NewTestLogger
+func NewWithCore(core zapcore.Core, opts ...zap.Option) *LogEntry {
+	return newScopedEntry(zap.New(core, opts...), zap.New(core, opts...))
+}
+
+// NewWithWriter returns a LogEntry built from config which writes every
+// level to w instead of the files, consoles and sinks of the config, e.g. for
+// logtest.NewTBLogger
+func NewWithWriter(config Config, w io.Writer) *LogEntry {
+	out := zapcore.AddSync(w)
+	return newZapLogger(config, out, out, zap.NewAtomicLevelAt(thresholdLevel(config.Level)))
+}
+
+// SetOutput redirects all levels of the default logger to w, keeping its current
+// config and level. SetOutput(io.Discard) silences the default logger, which is
+// handy in tests of libraries depending on this package.
+func SetOutput(w io.Writer) {
+	configureMu.Lock()
+	defer configureMu.Unlock()
+
+	current := defaults.Load()
+	config := current.config
+	config.Level = current.level.Level()
+	level := zap.NewAtomicLevelAt(config.Level)
+	output := zapcore.AddSync(w)
+	swapDefault(newZapLogger(config, output, output, level), level, config)
+}
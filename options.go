@@ -0,0 +1,75 @@
+package log
+
+import (
+	"io"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Option sets up a logger built by New
+type Option func(*Config)
+
+// New creates a logger from options instead of a Config, e.g.
+// log.New(log.WithLevel(log.InfoLevel), log.WithJSON(), log.WithRotation("logs", 100, 30, 10)).
+// Without options it logs to stdout and stderr at info level.
+func New(opts ...Option) *LogEntry {
+	var config Config
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return NewLogEntry(config)
+}
+
+// WithConfig starts from the config, the options after it override its settings
+func WithConfig(config Config) Option {
+	return func(c *Config) {
+		*c = config
+	}
+}
+
+// WithLevel sets the minimum level written
+func WithLevel(l Level) Option {
+	return func(c *Config) {
+		c.Level = l
+	}
+}
+
+// WithJSON encodes the entries as JSON
+func WithJSON() Option {
+	return func(c *Config) {
+		c.EncodeLogsAsJson = true
+	}
+}
+
+// WithCaller adds the caller to the entries
+func WithCaller() Option {
+	return func(c *Config) {
+		c.CallerEnabled = true
+	}
+}
+
+// WithRotation logs to rolling files in dir, rotated after maxSizeMB and
+// kept for maxAgeDays, at most maxBackups of them
+func WithRotation(dir string, maxSizeMB, maxAgeDays, maxBackups int) Option {
+	return func(c *Config) {
+		c.FileLoggingEnabled = true
+		c.Directory = dir
+		c.MaxSize = maxSizeMB
+		c.MaxAge = maxAgeDays
+		c.MaxBackups = maxBackups
+	}
+}
+
+// WithConsole logs the debug and info entries to w and the more severe ones
+// to errW, nil keeps stdout or stderr
+func WithConsole(w, errW io.Writer) Option {
+	return func(c *Config) {
+		c.ConsoleLoggingEnabled = true
+		if w != nil {
+			c.consoleInfo = zapcore.AddSync(w)
+		}
+		if errW != nil {
+			c.consoleError = zapcore.AddSync(errW)
+		}
+	}
+}
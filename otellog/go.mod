@@ -0,0 +1,23 @@
+module github.com/olee12/log/otellog
+
+go 1.21
+
+replace github.com/olee12/log => ../
+
+require (
+	github.com/olee12/log v0.0.0-00010101000000-000000000000
+	go.opentelemetry.io/otel/log v0.5.0
+	go.opentelemetry.io/otel/trace v1.29.0
+	go.uber.org/zap v1.26.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	go.opentelemetry.io/otel v1.29.0 // indirect
+	go.opentelemetry.io/otel/metric v1.29.0 // indirect
+	go.uber.org/multierr v1.11.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	golang.org/x/term v0.21.0 // indirect
+	gopkg.in/natefinch/lumberjack.v2 v2.2.1 // indirect
+)
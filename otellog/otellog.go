@@ -0,0 +1,201 @@
+// Package otellog implements the OpenTelemetry Logs Bridge API with package
+// log: libraries emitting records through go.opentelemetry.io/otel/log write
+// them to the files and sinks of a LogEntry, encoded like its own entries.
+// Install it as the global provider with
+//
+//	global.SetLoggerProvider(otellog.NewLoggerProvider(nil))
+//
+// SpanTrace correlates the entries with the OpenTelemetry spans, see
+// log.RegisterTraceExtractor.
+package otellog
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/olee12/log"
+	logapi "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/embedded"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// BodyKey holds the body of records whose body is not a string, their
+// message is empty
+const BodyKey = "body"
+
+// LoggerProvider implements logapi.LoggerProvider
+type LoggerProvider struct {
+	embedded.LoggerProvider
+	le *log.LogEntry
+}
+
+// NewLoggerProvider returns a provider of loggers writing to le, nil writes
+// to the logger of the context of every record, or the default logger
+func NewLoggerProvider(le *log.LogEntry) *LoggerProvider {
+	return &LoggerProvider{le: le}
+}
+
+// Logger returns a logger putting name, the instrumentation scope, in the
+// logger field and the instrumentation attributes in every entry
+func (p *LoggerProvider) Logger(name string, options ...logapi.LoggerOption) logapi.Logger {
+	config := logapi.NewLoggerConfig(options...)
+	var fields []zapcore.Field
+	if name != "" {
+		fields = append(fields, zap.String(log.NameKey, name))
+	}
+	attrs := config.InstrumentationAttributes()
+	for iter := attrs.Iter(); iter.Next(); {
+		attr := iter.Attribute()
+		fields = append(fields, zap.Any(string(attr.Key), attr.Value.AsInterface()))
+	}
+	l := &logger{le: p.le, fields: fields}
+	if p.le != nil {
+		l.skipped.Store(&skippedLogger{base: p.le, logger: p.le.WithCallerSkip(1)})
+	}
+	return l
+}
+
+// logger implements logapi.Logger
+type logger struct {
+	embedded.Logger
+	le     *log.LogEntry
+	fields []zapcore.Field
+	// skipped caches the base logger skipping the frame of Emit
+	skipped atomic.Pointer[skippedLogger]
+}
+
+// skippedLogger is a base logger and the logger deriving from it which skips
+// the frame of Emit
+type skippedLogger struct {
+	base, logger *log.LogEntry
+}
+
+// base returns the logger writing the records, le or the one of the context
+func (l *logger) base(ctx context.Context) *log.LogEntry {
+	if l.le != nil {
+		return l.le
+	}
+	return log.LoggerFromContext(ctx)
+}
+
+// Enabled checks the level on the base logger, the fields of the context do
+// not change it
+func (l *logger) Enabled(ctx context.Context, record logapi.Record) bool {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return l.base(ctx).Enabled(level(record.Severity()))
+}
+
+// emitter returns the logger of the record skipping the frame of Emit,
+// carrying the fields of the context. The skipping logger is derived again
+// only when the logger of the context changes.
+func (l *logger) emitter(ctx context.Context) *log.LogEntry {
+	base := l.base(ctx)
+	s := l.skipped.Load()
+	if s == nil || s.base != base {
+		s = &skippedLogger{base: base, logger: base.WithCallerSkip(1)}
+		l.skipped.Store(s)
+	}
+	return s.logger.FromContext(ctx)
+}
+
+// Emit writes the record with the caller of Emit, the time of the record and
+// its attributes as fields. With log.Config.TraceCorrelation the span of the
+// context is added when SpanTrace is registered.
+func (l *logger) Emit(ctx context.Context, record logapi.Record) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	body := record.Body()
+	msg := ""
+	if body.Kind() == logapi.KindString {
+		msg = body.AsString()
+	}
+	ce := l.emitter(ctx).Check(level(record.Severity()), msg)
+	if ce == nil {
+		return
+	}
+	fields := make([]zapcore.Field, 0, len(l.fields)+record.AttributesLen()+1)
+	fields = append(fields, l.fields...)
+	if msg == "" && !body.Empty() {
+		fields = append(fields, zap.Any(BodyKey, value(body)))
+	}
+	record.WalkAttributes(func(kv logapi.KeyValue) bool {
+		fields = append(fields, field(kv))
+		return true
+	})
+	ce.WithTime(record.Timestamp()).Write(fields...)
+}
+
+// level maps the severity numbers to the levels, the inverse of the mapping
+// of otlplog. The error and fatal ranges are written at error: records of
+// other libraries do not panic or exit. Unset severities are info.
+func level(severity logapi.Severity) log.Level {
+	switch {
+	case severity <= 0:
+		return log.InfoLevel
+	case severity < logapi.SeverityDebug:
+		return log.TraceLevel
+	case severity < logapi.SeverityInfo:
+		return log.DebugLevel
+	case severity == logapi.SeverityInfo:
+		return log.InfoLevel
+	case severity < logapi.SeverityWarn:
+		return log.NoticeLevel
+	case severity < logapi.SeverityError:
+		return log.WarnLevel
+	}
+	return log.ErrorLevel
+}
+
+// field converts an attribute
+func field(kv logapi.KeyValue) zapcore.Field {
+	switch kv.Value.Kind() {
+	case logapi.KindBool:
+		return zap.Bool(kv.Key, kv.Value.AsBool())
+	case logapi.KindFloat64:
+		return zap.Float64(kv.Key, kv.Value.AsFloat64())
+	case logapi.KindInt64:
+		return zap.Int64(kv.Key, kv.Value.AsInt64())
+	case logapi.KindString:
+		return zap.String(kv.Key, kv.Value.AsString())
+	case logapi.KindBytes:
+		return zap.Binary(kv.Key, kv.Value.AsBytes())
+	}
+	return zap.Any(kv.Key, value(kv.Value))
+}
+
+// value converts a value to the Go values encoded like them, slices and
+// maps recursively
+func value(v logapi.Value) interface{} {
+	switch v.Kind() {
+	case logapi.KindEmpty:
+		return nil
+	case logapi.KindBool:
+		return v.AsBool()
+	case logapi.KindFloat64:
+		return v.AsFloat64()
+	case logapi.KindInt64:
+		return v.AsInt64()
+	case logapi.KindString:
+		return v.AsString()
+	case logapi.KindBytes:
+		return v.AsBytes()
+	case logapi.KindSlice:
+		values := make([]interface{}, 0, len(v.AsSlice()))
+		for _, e := range v.AsSlice() {
+			values = append(values, value(e))
+		}
+		return values
+	case logapi.KindMap:
+		m := make(map[string]interface{}, len(v.AsMap()))
+		for _, kv := range v.AsMap() {
+			m[kv.Key] = value(kv.Value)
+		}
+		return m
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,20 @@
+package otellog
+
+import (
+	"context"
+
+	"github.com/olee12/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SpanTrace returns the trace of the OpenTelemetry span of the context, for
+// the trace correlation of package log:
+//
+//	log.RegisterTraceExtractor(otellog.SpanTrace)
+func SpanTrace(ctx context.Context) (log.TraceContext, bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return log.TraceContext{}, false
+	}
+	return log.TraceContext{TraceID: sc.TraceID().String(), SpanID: sc.SpanID().String(), Sampled: sc.IsSampled()}, true
+}
@@ -0,0 +1,182 @@
+// Package otlplog exports the entries of package log to an OpenTelemetry
+// Collector with the OTLP/gRPC logs protocol
+package otlplog
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olee12/log"
+	"google.golang.org/grpc"
+)
+
+// exportMethod is the OTLP logs service method
+const exportMethod = "/opentelemetry.proto.collector.logs.v1.LogsService/Export"
+
+// Config configures the resource and scope of the exported records
+type Config struct {
+	// ServiceName is the service.name resource attribute
+	ServiceName string
+	// Resource holds further resource attributes, e.g. deployment.environment
+	Resource log.Fields
+	// ScopeName is the instrumentation scope, the import path of package log
+	// by default
+	ScopeName string
+	// Timeout bounds an export, 10s by default
+	Timeout time.Duration
+}
+
+// Sink exports JSON encoded entries to the LogsService of a collector. It is
+// meant to be used in log.Config.ExtraInfoSinks and ExtraErrorSinks of a
+// logger with EncodeLogsAsJson enabled, other lines are exported as bodies.
+// Every write is one export request, with many records for the writes of
+// log.LogBatch. Records the collector rejects are returned as a
+// log.RejectionError, log.RetrySink retries the other failures.
+type Sink struct {
+	cc       grpc.ClientConnInterface
+	config   Config
+	resource []byte
+	scope    []byte
+}
+
+// NewSink returns a sink exporting over the connection
+func NewSink(cc grpc.ClientConnInterface, config Config) *Sink {
+	if config.ScopeName == "" {
+		config.ScopeName = "github.com/olee12/log"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	attrs := log.Fields{}
+	for k, v := range config.Resource {
+		attrs[k] = v
+	}
+	if config.ServiceName != "" {
+		attrs["service.name"] = config.ServiceName
+	}
+	return &Sink{
+		cc:       cc,
+		config:   config,
+		resource: appendResource(nil, attrs),
+		scope:    appendScope(nil, config.ScopeName),
+	}
+}
+
+func (s *Sink) Write(p []byte) (int, error) {
+	observed := time.Now()
+	var records [][]byte
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\n"), []byte("\n")) {
+		if len(line) > 0 {
+			records = append(records, appendLogRecord(nil, newLogRecord(log.ParseRecord(line), observed)))
+		}
+	}
+	if len(records) == 0 {
+		return len(p), nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+	var resp rawMessage
+	req := rawMessage(appendExportRequest(nil, s.resource, s.scope, records))
+	if err := s.cc.Invoke(ctx, exportMethod, &req, &resp, grpc.ForceCodec(rawCodec{})); err != nil {
+		return 0, err
+	}
+	if rejected, msg := partialSuccess(resp); rejected > 0 {
+		if msg == "" {
+			msg = strconv.FormatInt(rejected, 10) + " log records rejected"
+		}
+		return 0, log.NewRejectionError("%s", msg)
+	}
+	return len(p), nil
+}
+
+func (s *Sink) Sync() error {
+	return nil
+}
+
+// logRecord is an entry in the terms of the OTLP data model
+type logRecord struct {
+	time, observed time.Time
+	severity       int
+	severityText   string
+	body           string
+	attributes     map[string]interface{}
+	traceID        []byte
+	spanID         []byte
+}
+
+// newLogRecord maps the record: the caller, logger and stack become
+// attributes and the trace fields the trace context
+func newLogRecord(rec log.Record, observed time.Time) logRecord {
+	r := logRecord{
+		time:         rec.Time,
+		observed:     observed,
+		severity:     severityNumber(rec.Level),
+		severityText: strings.ToUpper(log.LevelName(rec.Level)),
+		body:         rec.Message,
+		attributes:   rec.Fields,
+	}
+	if r.attributes == nil {
+		r.attributes = map[string]interface{}{}
+	}
+	r.traceID = takeHexID(r.attributes, log.TraceIDKey, 16)
+	r.spanID = takeHexID(r.attributes, log.SpanIDKey, 8)
+	if rec.Logger != "" {
+		r.attributes["logger.name"] = rec.Logger
+	}
+	if file, line, ok := strings.Cut(rec.Caller, ":"); ok {
+		r.attributes["code.filepath"] = file
+		if n, err := strconv.ParseInt(line, 10, 64); err == nil {
+			r.attributes["code.lineno"] = n
+		}
+	} else if rec.Caller != "" {
+		r.attributes["code.filepath"] = rec.Caller
+	}
+	if rec.Stack != "" {
+		r.attributes["exception.stacktrace"] = rec.Stack
+	}
+	return r
+}
+
+// severityNumber maps the levels to the OTLP severity numbers
+func severityNumber(level log.Level) int {
+	switch level {
+	case log.TraceLevel:
+		return 1
+	case log.DebugLevel:
+		return 5
+	case log.InfoLevel:
+		return 9
+	case log.NoticeLevel:
+		return 10
+	case log.WarnLevel:
+		return 13
+	case log.ErrorLevel:
+		return 17
+	case log.DPanicLevel:
+		return 18
+	case log.PanicLevel:
+		return 19
+	case log.FatalLevel:
+		return 21
+	}
+	return 0
+}
+
+// takeHexID removes the hex id of n bytes under key, leaving other values
+func takeHexID(fields map[string]interface{}, key string, n int) []byte {
+	s, ok := fields[key].(string)
+	if !ok {
+		return nil
+	}
+	id, err := hex.DecodeString(s)
+	if err != nil || len(id) != n {
+		return nil
+	}
+	delete(fields, key)
+	return id
+}
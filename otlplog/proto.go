@@ -0,0 +1,198 @@
+package otlplog
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The OTLP messages are encoded with protowire rather than generated code,
+// the field numbers are the ones of opentelemetry/proto/logs/v1/logs.proto,
+// common/v1/common.proto and collector/logs/v1/logs_service.proto. The tests
+// decode them with the messages of go.opentelemetry.io/proto/otlp.
+
+// rawMessage is an encoded message passed through rawCodec
+type rawMessage []byte
+
+// rawCodec sends and receives the encoded messages as they are
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(*rawMessage)
+	if !ok {
+		return nil, fmt.Errorf("otlplog: cannot marshal %T", v)
+	}
+	return *m, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(*rawMessage)
+	if !ok {
+		return fmt.Errorf("otlplog: cannot unmarshal into %T", v)
+	}
+	*m = append((*m)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+// appendMessage appends the embedded message field num built by fn
+func appendMessage(b []byte, num protowire.Number, fn func([]byte) []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, fn(nil))
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+// appendExportRequest encodes an ExportLogsServiceRequest of one resource
+// and one scope holding the encoded LogRecords
+func appendExportRequest(b, resource, scope []byte, records [][]byte) []byte {
+	return appendMessage(b, 1, func(b []byte) []byte {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, resource)
+		return appendMessage(b, 2, func(b []byte) []byte {
+			b = protowire.AppendTag(b, 1, protowire.BytesType)
+			b = protowire.AppendBytes(b, scope)
+			for _, r := range records {
+				b = protowire.AppendTag(b, 2, protowire.BytesType)
+				b = protowire.AppendBytes(b, r)
+			}
+			return b
+		})
+	})
+}
+
+// appendResource encodes a Resource with the attributes
+func appendResource(b []byte, attrs map[string]interface{}) []byte {
+	return appendAttributes(b, 1, attrs)
+}
+
+// appendScope encodes an InstrumentationScope
+func appendScope(b []byte, name string) []byte {
+	return appendString(b, 1, name)
+}
+
+// appendLogRecord encodes a LogRecord
+func appendLogRecord(b []byte, r logRecord) []byte {
+	if !r.time.IsZero() {
+		b = protowire.AppendTag(b, 1, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, uint64(r.time.UnixNano()))
+	}
+	b = protowire.AppendTag(b, 2, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(r.severity))
+	b = appendString(b, 3, r.severityText)
+	b = appendMessage(b, 5, func(b []byte) []byte { return appendString(b, 1, r.body) })
+	b = appendAttributes(b, 6, r.attributes)
+	if r.traceID != nil {
+		b = protowire.AppendTag(b, 9, protowire.BytesType)
+		b = protowire.AppendBytes(b, r.traceID)
+	}
+	if r.spanID != nil {
+		b = protowire.AppendTag(b, 10, protowire.BytesType)
+		b = protowire.AppendBytes(b, r.spanID)
+	}
+	b = protowire.AppendTag(b, 11, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, uint64(r.observed.UnixNano()))
+}
+
+// appendAttributes encodes the attributes as repeated KeyValue field num, in
+// key order
+func appendAttributes(b []byte, num protowire.Number, attrs map[string]interface{}) []byte {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b = appendMessage(b, num, func(b []byte) []byte {
+			b = appendString(b, 1, k)
+			return appendMessage(b, 2, func(b []byte) []byte { return appendAnyValue(b, attrs[k]) })
+		})
+	}
+	return b
+}
+
+// appendAnyValue encodes the fields of an AnyValue holding v, a value
+// decoded from JSON or set by the sink. Nil is the empty value.
+func appendAnyValue(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return b
+	case string:
+		return appendString(b, 1, v)
+	case bool:
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		return protowire.AppendVarint(b, protowire.EncodeBool(v))
+	case int64:
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		return protowire.AppendVarint(b, uint64(v))
+	case int:
+		return appendAnyValue(b, int64(v))
+	case float64:
+		b = protowire.AppendTag(b, 4, protowire.Fixed64Type)
+		return protowire.AppendFixed64(b, math.Float64bits(v))
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return appendAnyValue(b, n)
+		}
+		if f, err := v.Float64(); err == nil {
+			return appendAnyValue(b, f)
+		}
+		return appendString(b, 1, v.String())
+	case []interface{}:
+		return appendMessage(b, 5, func(b []byte) []byte {
+			for _, e := range v {
+				b = appendMessage(b, 1, func(b []byte) []byte { return appendAnyValue(b, e) })
+			}
+			return b
+		})
+	case map[string]interface{}:
+		return appendMessage(b, 6, func(b []byte) []byte { return appendAttributes(b, 1, v) })
+	}
+	return appendString(b, 1, fmt.Sprint(v))
+}
+
+// partialSuccess returns the rejected record count and the error message of
+// an ExportLogsServiceResponse
+func partialSuccess(resp []byte) (rejected int64, msg string) {
+	partial := field(resp, 1)
+	if v := field(partial, 1); v != nil {
+		n, _ := protowire.ConsumeVarint(v)
+		rejected = int64(n)
+	}
+	return rejected, string(field(partial, 2))
+}
+
+// field returns the value of the last field num of the encoded message: the
+// bytes of length delimited fields and the encoded varint otherwise, nil
+// when it is missing or the message is malformed
+func field(b []byte, num protowire.Number) []byte {
+	var value []byte
+	for len(b) > 0 {
+		n, typ, tagLen := protowire.ConsumeTag(b)
+		if tagLen < 0 {
+			return nil
+		}
+		b = b[tagLen:]
+		valueLen := protowire.ConsumeFieldValue(n, typ, b)
+		if valueLen < 0 {
+			return nil
+		}
+		if n == num {
+			value = b[:valueLen]
+			if typ == protowire.BytesType {
+				value, _ = protowire.ConsumeBytes(value)
+			}
+		}
+		b = b[valueLen:]
+	}
+	return value
+}
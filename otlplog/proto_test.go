@@ -0,0 +1,112 @@
+package otlplog
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func stringValue(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+}
+
+func intValue(n int64) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: n}}
+}
+
+// TestExportRequestRoundTrip decodes the encoded request with the messages
+// generated from the OTLP schema, guarding the field numbers
+func TestExportRequestRoundTrip(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 42, time.UTC)
+	observed := at.Add(time.Second)
+	records := [][]byte{
+		appendLogRecord(nil, logRecord{
+			time:         at,
+			observed:     observed,
+			severity:     17,
+			severityText: "ERROR",
+			body:         "failed",
+			attributes: map[string]interface{}{
+				"bool":   true,
+				"float":  1.5,
+				"int":    42,
+				"list":   []interface{}{"a", json.Number("7")},
+				"map":    map[string]interface{}{"key": "value"},
+				"nil":    nil,
+				"number": json.Number("2.5"),
+				"string": "value",
+			},
+			traceID: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+			spanID:  []byte{1, 2, 3, 4, 5, 6, 7, 8},
+		}),
+		appendLogRecord(nil, logRecord{observed: observed, severity: 9, severityText: "INFO", body: "entry"}),
+	}
+	b := appendExportRequest(nil, appendResource(nil, map[string]interface{}{"service.name": "api"}), appendScope(nil, "scope"), records)
+
+	var got collogspb.ExportLogsServiceRequest
+	if err := proto.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+				{Key: "service.name", Value: stringValue("api")},
+			}},
+			ScopeLogs: []*logspb.ScopeLogs{{
+				Scope: &commonpb.InstrumentationScope{Name: "scope"},
+				LogRecords: []*logspb.LogRecord{
+					{
+						TimeUnixNano:         uint64(at.UnixNano()),
+						ObservedTimeUnixNano: uint64(observed.UnixNano()),
+						SeverityNumber:       logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+						SeverityText:         "ERROR",
+						Body:                 stringValue("failed"),
+						Attributes: []*commonpb.KeyValue{
+							{Key: "bool", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: true}}},
+							{Key: "float", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: 1.5}}},
+							{Key: "int", Value: intValue(42)},
+							{Key: "list", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{
+								Values: []*commonpb.AnyValue{stringValue("a"), intValue(7)},
+							}}}},
+							{Key: "map", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{
+								Values: []*commonpb.KeyValue{{Key: "key", Value: stringValue("value")}},
+							}}}},
+							{Key: "nil", Value: &commonpb.AnyValue{}},
+							{Key: "number", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: 2.5}}},
+							{Key: "string", Value: stringValue("value")},
+						},
+						TraceId: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+						SpanId:  []byte{1, 2, 3, 4, 5, 6, 7, 8},
+					},
+					{
+						ObservedTimeUnixNano: uint64(observed.UnixNano()),
+						SeverityNumber:       logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+						SeverityText:         "INFO",
+						Body:                 stringValue("entry"),
+					},
+				},
+			}},
+		}},
+	}
+	if !proto.Equal(&got, want) {
+		t.Errorf("decoded\n%v\nwant\n%v", &got, want)
+	}
+}
+
+func TestPartialSuccessRoundTrip(t *testing.T) {
+	b, err := proto.Marshal(&collogspb.ExportLogsServiceResponse{
+		PartialSuccess: &collogspb.ExportLogsPartialSuccess{RejectedLogRecords: 3, ErrorMessage: "too large"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rejected, msg := partialSuccess(b); rejected != 3 || msg != "too large" {
+		t.Errorf("partialSuccess = %d, %q, want 3, %q", rejected, msg, "too large")
+	}
+}
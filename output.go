@@ -0,0 +1,166 @@
+package log
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// LeveledSink makes an extra sink, see Config.ExtraInfoSinks and
+// Config.ExtraErrorSinks, write the entries at level and above of its output
+// whatever the logger level
+func LeveledSink(sink zapcore.WriteSyncer, level Level) zapcore.WriteSyncer {
+	return &leveledSink{WriteSyncer: sink, level: level}
+}
+
+type leveledSink struct {
+	zapcore.WriteSyncer
+	level Level
+}
+
+// outputPart is the writers of an output sharing the encoding and the level
+type outputPart struct {
+	out     zapcore.WriteSyncer
+	console bool
+	// level of the part, nil follows the logger level
+	level *Level
+}
+
+// splitOutput is an output of which the parts are encoded or leveled
+// differently, see newOutputCore. Writes of encoded entries, like crash
+// dumps, go to every part.
+type splitOutput struct {
+	zapcore.WriteSyncer
+	parts []outputPart
+}
+
+// newOutput combines the writers and the consoles of an output, split in
+// parts when the console is encoded differently or when files, consoles or
+// sinks have their own level
+func newOutput(config Config, writers, consoles []zapcore.WriteSyncer) zapcore.WriteSyncer {
+	var parts []outputPart
+	add := func(w zapcore.WriteSyncer, console bool, level *Level) {
+		for i := range parts {
+			if parts[i].console == console && sameLevel(parts[i].level, level) {
+				parts[i].out = zapcore.NewMultiWriteSyncer(parts[i].out, w)
+				return
+			}
+		}
+		parts = append(parts, outputPart{out: w, console: console, level: level})
+	}
+	for _, w := range writers {
+		switch w := w.(type) {
+		case *leveledSink:
+			level := w.level
+			add(w.WriteSyncer, false, &level)
+		case rollingFile, *encryptedFile, *headerWriter, *coalescingFile:
+			add(w, false, config.FileLevel)
+		default:
+			add(w, false, nil)
+		}
+	}
+	for _, w := range consoles {
+		add(w, consoleDiffers(config), config.ConsoleLevel)
+	}
+
+	all := append(writers[:len(writers):len(writers)], consoles...)
+	if len(parts) == 0 || len(parts) == 1 && parts[0].level == nil && !parts[0].console {
+		return zapcore.NewMultiWriteSyncer(all...)
+	}
+	return &splitOutput{WriteSyncer: zapcore.NewMultiWriteSyncer(all...), parts: parts}
+}
+
+func sameLevel(a, b *Level) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
+
+// newOutputCore returns the core writing the output, a core per part when it
+// is split, and the lowest level of the parts having their own level, nil
+// if none has. The bytes counted for the stats are the ones of the first
+// part then, the others get the same entries.
+func newOutputCore(config Config, encoder zapcore.Encoder, output zapcore.WriteSyncer) (zapcore.Core, *Level) {
+	split, ok := output.(*splitOutput)
+	if !ok {
+		if config.stats != nil {
+			output = newCountingWriteSyncer(output, config.stats)
+		}
+		return newIOCore(encoder, output, allLevels, config.PipelineStats), nil
+	}
+
+	var consoleEncoder zapcore.Encoder
+	var floor *Level
+	cores := make([]zapcore.Core, 0, len(split.parts))
+	for i, part := range split.parts {
+		enc := encoder
+		if part.console {
+			if consoleEncoder == nil {
+				consoleConfig := config
+				if config.consoleColor && config.LevelEncoder == nil {
+					consoleConfig.LevelEncoder = zapcore.LowercaseColorLevelEncoder
+				}
+				consoleEncoder = newSinkEncoder(consoleConfig, config.ConsoleEncoding)
+			}
+			enc = consoleEncoder
+		}
+		out := part.out
+		if i == 0 && config.stats != nil {
+			out = newCountingWriteSyncer(out, config.stats)
+		}
+		if part.level != nil && (floor == nil || levelRank(*part.level) < levelRank(*floor)) {
+			floor = part.level
+		}
+		cores = append(cores, &partCore{Core: newIOCore(enc, out, allLevels, config.PipelineStats), level: part.level})
+	}
+	return zapcore.NewTee(cores...), floor
+}
+
+// belowLevel marks the entries below the logger level which levelCore passes
+// on for the parts having a lower level of their own
+type belowLevel struct{}
+
+var belowLevelField = zapcore.Field{Type: zapcore.SkipType, Interface: belowLevel{}}
+
+func hasBelowLevelMarker(fields []zapcore.Field) bool {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if _, ok := fields[i].Interface.(belowLevel); ok && fields[i].Type == zapcore.SkipType {
+			return true
+		}
+	}
+	return false
+}
+
+// partCore writes the entries of its level to a part of a split output, the
+// parts following the logger level skip the entries marked below it
+type partCore struct {
+	zapcore.Core
+	level *Level
+}
+
+func (c *partCore) Enabled(lvl zapcore.Level) bool {
+	return c.level == nil || lvl >= thresholdLevel(*c.level)
+}
+
+func (c *partCore) With(fields []zapcore.Field) zapcore.Core {
+	return &partCore{Core: c.Core.With(fields), level: c.level}
+}
+
+func (c *partCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *partCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if c.level == nil && hasBelowLevelMarker(fields) || c.level != nil && !levelAccepts(*c.level, ent, fields) {
+		return nil
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// levelAccepts reports whether the entry is at the level or above, notice
+// entries being info entries carrying the notice marker
+func levelAccepts(level Level, ent zapcore.Entry, fields []zapcore.Field) bool {
+	if level == NoticeLevel {
+		return ent.Level > InfoLevel || ent.Level == InfoLevel && hasNoticeMarker(fields)
+	}
+	return ent.Level >= level
+}
@@ -0,0 +1,142 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// loggedLevels returns the levels of the "entry" messages written to out
+func loggedLevels(out string) []string {
+	var levels []string
+	for _, line := range strings.Split(out, "\n") {
+		if i := strings.Index(line, "entry-"); i >= 0 {
+			levels = append(levels, strings.Fields(line[i+len("entry-"):])[0])
+		}
+	}
+	return levels
+}
+
+func levelPtr(l Level) *Level {
+	return &l
+}
+
+func TestPerOutputLevels(t *testing.T) {
+	tests := []struct {
+		name         string
+		level        Level
+		consoleLevel *Level
+		fileLevel    *Level
+		sinkLevel    *Level
+		console      []string
+		file         []string
+		sink         []string
+		plainSink    []string
+	}{
+		{
+			name:      "logger level only",
+			level:     InfoLevel,
+			console:   []string{"info", "notice", "warn", "error"},
+			file:      []string{"info", "notice", "warn", "error"},
+			sink:      []string{"info", "notice", "warn", "error"},
+			plainSink: []string{"info", "notice", "warn", "error"},
+		},
+		{
+			name:         "console below the logger level",
+			level:        WarnLevel,
+			consoleLevel: levelPtr(DebugLevel),
+			console:      []string{"debug", "info", "notice", "warn", "error"},
+			file:         []string{"warn", "error"},
+			sink:         []string{"warn", "error"},
+			plainSink:    []string{"warn", "error"},
+		},
+		{
+			name:         "console above the logger level",
+			level:        DebugLevel,
+			consoleLevel: levelPtr(ErrorLevel),
+			console:      []string{"error"},
+			file:         []string{"debug", "info", "notice", "warn", "error"},
+			sink:         []string{"debug", "info", "notice", "warn", "error"},
+			plainSink:    []string{"debug", "info", "notice", "warn", "error"},
+		},
+		{
+			name:      "file and sink levels",
+			level:     InfoLevel,
+			fileLevel: levelPtr(TraceLevel),
+			sinkLevel: levelPtr(NoticeLevel),
+			console:   []string{"info", "notice", "warn", "error"},
+			file:      []string{"trace", "debug", "info", "notice", "warn", "error"},
+			sink:      []string{"notice", "warn", "error"},
+			plainSink: []string{"info", "notice", "warn", "error"},
+		},
+		{
+			name:         "every output with its own level",
+			level:        ErrorLevel,
+			consoleLevel: levelPtr(InfoLevel),
+			fileLevel:    levelPtr(WarnLevel),
+			sinkLevel:    levelPtr(DebugLevel),
+			console:      []string{"info", "notice", "warn", "error"},
+			file:         []string{"warn", "error"},
+			sink:         []string{"debug", "info", "notice", "warn", "error"},
+			plainSink:    []string{"error"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var console, sink, plainSink bytes.Buffer
+			var sinkWriter zapcore.WriteSyncer = zapcore.AddSync(&sink)
+			if tt.sinkLevel != nil {
+				sinkWriter = LeveledSink(sinkWriter, *tt.sinkLevel)
+			}
+			plainWriter := zapcore.AddSync(&plainSink)
+			le := New(WithConfig(Config{
+				Level:              tt.level,
+				ConsoleLevel:       tt.consoleLevel,
+				FileLevel:          tt.fileLevel,
+				FileLoggingEnabled: true,
+				Directory:          dir,
+				Filename:           "app.log",
+				ExtraInfoSinks:     []zapcore.WriteSyncer{sinkWriter, plainWriter},
+				ExtraErrorSinks:    []zapcore.WriteSyncer{sinkWriter, plainWriter},
+			}), WithConsole(&console, &console))
+
+			le.Trace("entry-trace")
+			le.Debug("entry-debug")
+			le.Info("entry-info")
+			le.Notice("entry-notice")
+			le.Warn("entry-warn")
+			le.Error("entry-error")
+			le.Close()
+
+			var file string
+			for _, name := range []string{"app_info.log", "app_error.log"} {
+				data, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				file += string(data)
+			}
+			outputs := []struct {
+				name string
+				out  string
+				want []string
+			}{
+				{"console", console.String(), tt.console},
+				{"file", file, tt.file},
+				{"leveled sink", sink.String(), tt.sink},
+				{"sink", plainSink.String(), tt.plainSink},
+			}
+			for _, o := range outputs {
+				if got := loggedLevels(o.out); !reflect.DeepEqual(got, o.want) {
+					t.Errorf("%s got %v, want %v", o.name, got, o.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package log
+
+// longPath returns p unchanged, only Windows limits path lengths
+func longPath(p string) string {
+	return p
+}
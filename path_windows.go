@@ -0,0 +1,32 @@
+//go:build windows
+
+package log
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// maxPath is the length above which Windows APIs need the extended-length prefix
+const maxPath = 260
+
+// longPath adds the \\?\ prefix to paths that are too long for the classic
+// Windows APIs, using the \\?\UNC\ form for network shares. The prefix
+// takes absolute paths only, relative ones are made absolute first.
+func longPath(p string) string {
+	if len(p) < maxPath || strings.HasPrefix(p, `\\?\`) {
+		return p
+	}
+	if !filepath.IsAbs(p) {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return p
+		}
+		p = abs
+	}
+	p = filepath.Clean(p)
+	if strings.HasPrefix(p, `\\`) {
+		return `\\?\UNC\` + p[2:]
+	}
+	return `\\?\` + p
+}
@@ -0,0 +1,131 @@
+//go:build windows
+
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLongPath(t *testing.T) {
+	long := strings.Repeat(`segment\`, 40)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{
+			name: "short drive letter path",
+			path: `C:\logs\app.log`,
+			want: `C:\logs\app.log`,
+		},
+		{
+			name: "long drive letter path",
+			path: `C:\logs\` + long + `app.log`,
+			want: `\\?\C:\logs\` + long + `app.log`,
+		},
+		{
+			name: "long drive letter path with slashes",
+			path: `C:/logs/` + strings.ReplaceAll(long, `\`, `/`) + `app.log`,
+			want: `\\?\C:\logs\` + long + `app.log`,
+		},
+		{
+			name: "short relative path",
+			path: `logs\app.log`,
+			want: `logs\app.log`,
+		},
+		{
+			name: "long relative path",
+			path: `logs\` + long + `app.log`,
+			want: `\\?\` + filepath.Join(wd, `logs\`+long+`app.log`),
+		},
+		{
+			name: "short UNC path",
+			path: `\\server\share\logs\app.log`,
+			want: `\\server\share\logs\app.log`,
+		},
+		{
+			name: "long UNC path",
+			path: `\\server\share\logs\` + long + `app.log`,
+			want: `\\?\UNC\server\share\logs\` + long + `app.log`,
+		},
+		{
+			name: "prefixed path",
+			path: `\\?\C:\logs\` + long + `app.log`,
+			want: `\\?\C:\logs\` + long + `app.log`,
+		},
+		{
+			name: "prefixed UNC path",
+			path: `\\?\UNC\server\share\` + long + `app.log`,
+			want: `\\?\UNC\server\share\` + long + `app.log`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := longPath(tt.path); got != tt.want {
+				t.Errorf("longPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRollingFileLongPath(t *testing.T) {
+	tests := []struct {
+		name      string
+		directory func(tmp string) string
+		filename  string
+	}{
+		{
+			name:      "short directory",
+			directory: func(tmp string) string { return tmp },
+			filename:  "app.log",
+		},
+		{
+			name: "directory over the limit",
+			directory: func(tmp string) string {
+				return filepath.Join(tmp, strings.Repeat("d", 100), strings.Repeat("e", 100), strings.Repeat("f", 100))
+			},
+			filename: "app.log",
+		},
+		{
+			name:      "filename taking the path over the limit",
+			directory: func(tmp string) string { return tmp },
+			filename:  strings.Repeat("n", 250) + ".log",
+		},
+		{
+			name: "directory with slashes",
+			directory: func(tmp string) string {
+				return filepath.ToSlash(filepath.Join(tmp, strings.Repeat("s", 150), strings.Repeat("t", 150)))
+			},
+			filename: "app.log",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := tt.directory(t.TempDir())
+			w, err := newRollingFile(Config{Directory: dir, MaxSize: 1}, tt.filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Write([]byte("entry\n")); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.(rollingFile).Close(); err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(longPath(filepath.Join(dir, tt.filename)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != "entry\n" {
+				t.Errorf("file holds %q, want the entry", b)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package log
+
+import (
+	"expvar"
+	"math/bits"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// histogramBuckets is the number of power-of-two buckets, from 1µs up to ~1min
+const histogramBuckets = 27
+
+// histogram counts durations in power-of-two microsecond buckets
+type histogram struct {
+	buckets [histogramBuckets]atomic.Uint64
+	count   atomic.Uint64
+	sum     atomic.Int64
+	max     atomic.Int64
+}
+
+func (h *histogram) observe(d time.Duration) {
+	us := uint64(d / time.Microsecond)
+	i := 0
+	if us > 0 {
+		i = bits.Len64(us)
+	}
+	if i >= histogramBuckets {
+		i = histogramBuckets - 1
+	}
+	h.buckets[i].Add(1)
+	h.count.Add(1)
+	h.sum.Add(int64(d))
+	for {
+		max := h.max.Load()
+		if int64(d) <= max || h.max.CompareAndSwap(max, int64(d)) {
+			break
+		}
+	}
+}
+
+// HistogramBucket counts the observations up to UpperBound, not cumulative
+type HistogramBucket struct {
+	UpperBound time.Duration
+	Count      uint64
+}
+
+// Histogram is a snapshot of the latencies of one pipeline stage
+type Histogram struct {
+	Count   uint64
+	Sum     time.Duration
+	Max     time.Duration
+	Buckets []HistogramBucket
+}
+
+// Mean is the average latency
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile estimates the latency below which the fraction q of observations
+// fall, as the upper bound of the bucket holding it
+func (h Histogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := uint64(q * float64(h.Count))
+	var seen uint64
+	for _, b := range h.Buckets {
+		seen += b.Count
+		if seen > rank {
+			if b.UpperBound > h.Max {
+				return h.Max
+			}
+			return b.UpperBound
+		}
+	}
+	return h.Max
+}
+
+func (h *histogram) snapshot() Histogram {
+	s := Histogram{
+		Count:   h.count.Load(),
+		Sum:     time.Duration(h.sum.Load()),
+		Max:     time.Duration(h.max.Load()),
+		Buckets: make([]HistogramBucket, histogramBuckets),
+	}
+	for i := range h.buckets {
+		s.Buckets[i] = HistogramBucket{
+			UpperBound: time.Duration(uint64(1)<<i) * time.Microsecond,
+			Count:      h.buckets[i].Load(),
+		}
+	}
+	return s
+}
+
+// PipelineLatencies holds the latency histogram of each pipeline stage
+type PipelineLatencies struct {
+	// Encode is the time spent encoding an entry
+	Encode Histogram
+	// QueueWait is the time an entry waited in the queue of an asynchronous
+	// sink, see ObserveQueueWait
+	QueueWait Histogram
+	// Write is the time spent writing an encoded entry to the outputs
+	Write Histogram
+}
+
+var pipeline struct {
+	encode    histogram
+	queueWait histogram
+	write     histogram
+}
+
+// PipelineStats returns the latencies recorded by loggers with
+// Config.PipelineStats enabled, since the start of the process
+func PipelineStats() PipelineLatencies {
+	return PipelineLatencies{
+		Encode:    pipeline.encode.snapshot(),
+		QueueWait: pipeline.queueWait.snapshot(),
+		Write:     pipeline.write.snapshot(),
+	}
+}
+
+// ObserveQueueWait records the time an entry waited in the queue of an
+// asynchronous sink before it was written, for sinks queueing entries
+// themselves
+func ObserveQueueWait(d time.Duration) {
+	pipeline.queueWait.observe(d)
+}
+
+// PublishPipelineStats publishes PipelineStats as the expvar name, so the
+// latencies are served with the other metrics of /debug/vars. Like
+// expvar.Publish it panics when the name is already used.
+func PublishPipelineStats(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return PipelineStats()
+	}))
+}
+
+// newIOCore returns the core encoding entries to out, timing the encode and
+// write stages when timed is set
+func newIOCore(enc zapcore.Encoder, out zapcore.WriteSyncer, enab zapcore.LevelEnabler, timed bool) zapcore.Core {
+	c := &ioCore{LevelEnabler: enab, enc: enc, out: out, timed: timed}
+	c.root = c
+	return c
+}
+
+// ioCore mirrors zap's ioCore, observing the encode and write latencies when
+// timed and collecting the entries of LogBatch. root is the core the clones
+// are made from, identifying the output in a batch.
+type ioCore struct {
+	zapcore.LevelEnabler
+	enc   zapcore.Encoder
+	out   zapcore.WriteSyncer
+	timed bool
+	root  *ioCore
+}
+
+func (c *ioCore) Level() zapcore.Level {
+	return zapcore.LevelOf(c.LevelEnabler)
+}
+
+func (c *ioCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &ioCore{LevelEnabler: c.LevelEnabler, enc: c.enc.Clone(), out: c.out, timed: c.timed, root: c.root}
+	for _, f := range fields {
+		f.AddTo(clone.enc)
+	}
+	return clone
+}
+
+func (c *ioCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *ioCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var start time.Time
+	if c.timed {
+		start = time.Now()
+	}
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	var encoded time.Time
+	if c.timed {
+		encoded = time.Now()
+		pipeline.encode.observe(encoded.Sub(start))
+	}
+	if err != nil {
+		return err
+	}
+	if batch := batchOf(fields); batch != nil && batch.add(c.root, buf.Bytes()) {
+		buf.Free()
+		return nil
+	}
+	_, err = c.out.Write(buf.Bytes())
+	if c.timed {
+		pipeline.write.observe(time.Since(encoded))
+	}
+	buf.Free()
+	if err != nil {
+		return err
+	}
+	if ent.Level > ErrorLevel {
+		_ = c.Sync()
+	}
+	return nil
+}
+
+func (c *ioCore) Sync() error {
+	return c.out.Sync()
+}
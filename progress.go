@@ -0,0 +1,158 @@
+package log
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/term"
+)
+
+// ProgressKey is the field naming the operation of the entries of Progress
+const ProgressKey = "progress"
+
+// progressWidth is the number of cells of the progress bars
+const progressWidth = 20
+
+// status is the line showing the operations in progress at the bottom of
+// the terminal. The console writers of terminals clear it before writing an
+// entry and draw it again below.
+var status struct {
+	sync.Mutex
+	out   *os.File
+	keys  []string
+	lines map[string]string
+}
+
+// Progress reports the percent done of the operation key. When the info
+// console of the default logger is a terminal showing human entries it
+// updates a status line in place, one part per key, and otherwise logs an
+// entry at info level with the progress and percent fields, the caller
+// should then limit the rate. From 100 percent on the operation is done: it
+// is removed from the status line and logged as an entry in both cases.
+func Progress(key string, percent float64, fields Fields) {
+	l := Default()
+	if !l.Enabled(InfoLevel) {
+		return
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	if percent < 0 {
+		percent = 0
+	}
+	if tty := progressTerminal(defaults.Load().config); tty != nil && percent < 100 {
+		setStatus(tty, key, progressLine(key, percent, fields))
+		return
+	}
+	removeStatus(key)
+	l.infoLogger.Info(key, appendFields([]zapcore.Field{
+		zap.String(ProgressKey, key),
+		zap.Float64("percent", percent),
+	}, fields, l.sortFields)...)
+}
+
+// progressTerminal returns the terminal the info entries of the config are
+// written to in the console encoding, nil if there is none
+func progressTerminal(config Config) *os.File {
+	if sinkJSON(config, config.ConsoleEncoding) || config.FileLoggingEnabled && !config.ConsoleLoggingEnabled {
+		return nil
+	}
+	infoConsole, _ := consoleWriters(config)
+	if tty, ok := infoConsole.(ttyWriter); ok {
+		return tty.File
+	}
+	return nil
+}
+
+func progressLine(key string, percent float64, fields Fields) string {
+	done := int(percent / 100 * progressWidth)
+	var b strings.Builder
+	b.WriteString(key + " [" + strings.Repeat("#", done) + strings.Repeat(".", progressWidth-done) + "] ")
+	b.WriteString(strconv.FormatFloat(percent, 'f', 0, 64) + "%")
+	writeRecordFields(&b, fields)
+	return b.String()
+}
+
+func setStatus(out *os.File, key, line string) {
+	status.Lock()
+	defer status.Unlock()
+	clearStatus()
+	if status.lines == nil {
+		status.lines = map[string]string{}
+	}
+	if _, ok := status.lines[key]; !ok {
+		status.keys = append(status.keys, key)
+	}
+	status.lines[key] = line
+	status.out = out
+	drawStatus()
+}
+
+func removeStatus(key string) {
+	status.Lock()
+	defer status.Unlock()
+	if _, ok := status.lines[key]; !ok {
+		return
+	}
+	clearStatus()
+	delete(status.lines, key)
+	for i, k := range status.keys {
+		if k == key {
+			status.keys = append(status.keys[:i], status.keys[i+1:]...)
+			break
+		}
+	}
+	drawStatus()
+}
+
+// clearStatus erases the status line, status must be locked
+func clearStatus() {
+	if status.out != nil && len(status.keys) > 0 {
+		status.out.WriteString("\r\x1b[2K")
+	}
+}
+
+// drawStatus writes the status line without ending it, status must be locked
+func drawStatus() {
+	if status.out == nil || len(status.keys) == 0 {
+		return
+	}
+	parts := make([]string, len(status.keys))
+	for i, key := range status.keys {
+		parts[i] = status.lines[key]
+	}
+	status.out.WriteString(strings.Join(parts, " | "))
+}
+
+// ttyWriter is a console writer of a terminal, it keeps the status line of
+// Progress below the entries
+type ttyWriter struct {
+	*os.File
+}
+
+// consoleFile wraps f in a ttyWriter when it is a terminal
+func consoleFile(f *os.File) zapcore.WriteSyncer {
+	if isTerminal(f) {
+		return ttyWriter{f}
+	}
+	return f
+}
+
+// isTerminal reports whether f is a terminal, unlike character devices such
+// as /dev/null
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+func (w ttyWriter) Write(p []byte) (int, error) {
+	status.Lock()
+	defer status.Unlock()
+	clearStatus()
+	n, err := w.File.Write(p)
+	drawStatus()
+	return n, err
+}
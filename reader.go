@@ -0,0 +1,385 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Record is an entry read back from a JSON encoded log file
+type Record struct {
+	Time    time.Time
+	Level   Level
+	Logger  string
+	Caller  string
+	Message string
+	Stack   string
+	// Fields holds the remaining keys, numbers are json.Number
+	Fields map[string]interface{}
+	// Raw is the line the record was read from
+	Raw []byte
+}
+
+// ReadFilter selects the records returned by a Reader, the zero value
+// selects every record
+type ReadFilter struct {
+	// MinLevel skips the records below the level, notice ranking between
+	// info and warn
+	MinLevel *Level
+	// Since and Until bound the record times when set, Until excluded
+	Since time.Time
+	Until time.Time
+	// Fields requires the fields to have the values, compared as text. Keys
+	// are dotted paths into nested objects, e.g. "http.status".
+	Fields map[string]string
+	// Contains requires the message to contain the text
+	Contains string
+}
+
+// Reader reads the records of JSON encoded log files. Lines which are not
+// JSON objects are returned as info records with the line as message.
+type Reader struct {
+	scanner *bufio.Scanner
+	filter  ReadFilter
+}
+
+// maxRecordLine bounds the length of the lines a Reader accepts
+const maxRecordLine = 16 << 20
+
+// NewReader reads the records of r matching the filter
+func NewReader(r io.Reader, filter ReadFilter) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordLine)
+	return &Reader{scanner: scanner, filter: filter}
+}
+
+// Next returns the next matching record, io.EOF at the end of the input
+func (r *Reader) Next() (Record, error) {
+	for r.scanner.Scan() {
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		rec := ParseRecord(append([]byte(nil), line...))
+		if r.filter.Match(rec) {
+			return rec, nil
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
+
+// ParseRecord splits a JSON encoded entry into the entry metadata and the fields
+func ParseRecord(line []byte) Record {
+	rec := Record{Level: InfoLevel, Raw: line}
+	fields := map[string]interface{}{}
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if err := dec.Decode(&fields); err != nil {
+		rec.Message = string(line)
+		return rec
+	}
+	if t, err := ParseEntryTime(takeText(fields, TimeKey)); err == nil {
+		rec.Time = t
+	}
+	if level, err := ParseLevel(takeString(fields, LevelKey)); err == nil {
+		rec.Level = level
+	}
+	rec.Logger = takeString(fields, NameKey)
+	rec.Caller = takeString(fields, CallerKey)
+	rec.Message = takeString(fields, MessageKey)
+	rec.Stack = takeString(fields, StacktraceKey)
+	rec.Fields = fields
+	return rec
+}
+
+// takeText removes the key from fields returning its value as text
+func takeText(fields map[string]interface{}, key string) string {
+	v, ok := fields[key]
+	if !ok {
+		return ""
+	}
+	delete(fields, key)
+	return valueText(v)
+}
+
+// takeString removes the key from fields when it holds a string and returns
+// it, fields of the same name with other values are kept
+func takeString(fields map[string]interface{}, key string) string {
+	s, ok := fields[key].(string)
+	if ok {
+		delete(fields, key)
+	}
+	return s
+}
+
+// valueText returns strings as they are and other values as JSON
+func valueText(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case nil:
+		return ""
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// Match reports whether the record is selected by the filter
+func (f ReadFilter) Match(rec Record) bool {
+	if f.MinLevel != nil && levelRank(rec.Level) < levelRank(*f.MinLevel) {
+		return false
+	}
+	if !f.Since.IsZero() && rec.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !rec.Time.Before(f.Until) {
+		return false
+	}
+	if f.Contains != "" && !strings.Contains(rec.Message, f.Contains) {
+		return false
+	}
+	for path, want := range f.Fields {
+		v, ok := lookupPath(rec.Fields, path)
+		if !ok || valueText(v) != want {
+			return false
+		}
+	}
+	return true
+}
+
+// levelRank orders the levels with notice between info and warn
+func levelRank(l Level) int {
+	if l == NoticeLevel {
+		return int(InfoLevel)*2 + 1
+	}
+	return int(l) * 2
+}
+
+func lookupPath(fields map[string]interface{}, path string) (interface{}, bool) {
+	if v, ok := fields[path]; ok {
+		return v, true
+	}
+	var cur interface{} = fields
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = obj[key]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// OpenLogFiles opens the log file at path preceded by its rotated backups,
+// oldest first, decompressing the gzipped ones
+func OpenLogFiles(path string) (io.ReadCloser, error) {
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(path, ext) + "-"
+	backups, err := filepath.Glob(globEscape(prefix) + "*" + ext + "*")
+	if err != nil {
+		return nil, err
+	}
+	// the backup names end with their rotation time, which sorts as text
+	sort.Strings(backups)
+
+	files := &multiFile{}
+	var readers []io.Reader
+	for _, name := range append(backups, path) {
+		if name != path && !strings.HasSuffix(name, ext) && !strings.HasSuffix(name, ext+".gz") {
+			continue
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			if name == path && os.IsNotExist(err) && len(files.closers) > 0 {
+				break
+			}
+			files.Close()
+			return nil, err
+		}
+		files.closers = append(files.closers, f)
+		var r io.Reader = f
+		if strings.HasSuffix(name, ".gz") {
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				files.Close()
+				return nil, fmt.Errorf("log file %s: %w", name, err)
+			}
+			r = gz
+		}
+		readers = append(readers, r)
+	}
+	files.Reader = io.MultiReader(readers...)
+	return files, nil
+}
+
+func globEscape(s string) string {
+	return strings.NewReplacer(`*`, `\*`, `?`, `\?`, `[`, `\[`).Replace(s)
+}
+
+type multiFile struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (m *multiFile) Close() error {
+	var first error
+	for _, c := range m.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// TailFile calls fn with the matching records appended to the file at path
+// until ctx is done or fn returns an error. It starts at the end of the file
+// and follows it when it is rotated or truncated.
+func TailFile(ctx context.Context, path string, filter ReadFilter, fn func(Record) error) error {
+	const poll = 250 * time.Millisecond
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	var next *os.File
+	defer func() {
+		f.Close()
+		if next != nil {
+			next.Close()
+		}
+	}()
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	var partial []byte
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			offset += int64(n)
+			partial = append(partial, buf[:n]...)
+			for {
+				i := bytes.IndexByte(partial, '\n')
+				if i < 0 {
+					break
+				}
+				line := bytes.TrimSpace(partial[:i])
+				partial = partial[i+1:]
+				if len(line) == 0 {
+					continue
+				}
+				if rec := ParseRecord(append([]byte(nil), line...)); filter.Match(rec) {
+					if err := fn(rec); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if next != nil {
+			// the old file is drained, continue with the new one
+			f.Close()
+			f, next, offset, partial = next, nil, 0, nil
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(poll):
+		}
+
+		// reopen when the file was rotated away or truncated
+		current, statErr := os.Stat(path)
+		opened, _ := f.Stat()
+		if statErr != nil || opened == nil {
+			continue
+		}
+		if current.Size() < offset && os.SameFile(current, opened) {
+			if offset, err = f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			partial = nil
+		} else if !os.SameFile(current, opened) {
+			next, _ = os.Open(path)
+		}
+	}
+}
+
+// FormatRecord renders the record for humans: time, level, logger, caller
+// and message followed by the fields as key=value in key order and the stack
+func FormatRecord(rec Record) string {
+	var b strings.Builder
+	if !rec.Time.IsZero() {
+		b.WriteString(rec.Time.Format("2006-01-02 15:04:05.000"))
+		b.WriteByte(' ')
+	}
+	fmt.Fprintf(&b, "%-6s", strings.ToUpper(LevelName(rec.Level)))
+	if rec.Logger != "" {
+		b.WriteString(" [" + rec.Logger + "]")
+	}
+	if rec.Caller != "" {
+		b.WriteString(" " + rec.Caller)
+	}
+	b.WriteString(" " + rec.Message)
+	writeRecordFields(&b, rec.Fields)
+	if rec.Stack != "" {
+		b.WriteString("\n\t" + strings.ReplaceAll(rec.Stack, "\n", "\n\t"))
+	}
+	return b.String()
+}
+
+// writeRecordFields writes the fields as key=value in key order, each after a
+// space, quoting the strings which need it
+func writeRecordFields(b *strings.Builder, fields map[string]interface{}) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := valueText(fields[k])
+		if _, ok := fields[k].(string); ok && (v == "" || strings.ContainsAny(v, " \t\n\"=")) {
+			v = fmt.Sprintf("%q", v)
+		}
+		b.WriteString(" " + k + "=" + v)
+	}
+}
+
+// PrettyPrint writes the records of r matching the filter to w with
+// FormatRecord, one per line, for command line tools
+func PrettyPrint(w io.Writer, r io.Reader, filter ReadFilter) error {
+	reader := NewReader(r, filter)
+	for {
+		rec, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, FormatRecord(rec)+"\n"); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// PanicKey is the field name of the recovered panic value
+const PanicKey = "panic"
+
+// RecoverAndLog recovers a panic and logs it at error level with the
+// goroutine stack, using FromContext(ctx). It must be deferred directly:
+//
+//	defer log.RecoverAndLog(ctx)
+func RecoverAndLog(ctx context.Context) {
+	if v := recover(); v != nil {
+		FromContext(ctx).logRecovered(v, zap.Skip())
+	}
+}
+
+// RecoverAndRepanic logs a panic like RecoverAndLog and panics again with the
+// same value, for code which must still crash but wants the logs first
+func RecoverAndRepanic(ctx context.Context) {
+	if v := recover(); v != nil {
+		FromContext(ctx).logRecovered(v, crashField)
+		panic(v)
+	}
+}
+
+// Recover recovers a panic and logs it at error level with the goroutine
+// stack. It must be deferred directly: defer logger.Recover()
+func (le *LogEntry) Recover() {
+	if v := recover(); v != nil {
+		le.logRecovered(v, zap.Skip())
+	}
+}
+
+// RecoverAndRepanic logs a panic like Recover and panics again with the same value
+func (le *LogEntry) RecoverAndRepanic() {
+	if v := recover(); v != nil {
+		le.logRecovered(v, crashField)
+		panic(v)
+	}
+}
+
+// logRecovered writes the panic with the caller set to the panicking function.
+// marker is crashField when the panic continues, to dump the crash buffer.
+func (le *LogEntry) logRecovered(v interface{}, marker zapcore.Field) {
+	ce := le.errorLogger.Check(ErrorLevel, fmt.Sprintf("recovered panic: %v", v))
+	if ce == nil {
+		return
+	}
+	if caller, ok := panicCaller(); ok {
+		ce.Caller = caller
+	}
+	ce.Stack = string(debug.Stack())
+	field := zap.Any(PanicKey, v)
+	if err, ok := v.(error); ok {
+		field = zap.NamedError(PanicKey, err)
+	}
+	ce.Write(field, marker)
+}
+
+// panicCaller finds the frame which panicked, the first one outside the
+// runtime after runtime.gopanic
+func panicCaller() (zapcore.EntryCaller, bool) {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	panicking := false
+	for {
+		frame, more := frames.Next()
+		if frame.Function == "runtime.gopanic" {
+			panicking = true
+		} else if panicking && !strings.HasPrefix(frame.Function, "runtime.") {
+			return zapcore.EntryCaller{
+				Defined:  true,
+				PC:       frame.PC,
+				File:     frame.File,
+				Line:     frame.Line,
+				Function: frame.Function,
+			}, true
+		}
+		if !more {
+			return zapcore.EntryCaller{}, false
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package log
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// MessageRenderer rewrites the message of every entry before it is encoded,
+// e.g. to translate it for the locale of the deployment, keyed on the
+// message or on the event_id of Event. fields holds the fields of the logger
+// and of the entry, which are written unchanged. RenderMessage is called on
+// the logging goroutine for every entry, including the ones of this package.
+type MessageRenderer interface {
+	RenderMessage(entry zapcore.Entry, fields map[string]interface{}) string
+}
+
+// MessageRendererFunc adapts a function to MessageRenderer
+type MessageRendererFunc func(entry zapcore.Entry, fields map[string]interface{}) string
+
+func (f MessageRendererFunc) RenderMessage(entry zapcore.Entry, fields map[string]interface{}) string {
+	return f(entry, fields)
+}
+
+// renderCore replaces the messages with the ones of the renderer. It keeps
+// the With fields to hand them to the renderer.
+type renderCore struct {
+	zapcore.Core
+	renderer MessageRenderer
+	context  []zapcore.Field
+}
+
+func newRenderCore(core zapcore.Core, renderer MessageRenderer) zapcore.Core {
+	if renderer == nil {
+		return core
+	}
+	return &renderCore{Core: core, renderer: renderer}
+}
+
+func (c *renderCore) With(fields []zapcore.Field) zapcore.Core {
+	context := make([]zapcore.Field, 0, len(c.context)+len(fields))
+	context = append(context, c.context...)
+	return &renderCore{Core: c.Core.With(fields), renderer: c.renderer, context: append(context, fields...)}
+}
+
+func (c *renderCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *renderCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.context {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	ent.Message = c.renderer.RenderMessage(ent, enc.Fields)
+	return c.Core.Write(ent, fields)
+}
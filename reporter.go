@@ -0,0 +1,300 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrorReporter receives the entries at error level and above, e.g. to send
+// them to a crash reporting service. fields holds the fields of the logger and
+// of the entry; the entry always has a Stack, see ParseStack. Report is called
+// on the logging goroutine, slow reporters should queue the work.
+//
+// Reporters implementing zapcore.WriteSyncer's Sync are synced with the logger,
+// the ones implementing io.Closer are closed with it.
+type ErrorReporter interface {
+	Report(entry zapcore.Entry, fields map[string]interface{})
+}
+
+// notReported marks the entries which are written to the outputs but not
+// sent to the reporters, like the "logging configured" one
+type notReported struct{}
+
+var notReportedField = zapcore.Field{Type: zapcore.SkipType, Interface: notReported{}}
+
+func hasNotReportedMarker(fields []zapcore.Field) bool {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if _, ok := fields[i].Interface.(notReported); ok && fields[i].Type == zapcore.SkipType {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorReporterFunc adapts a function to ErrorReporter
+type ErrorReporterFunc func(entry zapcore.Entry, fields map[string]interface{})
+
+func (f ErrorReporterFunc) Report(entry zapcore.Entry, fields map[string]interface{}) {
+	f(entry, fields)
+}
+
+// StackFrame is one call of a stack trace
+type StackFrame struct {
+	// Module is the import path of the package, Function the name inside it
+	Module   string
+	Function string
+	File     string
+	Line     int
+}
+
+// ParseStack parses a stack trace as written by zap, pairs of "function" and
+// "\tfile:line" lines, innermost call first
+func ParseStack(stack string) []StackFrame {
+	lines := strings.Split(strings.TrimSpace(stack), "\n")
+	var frames []StackFrame
+	for i := 0; i+1 < len(lines); i += 2 {
+		location := strings.TrimSpace(lines[i+1])
+		frame := StackFrame{File: location}
+		if j := strings.LastIndexByte(location, ':'); j > 0 {
+			frame.File = location[:j]
+			frame.Line, _ = strconv.Atoi(location[j+1:])
+		}
+		frame.Module, frame.Function = splitFuncName(strings.TrimSpace(lines[i]))
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// splitFuncName splits "github.com/a/b.(*T).F" into "github.com/a/b" and "(*T).F"
+func splitFuncName(function string) (module, name string) {
+	start := strings.LastIndexByte(function, '/') + 1
+	if i := strings.IndexByte(function[start:], '.'); i >= 0 {
+		return function[:start+i], function[start+i+1:]
+	}
+	return "", function
+}
+
+// logPackage is the import path of this package, its frames are left out of
+// the captured stacks
+var logPackage = reflect.TypeOf(LogEntry{}).PkgPath()
+
+// captureStack returns the stack of the logging goroutine in zap's format,
+// without the frames of zap and this package
+func captureStack() string {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	var sb strings.Builder
+	for {
+		frame, more := frames.Next()
+		internal := strings.HasPrefix(frame.Function, "go.uber.org/zap") ||
+			strings.HasPrefix(frame.Function, logPackage+".")
+		if !internal && frame.Function != "" {
+			fmt.Fprintf(&sb, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			return strings.TrimSuffix(sb.String(), "\n")
+		}
+	}
+}
+
+// reporterCore hands the entries at error level and above to the reporters
+type reporterCore struct {
+	reporters []ErrorReporter
+	fields    []zapcore.Field
+}
+
+// newReporterCore returns the core of the configured reporters, nil without any
+func newReporterCore(config Config) *reporterCore {
+	reporters := append([]ErrorReporter(nil), config.ErrorReporters...)
+	if config.SentryDSN != "" {
+		// an invalid dsn is reported by Configure and NewLogEntry
+		if sentry, err := NewSentryReporter(config.SentryDSN, config.SentrySampleRate); err == nil {
+			reporters = append(reporters, sentry)
+		}
+	}
+	if len(reporters) == 0 {
+		return nil
+	}
+	return &reporterCore{reporters: reporters}
+}
+
+func (c *reporterCore) Enabled(lvl zapcore.Level) bool {
+	return lvl >= ErrorLevel
+}
+
+func (c *reporterCore) With(fields []zapcore.Field) zapcore.Core {
+	return &reporterCore{
+		reporters: c.reporters,
+		fields:    append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+}
+
+func (c *reporterCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *reporterCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if hasNotReportedMarker(fields) {
+		return nil
+	}
+	if ent.Stack == "" {
+		ent.Stack = captureStack()
+	}
+	for _, r := range c.reporters {
+		// each reporter gets its own map, they may keep or change it
+		enc := zapcore.NewMapObjectEncoder()
+		for _, f := range c.fields {
+			f.AddTo(enc)
+		}
+		for _, f := range fields {
+			f.AddTo(enc)
+		}
+		r.Report(ent, enc.Fields)
+	}
+	return nil
+}
+
+func (c *reporterCore) Sync() error {
+	var firstErr error
+	for _, r := range c.reporters {
+		if s, ok := r.(interface{ Sync() error }); ok {
+			if err := s.Sync(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// Close closes the reporters, once LogEntry.Close synced them
+func (c *reporterCore) Close() error {
+	var err error
+	for _, r := range c.reporters {
+		if closer, ok := r.(io.Closer); ok {
+			err = multierr.Append(err, closer.Close())
+		}
+	}
+	return err
+}
+
+// reportQueue posts the payloads of a reporter in the background. Payloads
+// beyond the queue size or posted after Close are dropped, Sync waits for the
+// queue to drain.
+type reportQueue struct {
+	client *http.Client
+	queue  chan *http.Request
+
+	mu sync.Mutex
+	// pending counts the queued and sending payloads, idle is closed when it
+	// drops to zero
+	pending int
+	idle    chan struct{}
+	closed  bool
+}
+
+// reportQueueSize bounds the payloads waiting to be sent
+const reportQueueSize = 100
+
+// reportFlushTimeout bounds how long Sync waits for queued payloads
+const reportFlushTimeout = 5 * time.Second
+
+func newReportQueue() *reportQueue {
+	q := &reportQueue{
+		client: &http.Client{Timeout: 10 * time.Second},
+		queue:  make(chan *http.Request, reportQueueSize),
+	}
+	go q.run()
+	return q
+}
+
+// post queues a POST of the body with the headers
+func (q *reportQueue) post(url string, body []byte, headers map[string]string) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	select {
+	case q.queue <- req:
+		if q.pending == 0 {
+			q.idle = make(chan struct{})
+		}
+		q.pending++
+	default:
+	}
+}
+
+func (q *reportQueue) run() {
+	for req := range q.queue {
+		if resp, err := q.client.Do(req); err == nil {
+			resp.Body.Close()
+		}
+		q.mu.Lock()
+		q.pending--
+		if q.pending == 0 {
+			close(q.idle)
+		}
+		q.mu.Unlock()
+	}
+}
+
+// wait waits for the queued payloads to be sent or for ctx to be done
+func (q *reportQueue) wait(ctx context.Context) error {
+	q.mu.Lock()
+	if q.pending == 0 {
+		q.mu.Unlock()
+		return nil
+	}
+	idle := q.idle
+	q.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sync waits for the queued payloads to be sent
+func (q *reportQueue) Sync() error {
+	ctx, cancel := context.WithTimeout(context.Background(), reportFlushTimeout)
+	defer cancel()
+	if err := q.wait(ctx); err != nil {
+		return fmt.Errorf("error reporter flush timed out after %s", reportFlushTimeout)
+	}
+	return nil
+}
+
+// Close stops the queue, the payloads already queued are still sent
+func (q *reportQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		close(q.queue)
+	}
+	return nil
+}
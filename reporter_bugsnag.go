@@ -0,0 +1,83 @@
+package log
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// bugsnagEndpoint is the event API of Bugsnag
+const bugsnagEndpoint = "https://notify.bugsnag.com/"
+
+// BugsnagReporter sends entries to Bugsnag as handled events with their fields and stack
+type BugsnagReporter struct {
+	*reportQueue
+	apiKey       string
+	releaseStage string
+	hostname     string
+	endpoint     string
+}
+
+// NewBugsnagReporter returns a reporter for the project of the api key
+func NewBugsnagReporter(apiKey, releaseStage string) *BugsnagReporter {
+	hostname, _ := os.Hostname()
+	return &BugsnagReporter{
+		reportQueue:  newReportQueue(),
+		apiKey:       apiKey,
+		releaseStage: releaseStage,
+		hostname:     hostname,
+		endpoint:     bugsnagEndpoint,
+	}
+}
+
+func (r *BugsnagReporter) Report(ent zapcore.Entry, fields map[string]interface{}) {
+	stacktrace := []map[string]interface{}{}
+	for _, f := range ParseStack(ent.Stack) {
+		stacktrace = append(stacktrace, map[string]interface{}{
+			"file":       f.File,
+			"lineNumber": f.Line,
+			"method":     f.Module + "." + f.Function,
+			"inProject":  !isStdlib(f.Module),
+		})
+	}
+	event := map[string]interface{}{
+		"exceptions": []interface{}{map[string]interface{}{
+			"errorClass": ent.Message,
+			"message":    ent.Message,
+			"stacktrace": stacktrace,
+		}},
+		"severity":  "error",
+		"unhandled": ent.Level > ErrorLevel,
+		"app":       map[string]string{"releaseStage": r.releaseStage},
+		"device":    map[string]string{"hostname": r.hostname},
+		"metaData":  map[string]interface{}{"fields": fields},
+	}
+	if ent.LoggerName != "" {
+		event["context"] = ent.LoggerName
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"apiKey":         r.apiKey,
+		"payloadVersion": "5",
+		"notifier":       map[string]string{"name": "olee12-log", "version": "1.0", "url": "https://github.com/olee12/log"},
+		"events":         []interface{}{event},
+	})
+	if err != nil {
+		return
+	}
+	r.post(r.endpoint, body, map[string]string{
+		"Content-Type":            "application/json",
+		"Bugsnag-Api-Key":         r.apiKey,
+		"Bugsnag-Payload-Version": "5",
+		"Bugsnag-Sent-At":         time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// isStdlib reports whether the package is part of the standard library, whose
+// import paths have no dot in their first element
+func isStdlib(module string) bool {
+	first, _, _ := strings.Cut(module, "/")
+	return !strings.Contains(first, ".")
+}
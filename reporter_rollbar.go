@@ -0,0 +1,76 @@
+package log
+
+import (
+	"encoding/json"
+	"os"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// rollbarEndpoint is the item API of Rollbar
+const rollbarEndpoint = "https://api.rollbar.com/api/1/item/"
+
+// RollbarReporter sends entries to Rollbar as items with their fields and stack
+type RollbarReporter struct {
+	*reportQueue
+	token       string
+	environment string
+	hostname    string
+	endpoint    string
+}
+
+// NewRollbarReporter returns a reporter using a post_server_item access token
+func NewRollbarReporter(token, environment string) *RollbarReporter {
+	hostname, _ := os.Hostname()
+	return &RollbarReporter{
+		reportQueue: newReportQueue(),
+		token:       token,
+		environment: environment,
+		hostname:    hostname,
+		endpoint:    rollbarEndpoint,
+	}
+}
+
+func (r *RollbarReporter) Report(ent zapcore.Entry, fields map[string]interface{}) {
+	level := "error"
+	if ent.Level > ErrorLevel {
+		level = "critical"
+	}
+	frames := []map[string]interface{}{}
+	stack := ParseStack(ent.Stack)
+	// rollbar lists the innermost frame last
+	for i := len(stack) - 1; i >= 0; i-- {
+		f := stack[i]
+		frames = append(frames, map[string]interface{}{
+			"filename": f.File,
+			"lineno":   f.Line,
+			"method":   f.Module + "." + f.Function,
+		})
+	}
+	data := map[string]interface{}{
+		"environment": r.environment,
+		"level":       level,
+		"timestamp":   ent.Time.Unix(),
+		"platform":    "go",
+		"language":    "go",
+		"title":       ent.Message,
+		"uuid":        UUIDGenerator{}.NewID(),
+		"custom":      fields,
+		"server":      map[string]string{"host": r.hostname},
+		"notifier":    map[string]string{"name": "olee12-log", "version": "1.0"},
+		"body": map[string]interface{}{
+			"trace": map[string]interface{}{
+				"frames":    frames,
+				"exception": map[string]string{"class": ent.Message, "message": ent.Message},
+			},
+		},
+	}
+	body, err := json.Marshal(map[string]interface{}{"data": data})
+	if err != nil {
+		return
+	}
+	r.post(r.endpoint, body, map[string]string{
+		"Content-Type":           "application/json",
+		"X-Rollbar-Access-Token": r.token,
+	})
+}
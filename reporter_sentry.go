@@ -0,0 +1,154 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// sentryTagKeys are the fields sent as Sentry tags, the others are sent as extra data
+var sentryTagKeys = []string{RequestIDKey, TraceIDKey, TenantIDKey, TTLClassKey}
+
+// sentryDSN is the parsed Config.SentryDSN
+type sentryDSN struct {
+	raw       string
+	publicKey string
+	endpoint  string
+}
+
+// parseSentryDSN parses "https://<key>@<host>[/<path>]/<project>"
+func parseSentryDSN(dsn string) (*sentryDSN, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("sentry dsn: %w", err)
+	}
+	if u.User == nil || u.User.Username() == "" || u.Host == "" {
+		return nil, fmt.Errorf("sentry dsn %q has no public key or host", sinkName(dsn))
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	i := strings.LastIndexByte(path, '/')
+	project := path[i+1:]
+	if project == "" {
+		return nil, fmt.Errorf("sentry dsn %q has no project", sinkName(dsn))
+	}
+	return &sentryDSN{
+		raw:       dsn,
+		publicKey: u.User.Username(),
+		endpoint:  fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, path[:i], project),
+	}, nil
+}
+
+// SentryReporter sends entries to Sentry as events with their fields and stack
+type SentryReporter struct {
+	*reportQueue
+	dsn        *sentryDSN
+	sampleRate float64
+	hostname   string
+}
+
+// NewSentryReporter returns a reporter for the project of the dsn sending the
+// sampleRate fraction of the entries, all of them when sampleRate is 0
+func NewSentryReporter(dsn string, sampleRate float64) (*SentryReporter, error) {
+	parsed, err := parseSentryDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if sampleRate <= 0 || sampleRate > 1 {
+		sampleRate = 1
+	}
+	hostname, _ := os.Hostname()
+	return &SentryReporter{reportQueue: newReportQueue(), dsn: parsed, sampleRate: sampleRate, hostname: hostname}, nil
+}
+
+func (r *SentryReporter) Report(ent zapcore.Entry, fields map[string]interface{}) {
+	if r.sampleRate < 1 && rand.Float64() >= r.sampleRate {
+		return
+	}
+	envelope, err := r.envelope(ent, fields)
+	if err != nil {
+		return
+	}
+	r.post(r.dsn.endpoint, envelope, map[string]string{
+		"Content-Type":  "application/x-sentry-envelope",
+		"X-Sentry-Auth": "Sentry sentry_version=7, sentry_client=olee12-log/1.0, sentry_key=" + r.dsn.publicKey,
+	})
+}
+
+// envelope encodes the entry as a Sentry envelope holding one event
+func (r *SentryReporter) envelope(ent zapcore.Entry, fields map[string]interface{}) ([]byte, error) {
+	eventID := strings.ReplaceAll(UUIDGenerator{}.NewID(), "-", "")
+
+	tags := map[string]string{}
+	if ent.LoggerName != "" {
+		tags["logger"] = ent.LoggerName
+	}
+	for _, key := range sentryTagKeys {
+		if v, ok := fields[key]; ok {
+			tags[key] = fmt.Sprint(v)
+			delete(fields, key)
+		}
+	}
+	level := LevelName(ent.Level)
+	if ent.Level > ErrorLevel {
+		level = "fatal"
+	}
+	event := map[string]interface{}{
+		"event_id":    eventID,
+		"timestamp":   ent.Time.UTC().Format(time.RFC3339Nano),
+		"level":       level,
+		"platform":    "go",
+		"logger":      ent.LoggerName,
+		"server_name": r.hostname,
+		"message":     map[string]string{"formatted": ent.Message},
+		"tags":        tags,
+		"extra":       fields,
+	}
+	if ent.Caller.Defined {
+		event["culprit"] = ent.Caller.TrimmedPath()
+	}
+	if stack := ParseStack(ent.Stack); len(stack) > 0 {
+		// sentry lists the innermost frame last
+		frames := make([]map[string]interface{}, len(stack))
+		for i, f := range stack {
+			frames[len(stack)-1-i] = map[string]interface{}{
+				"function": f.Function,
+				"module":   f.Module,
+				"abs_path": f.File,
+				"lineno":   f.Line,
+			}
+		}
+		event["exception"] = map[string]interface{}{
+			"values": []interface{}{map[string]interface{}{
+				"type":       ent.Message,
+				"value":      ent.Message,
+				"stacktrace": map[string]interface{}{"frames": frames},
+			}},
+		}
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	header, _ := json.Marshal(map[string]string{
+		"event_id": eventID,
+		"dsn":      r.dsn.raw,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339Nano),
+	})
+	buf.Write(header)
+	buf.WriteString("\n")
+	itemHeader, _ := json.Marshal(map[string]interface{}{"type": "event", "length": len(body)})
+	buf.Write(itemHeader)
+	buf.WriteString("\n")
+	buf.Write(body)
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,36 @@
+package log
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStartupEntryNotReported(t *testing.T) {
+	console, err := os.CreateTemp(t.TempDir(), "console")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer console.Close()
+	reporter := &levelReporter{}
+	le := NewLogEntry(Config{
+		Level:              InfoLevel,
+		ConsoleInfoStream:  console,
+		ConsoleErrorStream: console,
+		ErrorReporters:     []ErrorReporter{reporter},
+	})
+	le.Error("failed")
+	if got, want := reporter.reported(), []string{"error"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("reported %v, want %v", got, want)
+	}
+
+	out, err := os.ReadFile(console.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the error output gets the startup entry too
+	if n := strings.Count(string(out), ConfiguredMessage); n != 2 {
+		t.Errorf("%d startup entries written, want 2:\n%s", n, out)
+	}
+}
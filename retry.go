@@ -0,0 +1,156 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrCircuitOpen is returned by the writes of a RetrySink while its circuit
+// breaker is open
+var ErrCircuitOpen = errors.New("log: sink circuit breaker open")
+
+// RetryPolicy configures RetrySink
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts of a write, 3 by default
+	MaxAttempts int
+	// InitialBackoff is the wait after the first failed attempt, 100ms by
+	// default. It doubles after each further one up to MaxBackoff.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between attempts, 5s by default
+	MaxBackoff time.Duration
+	// Jitter randomizes each wait by up to this fraction of it, in both
+	// directions, so clients do not retry in lockstep. 0.2 by default, negative
+	// disables it.
+	Jitter float64
+	// BreakerThreshold is the number of writes failing in a row which opens
+	// the circuit breaker, 0 disables it. While open, writes fail right away
+	// with ErrCircuitOpen.
+	BreakerThreshold int
+	// BreakerCooldown is the time the breaker stays open before one write is
+	// tried again, 30s by default
+	BreakerCooldown time.Duration
+	// OnFailure is called when a write fails after its attempts, with the
+	// error of the last one
+	OnFailure func(err error, attempts int)
+	// OnBreaker is called when the circuit breaker opens or closes
+	OnBreaker func(open bool)
+}
+
+// retrySink retries the failed writes of a sink, see RetrySink
+type retrySink struct {
+	zapcore.WriteSyncer
+	policy RetryPolicy
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	// probing is set while the write after the cooldown is tried
+	probing bool
+}
+
+// RetrySink wraps the sink to retry its failed writes with an exponential,
+// jittered backoff and to stop writing to it for a while after repeated
+// failures. Writes rejected with a RejectionError are not retried. The
+// retries block the writing goroutine, WithBackpressure or WithDiskQueue on
+// top keep them off the logging goroutines.
+func RetrySink(sink zapcore.WriteSyncer, policy RetryPolicy) zapcore.WriteSyncer {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 3
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = 100 * time.Millisecond
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = 5 * time.Second
+	}
+	if policy.Jitter == 0 {
+		policy.Jitter = 0.2
+	}
+	if policy.BreakerCooldown <= 0 {
+		policy.BreakerCooldown = 30 * time.Second
+	}
+	return &retrySink{WriteSyncer: sink, policy: policy}
+}
+
+func (s *retrySink) Write(p []byte) (int, error) {
+	if !s.allow() {
+		return 0, ErrCircuitOpen
+	}
+	var n int
+	var err error
+	attempts := 0
+	backoff := s.policy.InitialBackoff
+	for {
+		attempts++
+		n, err = s.WriteSyncer.Write(p)
+		if _, rejected := IsRejection(err); err == nil || rejected || attempts == s.policy.MaxAttempts {
+			break
+		}
+		time.Sleep(s.jittered(backoff))
+		backoff = min(2*backoff, s.policy.MaxBackoff)
+	}
+	s.record(err)
+	if err != nil && s.policy.OnFailure != nil {
+		s.policy.OnFailure(err, attempts)
+	}
+	return n, err
+}
+
+// jittered spreads d by up to Jitter of it
+func (s *retrySink) jittered(d time.Duration) time.Duration {
+	if s.policy.Jitter < 0 {
+		return d
+	}
+	return d + time.Duration((rand.Float64()*2-1)*s.policy.Jitter*float64(d))
+}
+
+// allow reports whether a write may go to the sink, letting one through at
+// the end of the cooldown of an open breaker
+func (s *retrySink) allow() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.openUntil.IsZero() {
+		return true
+	}
+	if s.probing || time.Now().Before(s.openUntil) {
+		return false
+	}
+	s.probing = true
+	return true
+}
+
+// record counts the failures in a row, opening and closing the breaker
+func (s *retrySink) record(err error) {
+	s.mu.Lock()
+	var changed, open bool
+	if _, rejected := IsRejection(err); err == nil || rejected {
+		s.failures = 0
+		changed = !s.openUntil.IsZero()
+		s.openUntil = time.Time{}
+	} else {
+		s.failures++
+		if s.policy.BreakerThreshold > 0 && (s.probing || s.failures >= s.policy.BreakerThreshold) {
+			changed = s.openUntil.IsZero()
+			open = true
+			s.openUntil = time.Now().Add(s.policy.BreakerCooldown)
+		}
+	}
+	s.probing = false
+	s.mu.Unlock()
+	if changed && s.policy.OnBreaker != nil {
+		s.policy.OnBreaker(open)
+	}
+}
+
+// Close closes the sink if it is an io.Closer
+func (s *retrySink) Close() error {
+	if c, ok := s.WriteSyncer.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
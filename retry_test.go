@@ -0,0 +1,157 @@
+package log
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var errSinkDown = errors.New("sink down")
+
+// scriptedSink returns the queued results, one per write, and nil after them
+type scriptedSink struct {
+	results []error
+	writes  int
+}
+
+func (s *scriptedSink) Write(p []byte) (int, error) {
+	s.writes++
+	if len(s.results) == 0 {
+		return len(p), nil
+	}
+	err := s.results[0]
+	s.results = s.results[1:]
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (s *scriptedSink) Sync() error {
+	return nil
+}
+
+// retryStep is a write to a RetrySink
+type retryStep struct {
+	// cooldown waits for the end of the cooldown of the breaker first
+	cooldown bool
+	// results are the results of the attempts of the write
+	results []error
+	wantErr error
+	// wantWrites is the number of attempts reaching the sink
+	wantWrites int
+}
+
+func TestRetrySinkStates(t *testing.T) {
+	rejected := NewRejectionError("bad record")
+	tests := []struct {
+		name  string
+		steps []retryStep
+		// wantBreaker are the changes of the breaker, true when it opens
+		wantBreaker []bool
+		// wantFailures are the attempts of the writes which failed
+		wantFailures []int
+	}{
+		{
+			name:  "first attempt succeeds",
+			steps: []retryStep{{wantWrites: 1}},
+		},
+		{
+			name:  "retried until success",
+			steps: []retryStep{{results: []error{errSinkDown, nil}, wantWrites: 2}},
+		},
+		{
+			name:         "attempts exhausted",
+			steps:        []retryStep{{results: []error{errSinkDown, errSinkDown}, wantErr: errSinkDown, wantWrites: 2}},
+			wantFailures: []int{2},
+		},
+		{
+			name: "rejections are not retried and keep the breaker closed",
+			steps: []retryStep{
+				{results: []error{rejected}, wantErr: rejected, wantWrites: 1},
+				{results: []error{rejected}, wantErr: rejected, wantWrites: 1},
+				{results: []error{rejected}, wantErr: rejected, wantWrites: 1},
+			},
+			wantFailures: []int{1, 1, 1},
+		},
+		{
+			name: "success resets the failures in a row",
+			steps: []retryStep{
+				{results: []error{errSinkDown, errSinkDown}, wantErr: errSinkDown, wantWrites: 2},
+				{wantWrites: 1},
+				{results: []error{errSinkDown, errSinkDown}, wantErr: errSinkDown, wantWrites: 2},
+				{wantWrites: 1},
+			},
+			wantFailures: []int{2, 2},
+		},
+		{
+			name: "breaker opens at the threshold",
+			steps: []retryStep{
+				{results: []error{errSinkDown, errSinkDown}, wantErr: errSinkDown, wantWrites: 2},
+				{results: []error{errSinkDown, errSinkDown}, wantErr: errSinkDown, wantWrites: 2},
+				{wantErr: ErrCircuitOpen},
+			},
+			wantBreaker:  []bool{true},
+			wantFailures: []int{2, 2},
+		},
+		{
+			name: "failed probe keeps the breaker open",
+			steps: []retryStep{
+				{results: []error{errSinkDown, errSinkDown}, wantErr: errSinkDown, wantWrites: 2},
+				{results: []error{errSinkDown, errSinkDown}, wantErr: errSinkDown, wantWrites: 2},
+				{cooldown: true, results: []error{errSinkDown, errSinkDown}, wantErr: errSinkDown, wantWrites: 2},
+				{wantErr: ErrCircuitOpen},
+			},
+			wantBreaker:  []bool{true},
+			wantFailures: []int{2, 2, 2},
+		},
+		{
+			name: "successful probe closes the breaker",
+			steps: []retryStep{
+				{results: []error{errSinkDown, errSinkDown}, wantErr: errSinkDown, wantWrites: 2},
+				{results: []error{errSinkDown, errSinkDown}, wantErr: errSinkDown, wantWrites: 2},
+				{cooldown: true, wantWrites: 1},
+				{wantWrites: 1},
+			},
+			wantBreaker:  []bool{true, false},
+			wantFailures: []int{2, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const cooldown = 20 * time.Millisecond
+			var breaker []bool
+			var failures []int
+			sink := &scriptedSink{}
+			retry := RetrySink(sink, RetryPolicy{
+				MaxAttempts:      2,
+				InitialBackoff:   time.Millisecond,
+				Jitter:           -1,
+				BreakerThreshold: 2,
+				BreakerCooldown:  cooldown,
+				OnFailure:        func(_ error, attempts int) { failures = append(failures, attempts) },
+				OnBreaker:        func(open bool) { breaker = append(breaker, open) },
+			})
+			for i, step := range tt.steps {
+				if step.cooldown {
+					time.Sleep(2 * cooldown)
+				}
+				sink.results, sink.writes = step.results, 0
+				_, err := retry.Write([]byte("entry"))
+				if !errors.Is(err, step.wantErr) {
+					t.Errorf("step %d: error %v, want %v", i, err, step.wantErr)
+				}
+				if sink.writes != step.wantWrites {
+					t.Errorf("step %d: %d writes to the sink, want %d", i, sink.writes, step.wantWrites)
+				}
+			}
+			if !reflect.DeepEqual(breaker, tt.wantBreaker) {
+				t.Errorf("breaker changes %v, want %v", breaker, tt.wantBreaker)
+			}
+			if !reflect.DeepEqual(failures, tt.wantFailures) {
+				t.Errorf("failed attempts %v, want %v", failures, tt.wantFailures)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package log
+
+import (
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// SamplingConfig caps the volume of repeated entries. Within each Tick the
+// first Initial entries with the same level and message are logged, after that
+// only every Thereafter-th one is.
+type SamplingConfig struct {
+	Tick       time.Duration
+	Initial    int
+	Thereafter int
+}
+
+// samplingCore samples entries unless the logger carries the sampled trace
+// marker, in which case every entry is written so the trace has complete logs
+type samplingCore struct {
+	zapcore.Core
+	sampled zapcore.Core
+	bypass  bool
+}
+
+func newSamplingCore(core zapcore.Core, config *SamplingConfig, stats *loggerStats) zapcore.Core {
+	if config == nil {
+		return core
+	}
+	tick := config.Tick
+	if tick <= 0 {
+		tick = time.Second
+	}
+	sampled := zapcore.NewSamplerWithOptions(core, tick, config.Initial, config.Thereafter,
+		zapcore.SamplerHook(stats.samplerHook))
+	return &samplingCore{Core: core, sampled: sampled}
+}
+
+func (c *samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplingCore{
+		Core:    c.Core.With(fields),
+		sampled: c.sampled.With(fields),
+		bypass:  c.bypass || hasSampledTrace(fields),
+	}
+}
+
+func (c *samplingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.bypass {
+		return c.Core.Check(ent, ce)
+	}
+	return c.sampled.Check(ent, ce)
+}
+
+func hasSampledTrace(fields []zapcore.Field) bool {
+	for _, f := range fields {
+		if f.Key == SampledKey && f.Type == zapcore.BoolType && f.Integer == 1 {
+			return true
+		}
+	}
+	return false
+}
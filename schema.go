@@ -0,0 +1,195 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// FieldKind is the kind of value a schema expects for a key
+type FieldKind string
+
+const (
+	StringKind   FieldKind = "string"
+	NumberKind   FieldKind = "number"
+	BoolKind     FieldKind = "bool"
+	TimeKind     FieldKind = "time"
+	DurationKind FieldKind = "duration"
+	ErrorKind    FieldKind = "error"
+	ObjectKind   FieldKind = "object"
+	ArrayKind    FieldKind = "array"
+	// AnyKind is reported for values of no specific kind, such as reflected ones,
+	// and matches every constraint
+	AnyKind FieldKind = "any"
+)
+
+// SchemaConfig describes the fields entries must carry. The fields of the
+// logger, the global fields and the fields of the entry are all checked.
+type SchemaConfig struct {
+	// Required keys every entry must carry, e.g. request_id or tenant
+	Required []string
+	// Types constrains the kind of the value of keys when they are present
+	Types map[string]FieldKind
+	// Strict panics with the *SchemaViolation after writing a violating entry,
+	// meant for development and tests. Otherwise violations are only counted,
+	// see SchemaViolations.
+	Strict bool
+	// OnViolation is called with every violation, e.g. to feed a metric
+	OnViolation func(SchemaViolation)
+}
+
+// SchemaViolation describes an entry not matching the schema
+type SchemaViolation struct {
+	Message string
+	// Missing lists the required keys the entry lacks
+	Missing []string
+	// Mismatched lists the keys carrying a value of the wrong kind as
+	// "key: want kind, got kind"
+	Mismatched []string
+}
+
+func (v *SchemaViolation) Error() string {
+	var problems []string
+	if len(v.Missing) > 0 {
+		problems = append(problems, "missing "+strings.Join(v.Missing, ", "))
+	}
+	problems = append(problems, v.Mismatched...)
+	return fmt.Sprintf("log entry %q violates schema: %s", v.Message, strings.Join(problems, "; "))
+}
+
+var schemaViolations atomic.Int64
+
+// schemaExempt marks the entries the package logs about itself, such as the
+// configuration announced by DeclareLogger and LogStartup, which are not checked
+type schemaExempt struct{}
+
+var schemaExemptField = zapcore.Field{Type: zapcore.SkipType, Interface: schemaExempt{}}
+
+// SchemaViolations returns the number of entries which violated the schema of
+// their logger since the start of the process
+func SchemaViolations() int64 {
+	return schemaViolations.Load()
+}
+
+// schemaCore validates the entries against the schema before writing them.
+// It keeps the fields added with With as the cores below encode them eagerly.
+type schemaCore struct {
+	zapcore.Core
+	schema  *SchemaConfig
+	context []zapcore.Field
+}
+
+func newSchemaCore(core zapcore.Core, schema *SchemaConfig) zapcore.Core {
+	if schema == nil {
+		return core
+	}
+	return &schemaCore{Core: core, schema: schema}
+}
+
+func (c *schemaCore) With(fields []zapcore.Field) zapcore.Core {
+	context := make([]zapcore.Field, 0, len(c.context)+len(fields))
+	context = append(context, c.context...)
+	return &schemaCore{Core: c.Core.With(fields), schema: c.schema, context: append(context, fields...)}
+}
+
+func (c *schemaCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *schemaCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	v := c.validate(ent, fields)
+	if v == nil {
+		return c.Core.Write(ent, fields)
+	}
+	schemaViolations.Add(1)
+	if c.schema.OnViolation != nil {
+		c.schema.OnViolation(*v)
+	}
+	err := c.Core.Write(ent, fields)
+	if c.schema.Strict {
+		panic(v)
+	}
+	return err
+}
+
+func (c *schemaCore) validate(ent zapcore.Entry, fields []zapcore.Field) *SchemaViolation {
+	for _, f := range fields {
+		if _, ok := f.Interface.(schemaExempt); ok && f.Type == zapcore.SkipType {
+			return nil
+		}
+	}
+	kinds := make(map[string]FieldKind, len(c.context)+len(fields))
+	if nested := collectKinds(kinds, c.context); !nested {
+		collectKinds(kinds, fields)
+	}
+
+	v := SchemaViolation{Message: ent.Message}
+	for _, key := range c.schema.Required {
+		if _, ok := kinds[key]; !ok {
+			v.Missing = append(v.Missing, key)
+		}
+	}
+	for key, want := range c.schema.Types {
+		got, ok := kinds[key]
+		if ok && got != want && got != AnyKind && want != AnyKind {
+			v.Mismatched = append(v.Mismatched, fmt.Sprintf("%s: want %s, got %s", key, want, got))
+		}
+	}
+	if len(v.Missing) == 0 && len(v.Mismatched) == 0 {
+		return nil
+	}
+	sort.Strings(v.Mismatched)
+	return &v
+}
+
+// collectKinds records the kind of every top-level field and reports whether
+// a namespace was opened, after which fields are nested
+func collectKinds(kinds map[string]FieldKind, fields []zapcore.Field) bool {
+	for _, f := range fields {
+		switch f.Type {
+		case zapcore.NamespaceType:
+			return true
+		case zapcore.SkipType:
+			continue
+		case zapcore.InlineMarshalerType:
+			if lazy, ok := f.Interface.(*lazyField); ok {
+				f = lazy.field()
+			} else {
+				continue
+			}
+		}
+		kinds[f.Key] = fieldKind(f)
+	}
+	return false
+}
+
+func fieldKind(f zapcore.Field) FieldKind {
+	switch f.Type {
+	case zapcore.StringType, zapcore.ByteStringType, zapcore.StringerType, zapcore.BinaryType:
+		return StringKind
+	case zapcore.BoolType:
+		return BoolKind
+	case zapcore.Int64Type, zapcore.Int32Type, zapcore.Int16Type, zapcore.Int8Type,
+		zapcore.Uint64Type, zapcore.Uint32Type, zapcore.Uint16Type, zapcore.Uint8Type, zapcore.UintptrType,
+		zapcore.Float64Type, zapcore.Float32Type, zapcore.Complex128Type, zapcore.Complex64Type:
+		return NumberKind
+	case zapcore.TimeType, zapcore.TimeFullType:
+		return TimeKind
+	case zapcore.DurationType:
+		return DurationKind
+	case zapcore.ErrorType:
+		return ErrorKind
+	case zapcore.ObjectMarshalerType:
+		return ObjectKind
+	case zapcore.ArrayMarshalerType:
+		return ArrayKind
+	default:
+		return AnyKind
+	}
+}
@@ -0,0 +1,131 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Scope adds the fields to the entries of le and of the loggers derived from
+// it until the returned function is called, for a temporary enrichment of a
+// block without deriving a logger:
+//
+//	defer le.Scope(log.Fields{"step": "parse"})()
+//
+// The fields are seen by every goroutine using le. Scopes are stacked, ending
+// one also ends the scopes started after it and not ended yet. Ending a
+// scope again does nothing.
+func (le *LogEntry) Scope(fields Fields) func() {
+	if le.scope == nil {
+		return func() {}
+	}
+	depth := le.scope.push(appendFields(nil, fields, le.sortFields))
+	var once sync.Once
+	return func() {
+		once.Do(func() { le.scope.pop(depth) })
+	}
+}
+
+// newScopedEntry creates a LogEntry of which the loggers add the fields of
+// its Scope
+func newScopedEntry(infoLogger *zap.Logger, errorLogger *zap.Logger) *LogEntry {
+	scope := &fieldScope{}
+	wrap := withScope(scope)
+	l := getLogEntry(infoLogger.WithOptions(wrap), errorLogger.WithOptions(wrap))
+	l.scope = scope
+	return l
+}
+
+// fieldScope is the stack of the fields of LogEntry.Scope. The fields are
+// flattened into current on every change so the cores read them without
+// locking.
+type fieldScope struct {
+	mu      sync.Mutex
+	stack   [][]zapcore.Field
+	current atomic.Pointer[[]zapcore.Field]
+}
+
+func (s *fieldScope) push(fields []zapcore.Field) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stack = append(s.stack, fields)
+	s.flatten()
+	return len(s.stack) - 1
+}
+
+func (s *fieldScope) pop(depth int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if depth >= len(s.stack) {
+		return
+	}
+	clear(s.stack[depth:])
+	s.stack = s.stack[:depth]
+	s.flatten()
+}
+
+// flatten stores the fields of the stack in current, s.mu must be held
+func (s *fieldScope) flatten() {
+	if len(s.stack) == 0 {
+		s.current.Store(nil)
+		return
+	}
+	var fields []zapcore.Field
+	for _, pushed := range s.stack {
+		fields = append(fields, pushed...)
+	}
+	s.current.Store(&fields)
+}
+
+// withScope returns the option adding the fields of scope to the core. The
+// scopes of a logger and of the ones it is derived from share a scopeCore.
+func withScope(scope *fieldScope) zap.Option {
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if c, ok := core.(*scopeCore); ok {
+			scopes := append(c.scopes[:len(c.scopes):len(c.scopes)], scope)
+			return &scopeCore{Core: c.Core, scopes: scopes}
+		}
+		return &scopeCore{Core: core, scopes: []*fieldScope{scope}}
+	})
+}
+
+// scopeCore adds the fields of the scopes, the ones of the parent loggers
+// first
+type scopeCore struct {
+	zapcore.Core
+	scopes []*fieldScope
+}
+
+func (c *scopeCore) With(fields []zapcore.Field) zapcore.Core {
+	return &scopeCore{Core: c.Core.With(fields), scopes: c.scopes}
+}
+
+// Check passes the entry through the Check of the wrapped cores, e.g. the
+// one of sampling, the scope fields are added when it is written
+func (c *scopeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return addChecked(ent, ce, c.Core, c)
+}
+
+func (c *scopeCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, c.addFields(fields))
+}
+
+// addFields prepends the current fields of the scopes to fields
+func (c *scopeCore) addFields(fields []zapcore.Field) []zapcore.Field {
+	var scoped []zapcore.Field
+	for _, scope := range c.scopes {
+		if current := scope.current.Load(); current != nil {
+			scoped = append(scoped, *current...)
+		}
+	}
+	if len(scoped) == 0 {
+		return fields
+	}
+	return append(scoped, fields...)
+}
+
+func (c *scopeCore) writeChecked(ce *zapcore.CheckedEntry, fields []zapcore.Field) {
+	ce.Write(c.addFields(fields)...)
+}
@@ -0,0 +1,147 @@
+package log
+
+import (
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// SecretMask replaces the secrets found in messages
+const SecretMask = "[REDACTED]"
+
+// secretPattern finds one kind of secret. With prefix the first submatch is
+// kept in front of the mask, such as the "Bearer " of a token.
+type secretPattern struct {
+	re     *regexp.Regexp
+	prefix bool
+	valid  func(string) bool
+}
+
+var secretPatterns = []secretPattern{
+	// AWS access key ids
+	{re: regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA)[0-9A-Z]{16}\b`)},
+	// AWS secret access keys assigned in the text
+	{re: regexp.MustCompile(`(?i)(aws_secret_access_key\s*[=:]\s*)[A-Za-z0-9/+=]{40,}`), prefix: true},
+	// bearer and basic authorization credentials
+	{re: regexp.MustCompile(`(?i)(\b(?:bearer|basic)\s+)[A-Za-z0-9\-._~+/]{8,}=*`), prefix: true},
+	// JSON web tokens
+	{re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)},
+	// card numbers, 13 to 19 digits optionally grouped by spaces or dashes,
+	// with the prefix of a card network so order ids and timestamps passing
+	// the checksum by chance are kept
+	{re: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), valid: cardNumber},
+}
+
+// maskSecrets replaces the secrets found in s with SecretMask
+func maskSecrets(s string) string {
+	for _, p := range secretPatterns {
+		if !p.re.MatchString(s) {
+			continue
+		}
+		s = p.re.ReplaceAllStringFunc(s, func(match string) string {
+			if p.valid != nil && !p.valid(match) {
+				return match
+			}
+			if p.prefix {
+				return p.re.FindStringSubmatch(match)[1] + SecretMask
+			}
+			return SecretMask
+		})
+	}
+	return s
+}
+
+// cardPrefixes are the issuer identification number ranges of the major card
+// networks, as inclusive ranges of the leading digits
+var cardPrefixes = []struct{ low, high string }{
+	{"4", "4"},       // Visa
+	{"51", "55"},     // Mastercard
+	{"2221", "2720"}, // Mastercard
+	{"34", "34"},     // American Express
+	{"37", "37"},     // American Express
+	{"300", "305"},   // Diners Club
+	{"36", "36"},     // Diners Club
+	{"38", "39"},     // Diners Club
+	{"6011", "6011"}, // Discover
+	{"644", "649"},   // Discover
+	{"65", "65"},     // Discover
+	{"3528", "3589"}, // JCB
+	{"62", "62"},     // UnionPay
+	{"5018", "5018"}, // Maestro
+	{"5020", "5020"}, // Maestro
+	{"5038", "5038"}, // Maestro
+	{"5893", "5893"}, // Maestro
+	{"6304", "6304"}, // Maestro
+	{"6759", "6759"}, // Maestro
+	{"6761", "6763"}, // Maestro
+}
+
+// cardNumber reports whether s, digits grouped by spaces or dashes, starts
+// with the prefix of a card network and passes the Luhn checksum
+func cardNumber(s string) bool {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(s)
+	return knownCardPrefix(digits) && luhnValid(digits)
+}
+
+func knownCardPrefix(digits string) bool {
+	for _, p := range cardPrefixes {
+		if len(digits) < len(p.low) {
+			continue
+		}
+		if prefix := digits[:len(p.low)]; prefix >= p.low && prefix <= p.high {
+			return true
+		}
+	}
+	return false
+}
+
+// luhnValid reports whether the digits pass the Luhn checksum of card numbers
+func luhnValid(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// secretsCore masks the secrets in the messages. It wraps the whole pipeline
+// of a logger so error reporters only ever see masked messages.
+type secretsCore struct {
+	zapcore.Core
+}
+
+func newSecretsCore(core zapcore.Core, enabled bool) zapcore.Core {
+	if !enabled {
+		return core
+	}
+	return &secretsCore{Core: core}
+}
+
+func (c *secretsCore) With(fields []zapcore.Field) zapcore.Core {
+	return &secretsCore{Core: c.Core.With(fields)}
+}
+
+// Check masks the message once the entry is known to be written, the
+// cores below keep their own level and filter decisions
+func (c *secretsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	ce = c.Core.Check(ent, ce)
+	if ce != nil {
+		ce.Entry.Message = maskSecrets(ce.Entry.Message)
+	}
+	return ce
+}
+
+func (c *secretsCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = maskSecrets(ent.Message)
+	return c.Core.Write(ent, fields)
+}
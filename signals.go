@@ -0,0 +1,138 @@
+package log
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// HandleSignals closes the loggers, syncing their sinks first, when the
+// process receives SIGTERM or SIGINT, and stops handling them. The signal is
+// not raised again: the application is expected to handle it too, e.g. for a
+// graceful shutdown, or to use HandleSignalsAndRaise. Without loggers the
+// default logger at the time of the signal is closed. Handling stops when ctx
+// is done.
+func HandleSignals(ctx context.Context, loggers ...*LogEntry) {
+	handleSignals(ctx, loggers, false)
+}
+
+// HandleSignalsAndRaise is HandleSignals raising the signal again once the
+// loggers are closed, so the process exits as it would without the handler
+// unless the application handles the signal too
+func HandleSignalsAndRaise(ctx context.Context, loggers ...*LogEntry) {
+	handleSignals(ctx, loggers, true)
+}
+
+func handleSignals(ctx context.Context, loggers []*LogEntry, reraise bool) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		defer signal.Stop(signals)
+		select {
+		case <-ctx.Done():
+		case sig := <-signals:
+			if err := closeLoggers(loggers); err != nil {
+				Errorv("failed close loggers on signal", zap.Error(err))
+			}
+			if reraise {
+				signal.Stop(signals)
+				raise(sig)
+			}
+		}
+	}()
+}
+
+// RotateOnSignal rotates the files of the loggers, or of the default logger,
+// whenever the process receives SIGUSR1, like logrotate expects. It does
+// nothing on Windows, which has no such signal. Handling stops when ctx is done.
+func RotateOnSignal(ctx context.Context, loggers ...*LogEntry) {
+	if rotateSignal == nil {
+		return
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, rotateSignal)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				if err := rotateLoggers(loggers); err != nil {
+					Errorv("failed rotate log files on signal", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// ReopenOnSignal reopens the files of the loggers, or of the default logger,
+// whenever the process receives SIGHUP, for files rotated by logrotate with
+// Config.ExternalRotation. It does nothing on Windows. Handling stops when
+// ctx is done.
+func ReopenOnSignal(ctx context.Context, loggers ...*LogEntry) {
+	if reopenSignal == nil {
+		return
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, reopenSignal)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				if err := reopenLoggers(loggers); err != nil {
+					Errorv("failed reopen log files on signal", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+func signalLoggers(loggers []*LogEntry) []*LogEntry {
+	if len(loggers) == 0 {
+		return []*LogEntry{Default()}
+	}
+	return loggers
+}
+
+func closeLoggers(loggers []*LogEntry) error {
+	var err error
+	for _, le := range signalLoggers(loggers) {
+		err = multierr.Append(err, le.Close())
+	}
+	return err
+}
+
+func rotateLoggers(loggers []*LogEntry) error {
+	var err error
+	for _, le := range signalLoggers(loggers) {
+		err = multierr.Append(err, le.Rotate())
+	}
+	return err
+}
+
+// raise sends sig to the process again now that it is no longer caught here
+func raise(sig os.Signal) {
+	p, err := os.FindProcess(os.Getpid())
+	if err == nil {
+		err = p.Signal(sig)
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+func reopenLoggers(loggers []*LogEntry) error {
+	var err error
+	for _, le := range signalLoggers(loggers) {
+		err = multierr.Append(err, le.Reopen())
+	}
+	return err
+}
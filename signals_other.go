@@ -0,0 +1,15 @@
+//go:build !windows
+
+package log
+
+import (
+	"os"
+	"syscall"
+)
+
+var (
+	// rotateSignal asks RotateOnSignal to rotate the log files
+	rotateSignal os.Signal = syscall.SIGUSR1
+	// reopenSignal asks ReopenOnSignal to reopen the log files
+	reopenSignal os.Signal = syscall.SIGHUP
+)
@@ -0,0 +1,9 @@
+//go:build windows
+
+package log
+
+import "os"
+
+// rotateSignal and reopenSignal are nil, Windows has no signals for
+// RotateOnSignal and ReopenOnSignal
+var rotateSignal, reopenSignal os.Signal
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package log
+
+import (
+	"errors"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func openEventLog(source string) (zapcore.WriteSyncer, error) {
+	return nil, errors.New("the Windows Event Log is only available on windows")
+}
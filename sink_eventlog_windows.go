@@ -0,0 +1,92 @@
+//go:build windows
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// eventLogID is the event id of every record written to the Event Log
+const eventLogID = 1
+
+// EventLogSink writes error and more severe entries to the Windows Event Log.
+// It expects JSON encoded entries, other lines are written as information
+// events holding the line.
+type EventLogSink struct {
+	log eventWriter
+}
+
+// eventWriter is the part of eventlog.Log used by the sink
+type eventWriter interface {
+	Info(eid uint32, msg string) error
+	Error(eid uint32, msg string) error
+	Close() error
+}
+
+// NewEventLogSink opens the Event Log for the source. Register the source once
+// with InstallEventLogSource to get properly rendered messages.
+func NewEventLogSink(source string) (*EventLogSink, error) {
+	l, err := eventlog.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	return &EventLogSink{log: l}, nil
+}
+
+// InstallEventLogSource registers the source in the registry, which requires
+// administrator rights
+func InstallEventLogSource(source string) error {
+	return eventlog.InstallAsEventCreate(source, eventlog.Error|eventlog.Warning|eventlog.Info)
+}
+
+func (s *EventLogSink) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\n"), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		level, ok := entryLevel(line)
+		var err error
+		switch {
+		case !ok:
+			err = s.log.Info(eventLogID, string(line))
+		case level >= ErrorLevel:
+			err = s.log.Error(eventLogID, string(line))
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (s *EventLogSink) Sync() error {
+	return nil
+}
+
+func (s *EventLogSink) Close() error {
+	return s.log.Close()
+}
+
+// entryLevel reads the level of a JSON encoded entry, defaulting to error,
+// ok is false for lines which are not JSON objects
+func entryLevel(line []byte) (level zapcore.Level, ok bool) {
+	var entry map[string]interface{}
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return 0, false
+	}
+	level = ErrorLevel
+	if lvl, ok := entry[LevelKey].(string); ok {
+		if parsed, err := ParseLevel(lvl); err == nil {
+			level = parsed
+		}
+	}
+	return level, true
+}
+
+func openEventLog(source string) (zapcore.WriteSyncer, error) {
+	return NewEventLogSink(source)
+}
@@ -0,0 +1,91 @@
+package log
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd socket activation
+const listenFDsStart = 3
+
+func init() {
+	if err := RegisterSink("fd", newFDSink); err != nil {
+		panic(err)
+	}
+}
+
+var (
+	fdFilesMu sync.Mutex
+	// fdFiles holds one file per descriptor for the process, the sinks of
+	// the loggers replaced by Reconfigure share it: the finalizer of a file
+	// closes its descriptor
+	fdFiles = map[int]*os.File{0: os.Stdin, 1: os.Stdout, 2: os.Stderr}
+)
+
+// fdFile returns the file of the descriptor
+func fdFile(fd int, name string) *os.File {
+	fdFilesMu.Lock()
+	defer fdFilesMu.Unlock()
+	f, ok := fdFiles[fd]
+	if !ok {
+		f = os.NewFile(uintptr(fd), name)
+		fdFiles[fd] = f
+	}
+	return f
+}
+
+// fdSink writes to an inherited file descriptor such as a socket handed over
+// by systemd. Sync is a no-op since sockets and pipes can't be synced.
+type fdSink struct {
+	*os.File
+}
+
+func (s fdSink) Sync() error {
+	return nil
+}
+
+// Close leaves the descriptor open for the other sinks writing to it
+func (s fdSink) Close() error {
+	return nil
+}
+
+// newFDSink opens "fd://3" style URLs. The host is either a descriptor number
+// or a name listed in LISTEN_FDNAMES of a socket-activated service.
+func newFDSink(u *url.URL) (Sink, error) {
+	name := u.Host
+	if name == "" {
+		name = strings.TrimPrefix(u.Opaque, "//")
+	}
+	fd, err := strconv.Atoi(name)
+	if err != nil {
+		if fd, err = listenFDByName(name); err != nil {
+			return nil, err
+		}
+	}
+	if fd < 0 {
+		return nil, fmt.Errorf("invalid file descriptor %d", fd)
+	}
+	return fdSink{File: fdFile(fd, "fd://"+name)}, nil
+}
+
+// listenFDByName looks up a descriptor passed with systemd socket activation
+func listenFDByName(name string) (int, error) {
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return -1, fmt.Errorf("no sockets passed to this process for %q", name)
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil {
+		return -1, fmt.Errorf("invalid LISTEN_FDS: %w", err)
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	for i := 0; i < count && i < len(names); i++ {
+		if names[i] == name {
+			return listenFDsStart + i, nil
+		}
+	}
+	return -1, fmt.Errorf("no socket named %q in LISTEN_FDNAMES", name)
+}
@@ -0,0 +1,56 @@
+package log
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestFDSinkSharesDescriptor(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	u, err := url.Parse(fmt.Sprintf("fd://%d", w.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the sink of a replaced logger is closed and garbage collected
+	old, err := newFDSink(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.Write([]byte("first\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := old.Close(); err != nil {
+		t.Fatal(err)
+	}
+	old = nil
+	for i := 0; i < 3; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+
+	sink, err := newFDSink(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sink.Write([]byte("second\n")); err != nil {
+		t.Fatalf("write after the old sink was collected: %v", err)
+	}
+	lines := bufio.NewScanner(r)
+	for _, want := range []string{"first", "second"} {
+		if !lines.Scan() || lines.Text() != want {
+			t.Fatalf("read %q, want %q", lines.Text(), want)
+		}
+	}
+	runtime.KeepAlive(w)
+}
@@ -0,0 +1,247 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FluentConfig configures a sink speaking the Fluentd forward protocol
+type FluentConfig struct {
+	// Address of the fluentd or fluent-bit forward input, host:port
+	Address string
+	// Tag routes the records inside the fluentd pipeline
+	Tag string
+	// SharedKey enables the shared key handshake of the secure forward input
+	SharedKey string
+	// Username and Password are sent for user authentication when set
+	Username string
+	Password string
+	// Hostname identifies this client in the handshake. It defaults to os.Hostname
+	Hostname string
+	// Timeout bounds dialing, the handshake and each write. It defaults to 5s
+	Timeout time.Duration
+	// MaxRecordBytes rejects bigger records, see RejectionError. Zero means no limit.
+	MaxRecordBytes int
+}
+
+// FluentSink writes JSON encoded entries to fluentd as forward protocol
+// messages stamped with the time of the entry. Lines which are not JSON
+// objects are sent as {"message": line}.
+type FluentSink struct {
+	mu     sync.Mutex
+	config FluentConfig
+	conn   net.Conn
+	enc    msgpackWriter
+}
+
+func init() {
+	if err := RegisterSink("fluent", newFluentSinkFromURL); err != nil {
+		panic(err)
+	}
+}
+
+// NewFluentSink returns a sink which connects lazily and reconnects after errors
+func NewFluentSink(config FluentConfig) *FluentSink {
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if config.Hostname == "" {
+		config.Hostname, _ = os.Hostname()
+	}
+	return &FluentSink{config: config}
+}
+
+// newFluentSinkFromURL opens "fluent://host:24224/tag?shared_key=...&username=...&password=...".
+// timeout and max_record_bytes are accepted as query parameters as well.
+func newFluentSinkFromURL(u *url.URL) (Sink, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("fluent sink url %q has no host", u.String())
+	}
+	q := u.Query()
+	config := FluentConfig{
+		Address:   u.Host,
+		Tag:       strings.TrimPrefix(u.Path, "/"),
+		SharedKey: q.Get("shared_key"),
+		Username:  q.Get("username"),
+		Password:  q.Get("password"),
+		Hostname:  q.Get("hostname"),
+	}
+	if maxBytes := q.Get("max_record_bytes"); maxBytes != "" {
+		n, err := strconv.Atoi(maxBytes)
+		if err != nil {
+			return nil, fmt.Errorf("fluent sink max_record_bytes: %w", err)
+		}
+		config.MaxRecordBytes = n
+	}
+	if timeout := q.Get("timeout"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("fluent sink timeout: %w", err)
+		}
+		config.Timeout = d
+	}
+	return NewFluentSink(config), nil
+}
+
+func (s *FluentSink) Write(p []byte) (int, error) {
+	if s.config.MaxRecordBytes > 0 && len(p) > s.config.MaxRecordBytes {
+		return 0, NewRejectionError("record of %d bytes exceeds the limit of %d bytes", len(p), s.config.MaxRecordBytes)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.enc.reset()
+	now := time.Now()
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\n"), []byte("\n")) {
+		s.encodeMessage(line, now)
+	}
+
+	if err := s.connect(); err != nil {
+		return 0, err
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(s.config.Timeout))
+	if _, err := s.conn.Write(s.enc.buf); err != nil {
+		s.closeConn()
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (s *FluentSink) Sync() error {
+	return nil
+}
+
+func (s *FluentSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeConn()
+}
+
+// encodeMessage appends a message mode event: [tag, time, record]. The time
+// is the one of the entry, now for lines without one.
+func (s *FluentSink) encodeMessage(line []byte, now time.Time) {
+	t := now
+	record := map[string]interface{}{}
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if err := dec.Decode(&record); err != nil {
+		record = map[string]interface{}{"message": string(line)}
+	} else if entryTime, err := ParseEntryTime(valueText(record[TimeKey])); err == nil {
+		t = entryTime
+	}
+	s.enc.writeArrayHeader(3)
+	s.enc.writeString(s.config.Tag)
+	s.enc.writeEventTime(t)
+	s.enc.writeValue(record)
+}
+
+func (s *FluentSink) connect() error {
+	if s.conn != nil {
+		return nil
+	}
+	conn, err := net.DialTimeout("tcp", s.config.Address, s.config.Timeout)
+	if err != nil {
+		return err
+	}
+	if s.config.SharedKey != "" {
+		conn.SetDeadline(time.Now().Add(s.config.Timeout))
+		if err := s.handshake(conn); err != nil {
+			conn.Close()
+			return err
+		}
+		conn.SetDeadline(time.Time{})
+	}
+	s.conn = conn
+	return nil
+}
+
+func (s *FluentSink) closeConn() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// handshake runs the HELO, PING, PONG exchange of the secure forward protocol
+func (s *FluentSink) handshake(conn net.Conn) error {
+	r := &msgpackReader{r: bufio.NewReader(conn)}
+
+	helo, err := readFluentMessage(r, "HELO", 2)
+	if err != nil {
+		return err
+	}
+	options, _ := helo[1].(map[string]interface{})
+	nonce := msgpackString(options["nonce"])
+	authSalt := msgpackString(options["auth"])
+
+	saltBytes := make([]byte, 16)
+	if _, err := rand.Read(saltBytes); err != nil {
+		return err
+	}
+	salt := hex.EncodeToString(saltBytes)
+
+	var ping msgpackWriter
+	ping.writeArrayHeader(6)
+	ping.writeString("PING")
+	ping.writeString(s.config.Hostname)
+	ping.writeString(salt)
+	ping.writeString(sha512Hex(salt, s.config.Hostname, nonce, s.config.SharedKey))
+	if s.config.Username != "" {
+		ping.writeString(s.config.Username)
+		ping.writeString(sha512Hex(authSalt, s.config.Username, s.config.Password))
+	} else {
+		ping.writeString("")
+		ping.writeString("")
+	}
+	if _, err := conn.Write(ping.buf); err != nil {
+		return err
+	}
+
+	pong, err := readFluentMessage(r, "PONG", 5)
+	if err != nil {
+		return err
+	}
+	if ok, _ := pong[1].(bool); !ok {
+		return fmt.Errorf("fluent authentication failed: %s", msgpackString(pong[2]))
+	}
+	serverHostname := msgpackString(pong[3])
+	if msgpackString(pong[4]) != sha512Hex(salt, serverHostname, nonce, s.config.SharedKey) {
+		return fmt.Errorf("fluent server %s failed shared key check", serverHostname)
+	}
+	return nil
+}
+
+func readFluentMessage(r *msgpackReader, kind string, minLen int) ([]interface{}, error) {
+	v, err := r.readValue()
+	if err != nil {
+		return nil, err
+	}
+	msg, ok := v.([]interface{})
+	if !ok || len(msg) < minLen || msgpackString(msg[0]) != kind {
+		return nil, fmt.Errorf("fluent handshake expected %s: %w", kind, errMsgpackShape)
+	}
+	return msg, nil
+}
+
+func sha512Hex(parts ...string) string {
+	h := sha512.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
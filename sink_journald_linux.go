@@ -0,0 +1,193 @@
+//go:build linux
+
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/sys/unix"
+)
+
+// journalSocket is where journald receives native protocol datagrams
+const journalSocket = "/run/systemd/journal/socket"
+
+// JournaldSink writes entries to the systemd journal with the entry level
+// mapped to PRIORITY and the structured fields as journal fields, so they can
+// be filtered with journalctl. It expects JSON encoded entries, other lines
+// are written as MESSAGE at info priority.
+type JournaldSink struct {
+	mu         sync.Mutex
+	conn       *net.UnixConn
+	addr       *net.UnixAddr
+	identifier string
+}
+
+// NewJournaldSink connects to the local journal. identifier is written as
+// SYSLOG_IDENTIFIER and defaults to the executable name.
+func NewJournaldSink(identifier string) (*JournaldSink, error) {
+	if identifier == "" {
+		identifier = filepath.Base(os.Args[0])
+	}
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &JournaldSink{
+		conn:       conn,
+		addr:       &net.UnixAddr{Name: journalSocket, Net: "unixgram"},
+		identifier: identifier,
+	}, nil
+}
+
+func (s *JournaldSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\n"), []byte("\n")) {
+		if err := s.send(s.encode(line)); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (s *JournaldSink) Sync() error {
+	return nil
+}
+
+func (s *JournaldSink) Close() error {
+	return s.conn.Close()
+}
+
+// encode converts a JSON encoded entry to the native journal format
+func (s *JournaldSink) encode(line []byte) []byte {
+	var buf bytes.Buffer
+	entry := map[string]json.RawMessage{}
+	if err := json.Unmarshal(line, &entry); err != nil {
+		writeJournalField(&buf, "MESSAGE", string(line))
+		writeJournalField(&buf, "PRIORITY", "6")
+		writeJournalField(&buf, "SYSLOG_IDENTIFIER", s.identifier)
+		return buf.Bytes()
+	}
+
+	level, err := ParseLevel(journalString(entry[LevelKey]))
+	if err != nil {
+		level = InfoLevel
+	}
+	writeJournalField(&buf, "MESSAGE", journalString(entry[MessageKey]))
+	writeJournalField(&buf, "PRIORITY", journalPriority(level))
+	writeJournalField(&buf, "SYSLOG_IDENTIFIER", s.identifier)
+	if caller := journalString(entry[CallerKey]); caller != "" {
+		if i := strings.LastIndexByte(caller, ':'); i > 0 {
+			writeJournalField(&buf, "CODE_FILE", caller[:i])
+			writeJournalField(&buf, "CODE_LINE", caller[i+1:])
+		}
+	}
+	for _, k := range []string{TimeKey, LevelKey, MessageKey, CallerKey} {
+		delete(entry, k)
+	}
+	for k, v := range entry {
+		writeJournalField(&buf, journalFieldName(k), journalString(v))
+	}
+	return buf.Bytes()
+}
+
+// send writes the datagram, passing it as a sealed memfd when it is too big
+// for the socket as the journal protocol specifies
+func (s *JournaldSink) send(msg []byte) error {
+	_, err := s.conn.WriteToUnix(msg, s.addr)
+	if err == nil || !errors.Is(err, syscall.EMSGSIZE) && !errors.Is(err, syscall.ENOBUFS) {
+		return err
+	}
+
+	fd, err := unix.MemfdCreate("journal-entry", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return err
+	}
+	file := os.NewFile(uintptr(fd), "journal-entry")
+	defer file.Close()
+	if _, err := file.Write(msg); err != nil {
+		return err
+	}
+	if _, err := unix.FcntlInt(uintptr(fd), unix.F_ADD_SEALS, unix.F_SEAL_SHRINK|unix.F_SEAL_GROW|unix.F_SEAL_WRITE|unix.F_SEAL_SEAL); err != nil {
+		return err
+	}
+	_, _, err = s.conn.WriteMsgUnix(nil, unix.UnixRights(fd), s.addr)
+	return err
+}
+
+// writeJournalField writes KEY=value, or the length-prefixed binary-safe form
+// for values containing newlines
+func writeJournalField(buf *bytes.Buffer, key, value string) {
+	buf.WriteString(key)
+	if !strings.ContainsRune(value, '\n') {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+	buf.WriteByte('\n')
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// journalFieldName maps a field key to a valid journal field name: upper
+// case letters, digits and underscores, not starting with an underscore or digit
+func journalFieldName(key string) string {
+	name := []byte(strings.ToUpper(key))
+	for i, c := range name {
+		if !('A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			name[i] = '_'
+		}
+	}
+	s := strings.TrimLeft(string(name), "_0123456789")
+	if s == "" {
+		s = "FIELD"
+	}
+	if len(s) > 64 {
+		s = s[:64]
+	}
+	return s
+}
+
+// journalString returns JSON strings unquoted and other values as JSON
+func journalString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
+
+// journalPriority maps levels to syslog priorities
+func journalPriority(level zapcore.Level) string {
+	switch level {
+	case TraceLevel, DebugLevel:
+		return "7"
+	case InfoLevel:
+		return "6"
+	case NoticeLevel:
+		return "5"
+	case WarnLevel:
+		return "4"
+	case ErrorLevel:
+		return "3"
+	default:
+		return "2"
+	}
+}
+
+func openJournald(identifier string) (zapcore.WriteSyncer, error) {
+	return NewJournaldSink(identifier)
+}
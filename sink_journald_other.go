@@ -0,0 +1,13 @@
+//go:build !linux
+
+package log
+
+import (
+	"errors"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func openJournald(identifier string) (zapcore.WriteSyncer, error) {
+	return nil, errors.New("journald is only available on linux")
+}
@@ -0,0 +1,127 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Sink is a destination opened from a URL by a factory registered with RegisterSink
+type Sink = zap.Sink
+
+// RegisterSink registers a factory for sink URLs with the given scheme, so
+// e.g. "udp://collector:5140" in Config.InfoSinkURLs opens the sink built by
+// the factory registered for "udp". Plain paths and "file://" URLs open files,
+// "stdout" and "stderr" the standard streams.
+func RegisterSink(scheme string, factory func(*url.URL) (Sink, error)) error {
+	return zap.RegisterSink(scheme, factory)
+}
+
+// sinkCloser closes the sinks opened by zap.Open
+type sinkCloser func()
+
+func (c sinkCloser) Close() error {
+	c()
+	return nil
+}
+
+// openExtraSinks returns the extra info and error destinations of the config
+// and the ones opened for it, which LogEntry.Close closes. The sinks of
+// ExtraInfoSinks and ExtraErrorSinks are left to the caller. When one cannot
+// be opened, the ones opened before it are closed.
+func openExtraSinks(config Config) (infoWriters, errWriters []zapcore.WriteSyncer, opened []io.Closer, err error) {
+	defer func() {
+		if err != nil {
+			for _, c := range opened {
+				c.Close()
+			}
+			infoWriters, errWriters, opened = nil, nil, nil
+		}
+	}()
+	closeLater := func(ws zapcore.WriteSyncer) {
+		if c, ok := ws.(io.Closer); ok {
+			opened = append(opened, c)
+		}
+	}
+
+	var deadLetter *DeadLetterWriter
+	if config.DeadLetter != nil {
+		deadLetterConfig := *config.DeadLetter
+		if deadLetterConfig.Directory, err = deadLetterDirectory(config); err != nil {
+			return nil, nil, nil, err
+		}
+		if deadLetter, err = NewDeadLetterWriter(deadLetterConfig); err != nil {
+			return nil, nil, nil, err
+		}
+		opened = append(opened, deadLetter)
+	}
+	wrap := func(ws zapcore.WriteSyncer, name string) zapcore.WriteSyncer {
+		if deadLetter == nil {
+			return ws
+		}
+		return WithDeadLetter(ws, name, deadLetter)
+	}
+
+	for i, ws := range config.ExtraInfoSinks {
+		infoWriters = append(infoWriters, wrap(ws, fmt.Sprintf("extra_info_%d", i)))
+	}
+	for i, ws := range config.ExtraErrorSinks {
+		errWriters = append(errWriters, wrap(ws, fmt.Sprintf("extra_error_%d", i)))
+	}
+
+	for _, u := range config.InfoSinkURLs {
+		ws, closeSink, err := zap.Open(u)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		opened = append(opened, sinkCloser(closeSink))
+		infoWriters = append(infoWriters, wrap(ws, sinkName(u)))
+	}
+	for _, u := range config.ErrorSinkURLs {
+		ws, closeSink, err := zap.Open(u)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		opened = append(opened, sinkCloser(closeSink))
+		errWriters = append(errWriters, wrap(ws, sinkName(u)))
+	}
+	if config.Journald {
+		ws, err := openJournald(config.JournaldIdentifier)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		closeLater(ws)
+		infoWriters = append(infoWriters, ws)
+		errWriters = append(errWriters, ws)
+	}
+	if config.WindowsEventLogSource != "" {
+		ws, err := openEventLog(config.WindowsEventLogSource)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		closeLater(ws)
+		errWriters = append(errWriters, ws)
+	}
+	return infoWriters, errWriters, opened, nil
+}
+
+// deadLetterDirectory resolves the directory of the dead-letter file like the
+// log directory, against Config.BaseDir
+func deadLetterDirectory(config Config) (string, error) {
+	config.Directory = config.DeadLetter.Directory
+	return resolveDirectory(config)
+}
+
+// sinkName identifies a sink URL without credentials passed in it
+func sinkName(sinkURL string) string {
+	u, err := url.Parse(sinkURL)
+	if err != nil {
+		return "sink"
+	}
+	u.User = nil
+	u.RawQuery = ""
+	return u.String()
+}
@@ -0,0 +1,57 @@
+package log
+
+import (
+	"net/url"
+	"os"
+	"sync/atomic"
+	"testing"
+)
+
+// closingSink counts its Close calls
+type closingSink struct {
+	closed *atomic.Int32
+}
+
+func (s closingSink) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (s closingSink) Sync() error {
+	return nil
+}
+
+func (s closingSink) Close() error {
+	s.closed.Add(1)
+	return nil
+}
+
+func TestCloseClosesSinks(t *testing.T) {
+	var closed atomic.Int32
+	if err := RegisterSink("closetest", func(*url.URL) (Sink, error) {
+		return closingSink{closed: &closed}, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	console, err := os.CreateTemp(t.TempDir(), "console")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer console.Close()
+	le := NewLogEntry(Config{
+		Level:              InfoLevel,
+		ConsoleInfoStream:  console,
+		ConsoleErrorStream: console,
+		InfoSinkURLs:       []string{"closetest://info"},
+		ErrorSinkURLs:      []string{"closetest://error"},
+	})
+	le.Info("entry")
+	if n := closed.Load(); n != 0 {
+		t.Fatalf("%d sinks closed before Close", n)
+	}
+	if err := le.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := closed.Load(); n != 2 {
+		t.Errorf("%d sinks closed, want 2", n)
+	}
+}
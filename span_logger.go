@@ -0,0 +1,37 @@
+package log
+
+import (
+	"context"
+)
+
+// SpanLogger holds the debug and info entries of a request in memory and only
+// writes them when the request fails, keeping full detail for failures at a
+// fraction of the volume. Warn entries are written right away, error and more
+// severe entries are written after flushing the held entries.
+type SpanLogger struct {
+	*LogEntry
+	buf *txBuffer
+}
+
+// StartSpanLogger derives a SpanLogger from FromContext(ctx) and stores it in
+// the returned context, so code below uses it through FromContext
+func StartSpanLogger(ctx context.Context) (context.Context, *SpanLogger) {
+	logEntry, buf := newBufferedLogEntry(FromContext(ctx), WarnLevel, ErrorLevel)
+	sl := &SpanLogger{LogEntry: logEntry, buf: buf}
+	return sl.ContextWithLogger(ctx), sl
+}
+
+// Flush writes the held entries
+func (sl *SpanLogger) Flush() error {
+	return sl.buf.flush(false)
+}
+
+// End finishes the request: the held entries are written if err is not nil
+// and dropped otherwise
+func (sl *SpanLogger) End(err error) error {
+	if err != nil {
+		return sl.Flush()
+	}
+	sl.buf.take()
+	return nil
+}
@@ -0,0 +1,21 @@
+module github.com/olee12/log/sqllog
+
+go 1.21
+
+replace github.com/olee12/log => ../
+
+require (
+	github.com/olee12/log v0.0.0-00010101000000-000000000000
+	go.uber.org/zap v1.26.0
+	gorm.io/gorm v1.25.12
+)
+
+require (
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	go.uber.org/multierr v1.11.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	golang.org/x/term v0.21.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+	gopkg.in/natefinch/lumberjack.v2 v2.2.1 // indirect
+)
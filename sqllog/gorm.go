@@ -0,0 +1,99 @@
+package sqllog
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/olee12/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+)
+
+// GormLogger implements gorm's logger.Interface:
+//
+//	db, err := gorm.Open(dialector, &gorm.Config{Logger: sqllog.NewGormLogger(config)})
+//
+// Without Config.LogParams the queries are logged with their placeholders.
+type GormLogger struct {
+	config Config
+	level  logger.LogLevel
+}
+
+var _ logger.Interface = (*GormLogger)(nil)
+
+// NewGormLogger logs the queries of gorm, at logger.Warn mode: failed and
+// slow queries, every query with logger.Info or Config.LogQueries
+func NewGormLogger(config Config) *GormLogger {
+	return &GormLogger{config: config, level: logger.Warn}
+}
+
+// LogMode returns a logger at the gorm level
+func (l *GormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Info {
+		l.entry(ctx).InfoCtx(ctx, fmt.Sprintf(msg, args...), zap.String("source", utils.FileWithLineNum()))
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Warn {
+		l.entry(ctx).WarnCtx(ctx, fmt.Sprintf(msg, args...), zap.String("source", utils.FileWithLineNum()))
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Error {
+		l.entry(ctx).ErrorCtx(ctx, fmt.Sprintf(msg, args...), zap.String("source", utils.FileWithLineNum()))
+	}
+}
+
+func (l *GormLogger) entry(ctx context.Context) *log.LogEntry {
+	if l.config.Logger != nil {
+		return l.config.Logger
+	}
+	return log.FromContext(ctx)
+}
+
+// Trace logs a finished query
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+	config := l.config
+	minLevel := log.WarnLevel
+	switch l.level {
+	case logger.Error:
+		minLevel = log.ErrorLevel
+	case logger.Info:
+		config.LogQueries = true
+		minLevel = log.DebugLevel
+	}
+	if config.LogQueries {
+		minLevel = log.DebugLevel
+	}
+	sql, rows := fc()
+	config.log(ctx, query{
+		sql:     sql,
+		rows:    rows,
+		elapsed: time.Since(begin),
+		err:     err,
+		extra:   []zapcore.Field{zap.String("source", utils.FileWithLineNum())},
+	}, minLevel)
+}
+
+// ParamsFilter keeps gorm from writing the parameters into the logged
+// queries unless Config.LogParams is set
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if l.config.LogParams {
+		return sql, params
+	}
+	return sql, nil
+}
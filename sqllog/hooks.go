@@ -0,0 +1,45 @@
+package sqllog
+
+import (
+	"context"
+	"time"
+)
+
+type startKey struct{}
+
+// Hooks implement the Hooks and OnErrorer interfaces of
+// github.com/qustavo/sqlhooks/v2 for database/sql drivers:
+//
+//	sql.Register("postgres-logged", sqlhooks.Wrap(&pq.Driver{}, sqllog.NewHooks(config)))
+type Hooks struct {
+	config Config
+}
+
+// NewHooks logs the queries of a wrapped driver
+func NewHooks(config Config) *Hooks {
+	return &Hooks{config: config}
+}
+
+// Before stores the start of the query in the context
+func (h *Hooks) Before(ctx context.Context, query string, args ...interface{}) (context.Context, error) {
+	return context.WithValue(ctx, startKey{}, time.Now()), nil
+}
+
+// After logs the query
+func (h *Hooks) After(ctx context.Context, sql string, args ...interface{}) (context.Context, error) {
+	h.config.log(ctx, query{sql: sql, params: args, rows: -1, elapsed: elapsed(ctx)}, 0)
+	return ctx, nil
+}
+
+// OnError logs the failed query, returning err unchanged
+func (h *Hooks) OnError(ctx context.Context, err error, sql string, args ...interface{}) error {
+	h.config.log(ctx, query{sql: sql, params: args, rows: -1, elapsed: elapsed(ctx), err: err}, 0)
+	return err
+}
+
+func elapsed(ctx context.Context) time.Duration {
+	if start, ok := ctx.Value(startKey{}).(time.Time); ok {
+		return time.Since(start)
+	}
+	return 0
+}
@@ -0,0 +1,119 @@
+// Package sqllog logs SQL queries with package log: a gorm logger and hooks
+// for database/sql drivers wrapped with sqlhooks. Failed queries are logged
+// as errors, queries slower than the threshold as warnings and the bound
+// parameters are redacted unless asked for.
+package sqllog
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/olee12/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gorm.io/gorm"
+)
+
+// DefaultSlowThreshold is used when Config.SlowThreshold is zero
+const DefaultSlowThreshold = 200 * time.Millisecond
+
+// Config of the query loggers
+type Config struct {
+	// Logger writes the queries, it defaults to the logger of the query
+	// context, see log.FromContext
+	Logger *log.LogEntry
+	// SlowThreshold logs the queries taking longer at warn level, it defaults
+	// to DefaultSlowThreshold, a negative threshold disables the warnings
+	SlowThreshold time.Duration
+	// LogQueries logs every query at debug level, not only the failed and slow ones
+	LogQueries bool
+	// LogParams writes the bound parameters, they are written as
+	// log.SecretMask otherwise
+	LogParams bool
+	// IgnoreNotFound skips the errors for queries returning no rows,
+	// sql.ErrNoRows and gorm's ErrRecordNotFound
+	IgnoreNotFound bool
+}
+
+func (c Config) slowThreshold() time.Duration {
+	if c.SlowThreshold == 0 {
+		return DefaultSlowThreshold
+	}
+	return c.SlowThreshold
+}
+
+func isNotFound(err error) bool {
+	return errors.Is(err, sql.ErrNoRows) || errors.Is(err, gorm.ErrRecordNotFound)
+}
+
+// query is a finished query, rows is negative when unknown
+type query struct {
+	sql     string
+	params  []interface{}
+	rows    int64
+	elapsed time.Duration
+	err     error
+	// extra are added to the fields, e.g. the source of gorm queries
+	extra []zapcore.Field
+}
+
+// log writes the query at the level it deserves, minLevel skips the queries below
+func (c Config) log(ctx context.Context, q query, minLevel zapcore.Level) {
+	var level zapcore.Level
+	var msg string
+	switch {
+	case q.err != nil && !(c.IgnoreNotFound && isNotFound(q.err)):
+		level, msg = log.ErrorLevel, "query failed"
+	case c.slowThreshold() > 0 && q.elapsed > c.slowThreshold():
+		level, msg = log.WarnLevel, "slow query"
+	case c.LogQueries:
+		level, msg = log.DebugLevel, "query"
+	default:
+		return
+	}
+	if level < minLevel {
+		return
+	}
+
+	fields := make([]zapcore.Field, 0, 6+len(q.extra))
+	fields = append(fields, zap.String("sql", q.sql), zap.Duration(log.DurationKey, q.elapsed))
+	if q.rows >= 0 {
+		fields = append(fields, zap.Int64("rows", q.rows))
+	}
+	if len(q.params) > 0 {
+		fields = append(fields, c.paramsField(q.params))
+	}
+	if level == log.WarnLevel {
+		fields = append(fields, zap.Duration("threshold", c.slowThreshold()))
+	}
+	if q.err != nil {
+		fields = append(fields, zap.Error(q.err))
+	}
+	fields = append(fields, q.extra...)
+
+	le := c.Logger
+	if le == nil {
+		le = log.FromContext(ctx)
+	}
+	switch level {
+	case log.ErrorLevel:
+		le.ErrorCtx(ctx, msg, fields...)
+	case log.WarnLevel:
+		le.WarnCtx(ctx, msg, fields...)
+	default:
+		le.DebugCtx(ctx, msg, fields...)
+	}
+}
+
+func (c Config) paramsField(params []interface{}) zapcore.Field {
+	if c.LogParams {
+		return zap.Any("params", params)
+	}
+	masked := make([]string, len(params))
+	for i := range masked {
+		masked[i] = log.SecretMask
+	}
+	return zap.Strings("params", masked)
+}
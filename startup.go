@@ -0,0 +1,243 @@
+package log
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// StartupMessage is the message of the entry logged by LogStartup
+const StartupMessage = "starting"
+
+// ConfiguredMessage is the message of the entries announcing a configured logger
+const ConfiguredMessage = "logging configured"
+
+// StartupDetails selects what the startup entries report besides the output
+// settings, for support tickets
+type StartupDetails struct {
+	// Config adds the effective config as the config object, with the secrets
+	// redacted and the unset settings left out
+	Config bool
+	// Env adds the names of the environment variables and the values of the
+	// ones tuning the Go runtime as the env object, other values are left out
+	Env bool
+	// Limits adds GOMAXPROCS, the CPUs, the memory limit of the runtime and the
+	// open files limit as the limits object
+	Limits bool
+}
+
+// LogStartup logs an entry at info level describing the process, the module
+// and Go version, the host, pid and arguments, and the output settings of the
+// default logger, with the details selected by its Config.Startup and the
+// extra fields
+func LogStartup(extra Fields) {
+	Default().LogStartup(extra)
+}
+
+// LogStartup logs the startup entry of the package level LogStartup to le,
+// with the details selected by the config of le
+func (le *LogEntry) LogStartup(extra Fields) {
+	var config Config
+	if le.config != nil {
+		config = *le.config
+	}
+	module := ""
+	if info, ok := debug.ReadBuildInfo(); ok {
+		module = info.Main.Path
+	}
+	hostname, _ := os.Hostname()
+	var args []string
+	if len(os.Args) > 1 {
+		for _, arg := range os.Args[1:] {
+			args = append(args, maskSecrets(arg))
+		}
+	}
+	fields := []zapcore.Field{
+		zap.String("module", module),
+		zap.String("go_version", runtime.Version()),
+		zap.String("hostname", hostname),
+		zap.Int("pid", os.Getpid()),
+		zap.Strings("args", args),
+	}
+	fields = append(fields, startupFields(config)...)
+	le.infoLogger.Info(StartupMessage, appendFields(fields, extra, le.sortFields)...)
+}
+
+// startupFields returns the output settings of the config followed by the
+// details it selects
+func startupFields(config Config) []zapcore.Field {
+	fields := []zapcore.Field{
+		zap.Bool("fileLogging", config.FileLoggingEnabled),
+		zap.Bool("consoleLogging", config.ConsoleLoggingEnabled),
+		zap.Bool("caller", config.CallerEnabled),
+		zap.Int("callerSkip", config.CallerSkip),
+		zap.Bool("jsonLogOutput", config.EncodeLogsAsJson),
+		zap.String("logDirectory", config.Directory),
+		zap.Int("maxSizeMB", config.MaxSize),
+		zap.Int("maxBackups", config.MaxBackups),
+		zap.Int("maxAgeInDays", config.MaxAge),
+	}
+	if config.Startup.Config {
+		fields = append(fields, zap.Any("config", redactedConfig(config)))
+	}
+	if config.Startup.Env {
+		fields = append(fields, zap.Any("env", envSummary()))
+	}
+	if config.Startup.Limits {
+		fields = append(fields, zap.Any("limits", resourceLimits()))
+	}
+	return append(fields, schemaExemptField)
+}
+
+// secretSettings are the config fields never reported, at any depth
+var secretSettings = map[string]bool{
+	"SentryDSN":  true,
+	"Key":        true,
+	"WebhookURL": true,
+}
+
+// redactedConfig returns the exported settings of the config which are set,
+// by field name
+func redactedConfig(config Config) map[string]interface{} {
+	v, _ := redactedValue(reflect.ValueOf(config))
+	m, _ := v.(map[string]interface{})
+	if m == nil {
+		m = map[string]interface{}{}
+	}
+	// the info level is the zero value, it is reported anyway
+	m["Level"] = LevelName(config.Level)
+	return m
+}
+
+// redactedValue converts v to values zap encodes as JSON: structs to maps of
+// their set exported fields, writers and other interfaces to their type, and
+// strings with their secrets and URL passwords masked. Functions and channels
+// are dropped, like the zero values.
+func redactedValue(v reflect.Value) (interface{}, bool) {
+	if !v.IsValid() || v.IsZero() {
+		return nil, false
+	}
+	if f, ok := v.Interface().(*os.File); ok {
+		return f.Name(), true
+	}
+	switch v.Kind() {
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return nil, false
+	case reflect.Interface:
+		return fmt.Sprintf("%T", v.Interface()), true
+	case reflect.Pointer:
+		if v.Elem().Kind() != reflect.Struct {
+			return redactedValue(v.Elem())
+		}
+		if m, ok := redactedValue(v.Elem()); ok {
+			return m, true
+		}
+		return fmt.Sprintf("%T", v.Interface()), true
+	case reflect.String:
+		if s, ok := v.Interface().(fmt.Stringer); ok {
+			return redactedString(s.String()), true
+		}
+		return redactedString(v.String()), true
+	case reflect.Struct:
+		m := map[string]interface{}{}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if secretSettings[field.Name] {
+				if !v.Field(i).IsZero() {
+					m[field.Name] = SecretMask
+				}
+				continue
+			}
+			if fv, ok := redactedValue(v.Field(i)); ok {
+				m[field.Name] = fv
+			}
+		}
+		return m, len(m) > 0
+	case reflect.Slice, reflect.Array:
+		values := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if ev, ok := redactedValue(v.Index(i)); ok {
+				values = append(values, ev)
+			}
+		}
+		return values, len(values) > 0
+	case reflect.Map:
+		m := map[string]interface{}{}
+		iter := v.MapRange()
+		for iter.Next() {
+			if ev, ok := redactedValue(iter.Value()); ok {
+				m[fmt.Sprint(iter.Key().Interface())] = ev
+			}
+		}
+		return m, len(m) > 0
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String(), true
+	}
+	return v.Interface(), true
+}
+
+// redactedString masks the secrets of s and the password of URLs
+func redactedString(s string) string {
+	if u, err := url.Parse(s); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			masked := url.User(u.User.Username()).String() + ":" + SecretMask + "@"
+			s = strings.Replace(s, u.User.String()+"@", masked, 1)
+		}
+	}
+	return maskSecrets(s)
+}
+
+// runtimeEnv are the environment variables tuning the Go runtime, whose
+// values the env summary reports
+var runtimeEnv = []string{"GOGC", "GOMEMLIMIT", "GOMAXPROCS", "GODEBUG", "GOTRACEBACK", "TZ"}
+
+// envSummary returns the sorted names of the environment variables and the
+// values of the runtime ones which are set
+func envSummary() map[string]interface{} {
+	names := make([]string, 0, len(os.Environ()))
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	runtimeValues := map[string]string{}
+	for _, name := range runtimeEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			runtimeValues[name] = maskSecrets(value)
+		}
+	}
+	return map[string]interface{}{
+		"names":   names,
+		"runtime": runtimeValues,
+	}
+}
+
+// resourceLimits returns the limits of the runtime and the process
+func resourceLimits() map[string]interface{} {
+	limits := map[string]interface{}{
+		"gomaxprocs": runtime.GOMAXPROCS(0),
+		"num_cpu":    runtime.NumCPU(),
+	}
+	// a negative limit reads the current one, math.MaxInt64 means none
+	if memLimit := debug.SetMemoryLimit(-1); memLimit != math.MaxInt64 {
+		limits["gomemlimit"] = memLimit
+	}
+	for name, value := range processLimits() {
+		limits[name] = value
+	}
+	return limits
+}
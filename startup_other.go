@@ -0,0 +1,17 @@
+//go:build !windows
+
+package log
+
+import "syscall"
+
+// processLimits returns the open files limit of the process
+func processLimits() map[string]interface{} {
+	var nofile syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &nofile); err != nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"nofile_soft": nofile.Cur,
+		"nofile_hard": nofile.Max,
+	}
+}
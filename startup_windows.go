@@ -0,0 +1,8 @@
+//go:build windows
+
+package log
+
+// processLimits returns nothing, Windows has no rlimits
+func processLimits() map[string]interface{} {
+	return nil
+}
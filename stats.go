@@ -0,0 +1,105 @@
+package log
+
+import (
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// LoggerStats is a snapshot of the volume handled by a logger
+type LoggerStats struct {
+	// Entries is the number of entries written
+	Entries int64
+	// Bytes is the number of encoded bytes written
+	Bytes int64
+	// Dropped is the number of entries dropped by sampling
+	Dropped int64
+	// WriteTime is the total time spent encoding and writing entries
+	WriteTime time.Duration
+}
+
+// AvgWriteTime is the mean time spent encoding and writing one entry
+func (s LoggerStats) AvgWriteTime() time.Duration {
+	if s.Entries == 0 {
+		return 0
+	}
+	return s.WriteTime / time.Duration(s.Entries)
+}
+
+type loggerStats struct {
+	entries   atomic.Int64
+	bytes     atomic.Int64
+	dropped   atomic.Int64
+	writeTime atomic.Int64
+}
+
+func (s *loggerStats) snapshot() LoggerStats {
+	return LoggerStats{
+		Entries:   s.entries.Load(),
+		Bytes:     s.bytes.Load(),
+		Dropped:   s.dropped.Load(),
+		WriteTime: time.Duration(s.writeTime.Load()),
+	}
+}
+
+func (s *loggerStats) reset() {
+	s.entries.Store(0)
+	s.bytes.Store(0)
+	s.dropped.Store(0)
+	s.writeTime.Store(0)
+}
+
+func (s *loggerStats) samplerHook(_ zapcore.Entry, decision zapcore.SamplingDecision) {
+	if s != nil && decision&zapcore.LogDropped != 0 {
+		s.dropped.Add(1)
+	}
+}
+
+// statsCore times the encoding and writing of every entry
+type statsCore struct {
+	zapcore.Core
+	stats *loggerStats
+}
+
+func newStatsCore(core zapcore.Core, stats *loggerStats) zapcore.Core {
+	if stats == nil {
+		return core
+	}
+	return &statsCore{Core: core, stats: stats}
+}
+
+func (c *statsCore) With(fields []zapcore.Field) zapcore.Core {
+	return &statsCore{Core: c.Core.With(fields), stats: c.stats}
+}
+
+func (c *statsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *statsCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	start := time.Now()
+	err := c.Core.Write(ent, fields)
+	c.stats.writeTime.Add(int64(time.Since(start)))
+	c.stats.entries.Add(1)
+	return err
+}
+
+// countingWriteSyncer counts the bytes written to the underlying sink
+type countingWriteSyncer struct {
+	zapcore.WriteSyncer
+	stats *loggerStats
+}
+
+func newCountingWriteSyncer(ws zapcore.WriteSyncer, stats *loggerStats) zapcore.WriteSyncer {
+	return &countingWriteSyncer{WriteSyncer: ws, stats: stats}
+}
+
+func (w *countingWriteSyncer) Write(p []byte) (int, error) {
+	n, err := w.WriteSyncer.Write(p)
+	w.stats.bytes.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,176 @@
+package log
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// tail keeps the recent entries of every logger once TailHandler is called
+var tail atomic.Pointer[lineRing]
+
+// TailHandler serves the last n entries written by the loggers of this
+// package, encoded like their outputs, for a /debug/logs endpoint. The query
+// parameter n asks for fewer entries, follow=1 keeps streaming new entries
+// until the client goes away. The entries are kept in memory from the first
+// call on, the last call sets how many.
+func TailHandler(n int) http.Handler {
+	ring := installTailRing(n)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := n
+		if v, err := strconv.Atoi(r.URL.Query().Get("n")); err == nil && v >= 0 && v < limit {
+			limit = v
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		lines, seq, changed := ring.last(limit)
+		for _, line := range lines {
+			if _, err := w.Write(line); err != nil {
+				return
+			}
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok || r.URL.Query().Get("follow") == "" {
+			return
+		}
+		for {
+			flusher.Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-changed:
+			}
+			lines, seq, changed = ring.since(seq)
+			for _, line := range lines {
+				if _, err := w.Write(line); err != nil {
+					return
+				}
+			}
+		}
+	})
+}
+
+func installTailRing(n int) *lineRing {
+	return installRing(&tail, n)
+}
+
+// installRing stores a ring of n lines in p unless it already holds one of that size
+func installRing(p *atomic.Pointer[lineRing], n int) *lineRing {
+	if n <= 0 {
+		n = 1
+	}
+	for {
+		old := p.Load()
+		if old != nil && len(old.lines) == n {
+			return old
+		}
+		ring := newLineRing(n)
+		if p.CompareAndSwap(old, ring) {
+			return ring
+		}
+	}
+}
+
+// lineRing holds the last lines written, seq counts every line ever added
+type lineRing struct {
+	mu      sync.Mutex
+	lines   [][]byte
+	seq     int
+	changed chan struct{}
+}
+
+func newLineRing(n int) *lineRing {
+	return &lineRing{lines: make([][]byte, n)}
+}
+
+func (r *lineRing) add(line []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines[r.seq%len(r.lines)] = line
+	r.seq++
+	if r.changed != nil {
+		close(r.changed)
+		r.changed = nil
+	}
+}
+
+// wait returns the channel closed by the next add, made on demand so rings
+// nobody follows don't allocate one per line
+func (r *lineRing) wait() <-chan struct{} {
+	if r.changed == nil {
+		r.changed = make(chan struct{})
+	}
+	return r.changed
+}
+
+// last returns up to n of the latest lines, the sequence to continue from and
+// a channel closed when lines are added
+func (r *lineRing) last(n int) ([][]byte, int, <-chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.linesFrom(r.seq - n), r.seq, r.wait()
+}
+
+// since returns the lines added after seq, like last
+func (r *lineRing) since(seq int) ([][]byte, int, <-chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.linesFrom(seq), r.seq, r.wait()
+}
+
+func (r *lineRing) linesFrom(seq int) [][]byte {
+	if oldest := r.seq - len(r.lines); seq < oldest {
+		seq = oldest
+	}
+	if seq < 0 {
+		seq = 0
+	}
+	lines := make([][]byte, 0, r.seq-seq)
+	for ; seq < r.seq; seq++ {
+		lines = append(lines, r.lines[seq%len(r.lines)])
+	}
+	return lines
+}
+
+// tailCore copies the entries into the tail ring when one is installed. It
+// keeps the With fields to encode them only when needed.
+type tailCore struct {
+	zapcore.Core
+	enc     zapcore.Encoder
+	context []zapcore.Field
+}
+
+func newTailCore(core zapcore.Core, enc zapcore.Encoder) zapcore.Core {
+	return &tailCore{Core: core, enc: enc}
+}
+
+func (c *tailCore) With(fields []zapcore.Field) zapcore.Core {
+	context := make([]zapcore.Field, 0, len(c.context)+len(fields))
+	context = append(context, c.context...)
+	return &tailCore{Core: c.Core.With(fields), enc: c.enc, context: append(context, fields...)}
+}
+
+func (c *tailCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *tailCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	err := c.Core.Write(ent, fields)
+	if ring := tail.Load(); ring != nil && !hasBelowLevelMarker(fields) {
+		enc := c.enc.Clone()
+		for _, f := range c.context {
+			f.AddTo(enc)
+		}
+		if buf, encErr := enc.EncodeEntry(ent, fields); encErr == nil {
+			ring.add(append([]byte(nil), buf.Bytes()...))
+			buf.Free()
+		}
+	}
+	return err
+}
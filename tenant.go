@@ -0,0 +1,140 @@
+package log
+
+import (
+	"container/list"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// TenantIDKey is the field name of the tenant stamped by TenantLoggers
+const TenantIDKey = "tenant_id"
+
+// TenantLoggers hands out loggers writing each tenant into its own directory
+// below the base config's Directory, all with the base rotation policy. At
+// most maxOpen tenants keep their files open, the least recently used one is
+// closed when another tenant writes.
+type TenantLoggers struct {
+	mu      sync.Mutex
+	base    Config
+	maxOpen int
+	// lru holds the tenants whose files may be open, most recently used first
+	lru     *list.List
+	tenants map[string]*tenantLogger
+}
+
+type tenantLogger struct {
+	id     string
+	logger *LogEntry
+	// el is the element of the tenant in lru, nil once its files are closed
+	el *list.Element
+}
+
+// NewTenantLoggers returns a manager for the base config. maxOpen <= 0 means no cap.
+func NewTenantLoggers(base Config, maxOpen int) *TenantLoggers {
+	base.FileLoggingEnabled = true
+	return &TenantLoggers{
+		base:    base,
+		maxOpen: maxOpen,
+		lru:     list.New(),
+		tenants: map[string]*tenantLogger{},
+	}
+}
+
+// GetLogger returns the logger of the tenant, the same one for every call. A
+// logger whose files were closed for the cap keeps working, its next write
+// reopens them and closes the ones of the least recently used tenant.
+func (t *TenantLoggers) GetLogger(tenantID string) *LogEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if tl, ok := t.tenants[tenantID]; ok {
+		return tl.logger
+	}
+
+	config := t.base
+	config.Directory = filepath.Join(t.base.Directory, tenantDirectory(tenantID))
+	built := buildLogEntry(config)
+	tl := &tenantLogger{id: tenantID}
+	wrap := zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &tenantCore{Core: core, tenants: t, tenant: tl}
+	})
+	// the files are closed through built, the loggers derived from it share them
+	tl.logger = built.derive(built.infoLogger.WithOptions(wrap), built.errorLogger.WithOptions(wrap)).WithFields(Fields{TenantIDKey: tenantID})
+	t.tenants[tenantID] = tl
+	return tl.logger
+}
+
+// Close closes the files of every tenant, the loggers reopen them on their
+// next write
+func (t *TenantLoggers) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var err error
+	for t.lru.Len() > 0 {
+		if closeErr := t.evict(t.lru.Back()); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// touch makes the tenant the most recently used one before it writes, closing
+// the files of the least recently used tenants beyond maxOpen
+func (t *TenantLoggers) touch(tl *tenantLogger) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if tl.el != nil {
+		t.lru.MoveToFront(tl.el)
+		return
+	}
+	tl.el = t.lru.PushFront(tl)
+	for t.maxOpen > 0 && t.lru.Len() > t.maxOpen {
+		_ = t.evict(t.lru.Back())
+	}
+}
+
+// evict closes the files of the tenant, t.mu must be held
+func (t *TenantLoggers) evict(el *list.Element) error {
+	tl := t.lru.Remove(el).(*tenantLogger)
+	tl.el = nil
+	return tl.logger.Close()
+}
+
+// tenantCore counts the tenant as using its files for the entries it writes
+type tenantCore struct {
+	zapcore.Core
+	tenants *TenantLoggers
+	tenant  *tenantLogger
+}
+
+func (c *tenantCore) With(fields []zapcore.Field) zapcore.Core {
+	return &tenantCore{Core: c.Core.With(fields), tenants: c.tenants, tenant: c.tenant}
+}
+
+func (c *tenantCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Core.Enabled(ent.Level) {
+		c.tenants.touch(c.tenant)
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// tenantDirectory maps a tenant id to a single safe path element
+func tenantDirectory(tenantID string) string {
+	name := []byte(tenantID)
+	for i, c := range name {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.') {
+			name[i] = '_'
+		}
+	}
+	dir := strings.Trim(string(name), ".")
+	if dir == "" {
+		dir = "_"
+	}
+	return dir
+}
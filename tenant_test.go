@@ -0,0 +1,66 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openTenants returns the tenant directories below dir with files open by
+// the process
+func openTenants(t *testing.T, dir string) map[string]bool {
+	t.Helper()
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("no /proc/self/fd to count the open files")
+	}
+	open := map[string]bool{}
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join("/proc/self/fd", fd.Name()))
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(dir, target); err == nil && !strings.HasPrefix(rel, "..") {
+			open[strings.Split(rel, string(filepath.Separator))[0]] = true
+		}
+	}
+	return open
+}
+
+func TestTenantLoggersMaxOpen(t *testing.T) {
+	dir := t.TempDir()
+	tenants := NewTenantLoggers(Config{Level: InfoLevel, Directory: dir, Filename: "app"}, 1)
+	defer tenants.Close()
+
+	a := tenants.GetLogger("a")
+	b := tenants.GetLogger("b")
+	for i, step := range []struct {
+		logger *LogEntry
+		tenant string
+	}{
+		{a, "a"},
+		{b, "b"},
+		// a was closed for b and reopens its file
+		{a, "a"},
+		{a, "a"},
+		{b, "b"},
+	} {
+		step.logger.Info("entry")
+		open := openTenants(t, dir)
+		if len(open) != 1 || !open[step.tenant] {
+			t.Errorf("step %d: tenants with open files %v, want only %s", i, open, step.tenant)
+		}
+	}
+
+	if tenants.GetLogger("a") != a {
+		t.Error("GetLogger returned another logger for a closed tenant")
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "a", "app_info.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(content), `entry`); n != 3 {
+		t.Errorf("%d entries in the file of a, want 3:\n%s", n, content)
+	}
+}
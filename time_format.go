@@ -0,0 +1,130 @@
+package log
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Named presets of Config.TimeFormat, any other value is a time.Format layout
+const (
+	TimeFormatRFC3339     = "RFC3339"
+	TimeFormatRFC3339Nano = "RFC3339Nano"
+	TimeFormatISO8601     = "ISO8601"
+	// TimeFormatEpoch writes the time as a number of seconds since the Unix epoch
+	TimeFormatEpoch = "epoch"
+	// TimeFormatEpochMillis writes the time as a number of milliseconds since the Unix epoch
+	TimeFormatEpochMillis = "epoch-millis"
+	// TimeFormatEpochMicros writes the time as a number of microseconds since the Unix epoch
+	TimeFormatEpochMicros = "epoch-micros"
+	// TimeFormatEpochNanos writes the time as a number of nanoseconds since the Unix epoch
+	TimeFormatEpochNanos = "epoch-nanos"
+)
+
+// epochEncoders are the numeric presets, for pipelines storing timestamps as numbers
+var epochEncoders = map[string]zapcore.TimeEncoder{
+	TimeFormatEpoch:       EpochSecondsTimeEncoder,
+	TimeFormatEpochMillis: EpochMillisTimeEncoder,
+	TimeFormatEpochMicros: EpochMicrosTimeEncoder,
+	TimeFormatEpochNanos:  EpochNanosTimeEncoder,
+}
+
+var timeLayouts = map[string]string{
+	TimeFormatRFC3339:     time.RFC3339,
+	TimeFormatRFC3339Nano: time.RFC3339Nano,
+	TimeFormatISO8601:     "2006-01-02T15:04:05.000Z0700",
+}
+
+// EpochSecondsTimeEncoder serializes a time.Time to the whole seconds since the Unix epoch
+func EpochSecondsTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendInt64(t.Unix())
+}
+
+// EpochMillisTimeEncoder serializes a time.Time to the milliseconds since the Unix epoch
+func EpochMillisTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendInt64(t.UnixMilli())
+}
+
+// EpochMicrosTimeEncoder serializes a time.Time to the microseconds since the Unix epoch
+func EpochMicrosTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendInt64(t.UnixMicro())
+}
+
+// EpochNanosTimeEncoder serializes a time.Time to the nanoseconds since the Unix epoch
+func EpochNanosTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendInt64(t.UnixNano())
+}
+
+// newTimeEncoder returns the encoder for Config.TimeFormat in Config.TimeZone.
+// Without a format the JSON and console encodings keep their short layouts.
+func newTimeEncoder(config Config) (zapcore.TimeEncoder, error) {
+	loc, err := timeLocation(config.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	var encoder zapcore.TimeEncoder
+	switch format := config.TimeFormat; {
+	case format == "" && config.EncodeLogsAsJson:
+		encoder = ShortTimeEncoder
+	case format == "":
+		encoder = ConsoleLogTimeEncoder
+	case epochEncoders[format] != nil:
+		encoder = epochEncoders[format]
+	case timeLayouts[format] != "":
+		encoder = zapcore.TimeEncoderOfLayout(timeLayouts[format])
+	default:
+		encoder = zapcore.TimeEncoderOfLayout(format)
+	}
+
+	if loc == nil || epochEncoders[config.TimeFormat] != nil {
+		return encoder, nil
+	}
+	return func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		encoder(t.In(loc), enc)
+	}, nil
+}
+
+// timeLocation loads the zone, nil keeps the local time of the entries
+func timeLocation(zone string) (*time.Location, error) {
+	switch zone {
+	case "", "Local":
+		return nil, nil
+	case "UTC":
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return nil, fmt.Errorf("log time zone: %w", err)
+	}
+	return loc, nil
+}
+
+// ParseEntryTime parses the time of an encoded entry written with the default
+// layouts, the presets or epoch numbers. Times without a zone are local.
+func ParseEntryTime(s string) (time.Time, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return epochTime(n), nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05.000", "2006-01-02 15:04:05.000", time.RFC3339Nano, timeLayouts[TimeFormatISO8601]} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown log time format %q", s)
+}
+
+// epochTime guesses the unit of an epoch number from its magnitude
+func epochTime(n int64) time.Time {
+	switch {
+	case n < 1e11:
+		return time.Unix(n, 0)
+	case n < 1e14:
+		return time.UnixMilli(n)
+	case n < 1e17:
+		return time.UnixMicro(n)
+	}
+	return time.Unix(0, n)
+}
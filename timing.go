@@ -0,0 +1,48 @@
+package log
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DurationKey holds the time an operation took
+	DurationKey = "duration"
+	// SpanKey names the operation timed by Span
+	SpanKey = "span"
+)
+
+// Timed returns a function logging msg at info level with the time since
+// Timed was called:
+//
+//	defer logger.Timed("loaded config")()
+func (le *LogEntry) Timed(msg string) func() {
+	start := time.Now()
+	return func() {
+		le.infoLogger.Info(msg, zap.Duration(DurationKey, time.Since(start)))
+	}
+}
+
+// Timed is Timed of the default logger
+func Timed(msg string) func() {
+	start := time.Now()
+	return func() {
+		Default().infoLogger.Info(msg, zap.Duration(DurationKey, time.Since(start)))
+	}
+}
+
+// Span logs the start of the named operation at debug level with the logger
+// of FromContext(ctx), and returns a function logging its end at info level
+// with the duration, lightweight timing without a tracing stack:
+//
+//	defer log.Span(ctx, "charge card")()
+func Span(ctx context.Context, name string) func() {
+	logger := FromContext(ctx)
+	start := time.Now()
+	logger.infoLogger.Debug(name+" started", zap.String(SpanKey, name))
+	return func() {
+		logger.infoLogger.Info(name+" finished", zap.String(SpanKey, name), zap.Duration(DurationKey, time.Since(start)))
+	}
+}
@@ -0,0 +1,91 @@
+package log
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// TraceIDKey is the field name of the trace id added by trace correlation
+	TraceIDKey = "trace_id"
+	// SpanIDKey is the field name of the span id added by trace correlation
+	SpanIDKey = "span_id"
+	// SampledKey is the field name of the trace sampling decision. Loggers
+	// carrying sampled=true bypass Config.Sampling, and Level with
+	// Config.SampledTraces.
+	SampledKey = "sampled"
+)
+
+var traceKey = key(2)
+
+// TraceContext identifies the trace a record belongs to
+type TraceContext struct {
+	TraceID string
+	SpanID  string
+	Sampled bool
+}
+
+// ContextWithTrace stores the trace in the context so FromContext can correlate
+// records with it
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceKey, tc)
+}
+
+// TraceFromContext returns the trace stored by ContextWithTrace
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceKey).(TraceContext)
+	return tc, ok
+}
+
+// TraceExtractor returns the trace of the span a tracing library keeps in
+// the context
+type TraceExtractor func(ctx context.Context) (TraceContext, bool)
+
+var (
+	traceExtractorsMu sync.RWMutex
+	traceExtractors   []TraceExtractor
+)
+
+// RegisterTraceExtractor adds an extractor used by trace correlation for the
+// contexts without a trace stored by ContextWithTrace, e.g. otellog.SpanTrace
+// for the OpenTelemetry spans. The first extractor finding a trace wins.
+func RegisterTraceExtractor(extractor TraceExtractor) {
+	traceExtractorsMu.Lock()
+	defer traceExtractorsMu.Unlock()
+	traceExtractors = append(traceExtractors, extractor)
+}
+
+// contextTrace returns the trace stored by ContextWithTrace, or else the one
+// found by the registered extractors
+func contextTrace(ctx context.Context) (TraceContext, bool) {
+	if tc, ok := TraceFromContext(ctx); ok {
+		return tc, true
+	}
+	traceExtractorsMu.RLock()
+	defer traceExtractorsMu.RUnlock()
+	for _, extractor := range traceExtractors {
+		if tc, ok := extractor(ctx); ok {
+			return tc, true
+		}
+	}
+	return TraceContext{}, false
+}
+
+// WithTrace returns a child logger which stamps the trace on every record
+func (le *LogEntry) WithTrace(tc TraceContext) *LogEntry {
+	return le.with(traceFields(tc))
+}
+
+func traceFields(tc TraceContext) []zapcore.Field {
+	fields := make([]zapcore.Field, 0, 3)
+	if tc.TraceID != "" {
+		fields = append(fields, zap.String(TraceIDKey, tc.TraceID))
+	}
+	if tc.SpanID != "" {
+		fields = append(fields, zap.String(SpanIDKey, tc.SpanID))
+	}
+	return append(fields, zap.Bool(SampledKey, tc.Sampled))
+}
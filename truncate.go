@@ -0,0 +1,125 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// TruncatedKey marks the entries of which a value was truncated
+const TruncatedKey = "truncated"
+
+// ellipsis ends the truncated values
+const ellipsis = "…"
+
+// truncateCore shortens the messages and the string, binary, error,
+// stringer and reflected values of the fields exceeding the limits
+type truncateCore struct {
+	zapcore.Core
+	maxField   int
+	maxMessage int
+	// marked is set once the With fields carry the truncated marker
+	marked bool
+}
+
+func newTruncateCore(core zapcore.Core, maxField, maxMessage int) zapcore.Core {
+	if maxField <= 0 && maxMessage <= 0 {
+		return core
+	}
+	return &truncateCore{Core: core, maxField: maxField, maxMessage: maxMessage}
+}
+
+func (c *truncateCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	fields, truncated := c.truncateFields(fields)
+	if truncated && !c.marked {
+		fields = append(fields, zap.Bool(TruncatedKey, true))
+		clone.marked = true
+	}
+	clone.Core = c.Core.With(fields)
+	return &clone
+}
+
+func (c *truncateCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *truncateCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var truncated bool
+	if c.maxMessage > 0 && len(ent.Message) > c.maxMessage {
+		ent.Message = truncateString(ent.Message, c.maxMessage)
+		truncated = true
+	}
+	fields, fieldsTruncated := c.truncateFields(fields)
+	if (truncated || fieldsTruncated) && !c.marked {
+		fields = append(fields[:len(fields):len(fields)], zap.Bool(TruncatedKey, true))
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// truncateFields returns fields with the oversized values truncated, on a
+// copy when any is
+func (c *truncateCore) truncateFields(fields []zapcore.Field) ([]zapcore.Field, bool) {
+	if c.maxField <= 0 {
+		return fields, false
+	}
+	truncated := false
+	for i, f := range fields {
+		short, ok := c.truncateField(f)
+		if !ok {
+			continue
+		}
+		if !truncated {
+			fields = append([]zapcore.Field(nil), fields...)
+			truncated = true
+		}
+		fields[i] = short
+	}
+	return fields, truncated
+}
+
+func (c *truncateCore) truncateField(f zapcore.Field) (zapcore.Field, bool) {
+	var s string
+	switch f.Type {
+	case zapcore.StringType:
+		s = f.String
+	case zapcore.ByteStringType, zapcore.BinaryType:
+		b, _ := f.Interface.([]byte)
+		s = string(b)
+	case zapcore.StringerType:
+		if v, ok := f.Interface.(fmt.Stringer); ok {
+			s = v.String()
+		}
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok {
+			s = err.Error()
+		}
+	case zapcore.ReflectType:
+		b, err := json.Marshal(f.Interface)
+		if err != nil {
+			return f, false
+		}
+		s = string(b)
+	default:
+		return f, false
+	}
+	if len(s) <= c.maxField {
+		return f, false
+	}
+	return zap.String(f.Key, truncateString(s, c.maxField)), true
+}
+
+// truncateString cuts s to at most max bytes on a rune boundary and appends
+// the ellipsis
+func truncateString(s string, max int) string {
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max] + ellipsis
+}
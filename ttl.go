@@ -0,0 +1,65 @@
+package log
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// TTLClassKey is the field name of the retention class of a record
+const TTLClassKey = "ttl_class"
+
+// TTL tags a record with a retention class such as "debug-7d" or "audit-7y",
+// so retention and archiving can follow the data-governance category of the
+// record rather than the file it was written to
+func TTL(class string) Field {
+	return zap.String(TTLClassKey, class)
+}
+
+// ParseTTLClass returns the retention of a class named "<category>-<n><unit>",
+// the unit being h (hours), d (days), w (weeks), m (30 day months) or y (365 day years)
+func ParseTTLClass(class string) (time.Duration, error) {
+	i := strings.LastIndexByte(class, '-')
+	if i < 0 || len(class)-i < 3 {
+		return 0, fmt.Errorf("ttl class %q has no <n><unit> suffix", class)
+	}
+	spec := class[i+1:]
+	n, err := strconv.Atoi(spec[:len(spec)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("ttl class %q has an invalid count", class)
+	}
+
+	day := 24 * time.Hour
+	var unit time.Duration
+	switch spec[len(spec)-1] {
+	case 'h':
+		unit = time.Hour
+	case 'd':
+		unit = day
+	case 'w':
+		unit = 7 * day
+	case 'm':
+		unit = 30 * day
+	case 'y':
+		unit = 365 * day
+	default:
+		return 0, fmt.Errorf("ttl class %q has an unknown unit", class)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// ttlMaxAge returns the MaxAge in days of the rolled files of a logger with
+// the TTL class, an explicit MaxAge wins
+func ttlMaxAge(config Config) int {
+	if config.MaxAge > 0 || config.TTLClass == "" {
+		return config.MaxAge
+	}
+	d, err := ParseTTLClass(config.TTLClass)
+	if err != nil {
+		return config.MaxAge
+	}
+	return int((d + 24*time.Hour - 1) / (24 * time.Hour))
+}
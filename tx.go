@@ -0,0 +1,163 @@
+package log
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// TxLogger buffers its records in memory until the transaction is committed
+// or rolled back. It logs like any LogEntry.
+type TxLogger struct {
+	*LogEntry
+	buf *txBuffer
+}
+
+// txRecord is an entry accepted by the Check of the wrapped cores, or one
+// the wrapping cores wrote directly when ce is nil
+type txRecord struct {
+	core   zapcore.Core
+	ce     *zapcore.CheckedEntry
+	ent    zapcore.Entry
+	fields []zapcore.Field
+}
+
+type txBuffer struct {
+	mu      sync.Mutex
+	records []txRecord
+}
+
+// BeginTx returns a logger derived from FromContext(ctx) whose records are only
+// written on Commit. Panic and Fatal records are written right away together
+// with everything buffered before them, since the process is going down.
+func BeginTx(ctx context.Context) *TxLogger {
+	logEntry, buf := newBufferedLogEntry(FromContext(ctx), DPanicLevel, DPanicLevel)
+	return &TxLogger{LogEntry: logEntry, buf: buf}
+}
+
+// Commit writes the buffered records
+func (tx *TxLogger) Commit() error {
+	return tx.buf.flush(false)
+}
+
+// Rollback drops the buffered records
+func (tx *TxLogger) Rollback() {
+	tx.buf.take()
+}
+
+// RollbackDebug writes the buffered records at debug level, so they are kept
+// only when debug logging is enabled
+func (tx *TxLogger) RollbackDebug() error {
+	return tx.buf.flush(true)
+}
+
+func (b *txBuffer) add(rec txRecord) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.records = append(b.records, rec)
+}
+
+func (b *txBuffer) take() []txRecord {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	records := b.records
+	b.records = nil
+	return records
+}
+
+func (b *txBuffer) flush(asDebug bool) error {
+	var firstErr error
+	for _, rec := range b.take() {
+		if asDebug {
+			if !rec.core.Enabled(DebugLevel) {
+				continue
+			}
+			rec.ent.Level = DebugLevel
+		}
+		if rec.ce != nil {
+			// the cores which accepted the entry write it, errors go to the
+			// ErrorOutput of the logger
+			rec.ce.Entry.Level = rec.ent.Level
+			rec.ce.Write(rec.fields...)
+			continue
+		}
+		if err := rec.core.Write(rec.ent, rec.fields); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// newBufferedLogEntry derives a logger from base which buffers entries below
+// writeLevel. Entries at writeLevel or above are written right away, at
+// flushLevel or above after writing everything buffered before them.
+func newBufferedLogEntry(base *LogEntry, writeLevel, flushLevel zapcore.Level) (*LogEntry, *txBuffer) {
+	buf := &txBuffer{}
+	wrap := zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &bufferCore{Core: core, buf: buf, writeLevel: writeLevel, flushLevel: flushLevel}
+	})
+	return base.derive(base.infoLogger.WithOptions(wrap), base.errorLogger.WithOptions(wrap)), buf
+}
+
+// bufferCore captures entries into a buffer instead of writing them
+type bufferCore struct {
+	zapcore.Core
+	buf        *txBuffer
+	writeLevel zapcore.Level
+	flushLevel zapcore.Level
+}
+
+func (c *bufferCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.Core = c.Core.With(fields)
+	return &clone
+}
+
+// Check passes the entry through the Check of the wrapped cores, so sampling
+// and the levels of the reporters apply to the buffered entries too, and
+// holds the result until the buffer is flushed
+func (c *bufferCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level >= c.writeLevel && ent.Level < c.flushLevel {
+		return c.Core.Check(ent, ce)
+	}
+	if c.Enabled(ent.Level) {
+		return addChecked(ent, ce, c.Core, c)
+	}
+	return ce
+}
+
+func (c *bufferCore) writeChecked(ce *zapcore.CheckedEntry, fields []zapcore.Field) {
+	if ce.Entry.Level < c.writeLevel {
+		c.buf.add(txRecord{
+			core:   c.Core,
+			ce:     ce,
+			ent:    ce.Entry,
+			fields: append([]zapcore.Field(nil), fields...),
+		})
+		return
+	}
+	// like the one of ce, the write errors of the checked entries go to the
+	// ErrorOutput of the logger
+	_ = c.buf.flush(false)
+	ce.Write(fields...)
+}
+
+// Write is reached for the entries written directly by the wrapping cores
+func (c *bufferCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ent.Level < c.writeLevel {
+		c.buf.add(txRecord{
+			core:   c.Core,
+			ent:    ent,
+			fields: append([]zapcore.Field(nil), fields...),
+		})
+		return nil
+	}
+	var err error
+	if ent.Level >= c.flushLevel {
+		err = c.buf.flush(false)
+	}
+	return multierr.Append(err, c.Core.Write(ent, fields))
+}
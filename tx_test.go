@@ -0,0 +1,125 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// levelReporter records the levels of the reported entries
+type levelReporter struct {
+	mu     sync.Mutex
+	levels []string
+}
+
+func (r *levelReporter) Report(entry zapcore.Entry, _ map[string]interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.levels = append(r.levels, entry.Level.String())
+}
+
+func (r *levelReporter) reported() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.levels...)
+}
+
+// newReportingLogger writes JSON to the returned buffer and reports to the
+// reporter like a logger built by NewLogEntry
+func newReportingLogger(config Config, reporter ErrorReporter) (*LogEntry, *bytes.Buffer) {
+	var buf bytes.Buffer
+	config.Level = InfoLevel
+	config.EncodeLogsAsJson = true
+	if reporter != nil {
+		config.ErrorReporters = []ErrorReporter{reporter}
+		config.reporters = newReporterCore(config)
+	}
+	return NewWithWriter(config, &buf), &buf
+}
+
+// bufferedLoggers are the loggers written through a buffer, with the call
+// writing the held entries
+var bufferedLoggers = []struct {
+	name string
+	log  func(le *LogEntry, write func(le *LogEntry))
+}{
+	{
+		name: "plain",
+		log:  func(le *LogEntry, write func(le *LogEntry)) { write(le) },
+	},
+	{
+		name: "committed tx",
+		log: func(le *LogEntry, write func(le *LogEntry)) {
+			tx := BeginTx(le.ContextWithLogger(context.Background()))
+			write(tx.LogEntry)
+			_ = tx.Commit()
+		},
+	},
+	{
+		name: "failed span",
+		log: func(le *LogEntry, write func(le *LogEntry)) {
+			_, sl := StartSpanLogger(le.ContextWithLogger(context.Background()))
+			write(sl.LogEntry)
+			_ = sl.End(errors.New("failed"))
+		},
+	},
+}
+
+func TestTxLoggerSampling(t *testing.T) {
+	for _, tt := range bufferedLoggers {
+		t.Run(tt.name, func(t *testing.T) {
+			le, buf := newReportingLogger(Config{Sampling: &SamplingConfig{Tick: time.Minute, Initial: 1}}, nil)
+			tt.log(le, func(le *LogEntry) {
+				for i := 0; i < 5; i++ {
+					le.Info("repeated")
+				}
+			})
+			if n := strings.Count(buf.String(), "repeated"); n != 1 {
+				t.Errorf("%d entries written, want 1 sampled:\n%s", n, buf.String())
+			}
+		})
+	}
+}
+
+func TestTxLoggerReporters(t *testing.T) {
+	for _, tt := range bufferedLoggers {
+		t.Run(tt.name, func(t *testing.T) {
+			reporter := &levelReporter{}
+			le, buf := newReportingLogger(Config{}, reporter)
+			tt.log(le, func(le *LogEntry) {
+				le.Info("info")
+				le.Warn("warn")
+				le.Error("error")
+			})
+			if got, want := reporter.reported(), []string{"error"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("reported %v, want %v", got, want)
+			}
+			// the span logger writes warn entries before the held ones
+			got := loggedMessages(buf.String())
+			sort.Strings(got)
+			if want := []string{"error", "info", "warn"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("wrote %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// loggedMessages returns the messages of the JSON entries written to out
+func loggedMessages(out string) []string {
+	var msgs []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if i := strings.Index(line, `"msg":"`); i >= 0 {
+			msg := line[i+len(`"msg":"`):]
+			msgs = append(msgs, msg[:strings.IndexByte(msg, '"')])
+		}
+	}
+	return msgs
+}
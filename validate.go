@@ -0,0 +1,104 @@
+package log
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"go.uber.org/multierr"
+)
+
+// Validate reports the settings of the config which make no sense, every
+// problem found as a separate error, see multierr.Errors. Configure refuses
+// invalid configs, NewLogEntry logs the errors and carries on.
+func (c Config) Validate() error {
+	var err error
+	invalid := func(format string, args ...interface{}) {
+		err = multierr.Append(err, fmt.Errorf("log config: "+format, args...))
+	}
+
+	if !knownLevel(c.Level) {
+		invalid("unknown level %d", c.Level)
+	}
+	if c.ConsoleLevel != nil && !knownLevel(*c.ConsoleLevel) {
+		invalid("unknown ConsoleLevel %d", *c.ConsoleLevel)
+	}
+	if c.FileLevel != nil && !knownLevel(*c.FileLevel) {
+		invalid("unknown FileLevel %d", *c.FileLevel)
+	}
+	if c.CallerSkip < 0 {
+		invalid("CallerSkip %d is negative", c.CallerSkip)
+	}
+	if c.FileLoggingEnabled {
+		if c.Directory == "" && c.BaseDir == "" {
+			// an empty Directory alone logs to BaseDir
+			invalid("file logging is enabled without a Directory or BaseDir")
+		}
+		if c.Filename != "" && c.Filename != filepath.Base(c.Filename) {
+			invalid("Filename %q must not contain a directory, use Directory", c.Filename)
+		}
+	}
+	if c.MaxSize < 0 {
+		invalid("MaxSize %d is negative", c.MaxSize)
+	}
+	if c.MaxBackups < 0 {
+		invalid("MaxBackups %d is negative", c.MaxBackups)
+	}
+	if c.MaxAge < 0 {
+		invalid("MaxAge %d is negative", c.MaxAge)
+	}
+	if c.CoalesceWindow < 0 {
+		invalid("CoalesceWindow %s is negative", c.CoalesceWindow)
+	}
+	if c.MaxFieldBytes < 0 {
+		invalid("MaxFieldBytes %d is negative", c.MaxFieldBytes)
+	}
+	if c.MaxMessageBytes < 0 {
+		invalid("MaxMessageBytes %d is negative", c.MaxMessageBytes)
+	}
+	if c.CrashBuffer < 0 {
+		invalid("CrashBuffer %d is negative", c.CrashBuffer)
+	}
+	if !c.ConsoleEncoding.valid() {
+		invalid("unknown ConsoleEncoding %q", c.ConsoleEncoding)
+	}
+	if !c.FileEncoding.valid() {
+		invalid("unknown FileEncoding %q", c.FileEncoding)
+	}
+	if c.ContainerMode && c.CLIOutput {
+		invalid("ContainerMode and CLIOutput exclude each other")
+	}
+	if c.DuplicateKeys < DuplicateKeysAllow || c.DuplicateKeys > DuplicateKeysRename {
+		invalid("unknown DuplicateKeys mode %d", c.DuplicateKeys)
+	}
+	if _, timeErr := newTimeEncoder(c); timeErr != nil {
+		invalid("time format: %w", timeErr)
+	}
+	if c.TTLClass != "" {
+		if _, ttlErr := ParseTTLClass(c.TTLClass); ttlErr != nil {
+			invalid("TTLClass: %w", ttlErr)
+		}
+	}
+	if c.SentryDSN != "" {
+		if _, dsnErr := parseSentryDSN(c.SentryDSN); dsnErr != nil {
+			invalid("SentryDSN: %w", dsnErr)
+		}
+	}
+	if c.SentrySampleRate < 0 || c.SentrySampleRate > 1 {
+		invalid("SentrySampleRate %v is not between 0 and 1", c.SentrySampleRate)
+	}
+	for i, sink := range c.ExtraInfoSinks {
+		if sink == nil {
+			invalid("ExtraInfoSinks[%d] is nil", i)
+		}
+	}
+	for i, sink := range c.ExtraErrorSinks {
+		if sink == nil {
+			invalid("ExtraErrorSinks[%d] is nil", i)
+		}
+	}
+	return err
+}
+
+func knownLevel(l Level) bool {
+	return l >= TraceLevel && l <= FatalLevel || l == NoticeLevel
+}
@@ -0,0 +1,104 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var wideEventKey = key(3)
+
+// WideEventEntry accumulates attributes during a request and emits them as a
+// single canonical record when the request ends
+type WideEventEntry struct {
+	mu      sync.Mutex
+	ctx     context.Context
+	msg     string
+	start   time.Time
+	keys    []string
+	values  map[string]interface{}
+	err     error
+	emitted bool
+}
+
+// StartWideEvent attaches a new wide event to the context. Handlers retrieve it
+// with WideEvent(ctx) and the owner of the request calls Emit once it ends.
+func StartWideEvent(ctx context.Context, msg string) (context.Context, *WideEventEntry) {
+	we := &WideEventEntry{
+		msg:    msg,
+		start:  time.Now(),
+		values: map[string]interface{}{},
+	}
+	ctx = context.WithValue(ctx, wideEventKey, we)
+	we.ctx = ctx
+	return ctx, we
+}
+
+// WideEvent returns the wide event started for the context, or nil. All
+// methods are safe to call on a nil *WideEventEntry so handlers need not check.
+func WideEvent(ctx context.Context) *WideEventEntry {
+	we, _ := ctx.Value(wideEventKey).(*WideEventEntry)
+	return we
+}
+
+// Set records an attribute, overwriting an earlier value with the same key
+func (we *WideEventEntry) Set(k string, v interface{}) {
+	if we == nil {
+		return
+	}
+	we.mu.Lock()
+	defer we.mu.Unlock()
+	if _, ok := we.values[k]; !ok {
+		we.keys = append(we.keys, k)
+	}
+	we.values[k] = v
+}
+
+// SetFields records all the fields as attributes
+func (we *WideEventEntry) SetFields(fields Fields) {
+	for k, v := range fields {
+		we.Set(k, v)
+	}
+}
+
+// SetError marks the request as failed, the record is then emitted at error level
+func (we *WideEventEntry) SetError(err error) {
+	if we == nil {
+		return
+	}
+	we.mu.Lock()
+	defer we.mu.Unlock()
+	we.err = err
+}
+
+// Emit writes the canonical record with every attribute and the request
+// duration. Only the first call writes.
+func (we *WideEventEntry) Emit() {
+	if we == nil {
+		return
+	}
+	we.mu.Lock()
+	if we.emitted {
+		we.mu.Unlock()
+		return
+	}
+	we.emitted = true
+	keys, values, err := we.keys, we.values, we.err
+	we.mu.Unlock()
+
+	// the attributes are converted like the Fields of WithFields
+	logger := FromContext(we.ctx)
+	fields := make([]zapcore.Field, 0, len(keys)+2)
+	for _, k := range keys {
+		fields = append(fields, convertField(k, values[k], logger.sortFields))
+	}
+	fields = append(fields, zap.Duration(DurationKey, time.Since(we.start)))
+	if err != nil {
+		logger.errorLogger.Error(we.msg, append(fields, zap.Error(err))...)
+		return
+	}
+	logger.infoLogger.Info(we.msg, fields...)
+}
@@ -0,0 +1,27 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestWideEventConvertsFields(t *testing.T) {
+	var buf bytes.Buffer
+	le := NewWithWriter(Config{Level: InfoLevel, EncodeLogsAsJson: true, SortFields: true}, &buf)
+	ctx, we := StartWideEvent(ContextWithCustomizedLogger(context.Background(), le), "request")
+	we.Set("user", Fields{"h": 8, "c": 3, "a": 1, "f": 6, "b": 2, "g": 7, "e": 5, "d": 4})
+	we.Set("items", []Fields{{"b": 2, "a": 1}})
+	WideEvent(ctx).Emit()
+
+	out := buf.String()
+	for _, want := range []string{
+		`"user":{"a":1,"b":2,"c":3,"d":4,"e":5,"f":6,"g":7,"h":8}`,
+		`"items":[{"a":1,"b":2}]`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s in\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package log
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// writerTaps holds the writers added by AddWriter, replaced as a whole under
+// writerTapsMu so the cores read it without locking
+var (
+	writerTaps   atomic.Pointer[[]*writerTap]
+	writerTapsMu sync.Mutex
+)
+
+// writerTap is a writer added by AddWriter
+type writerTap struct {
+	out      zapcore.WriteSyncer
+	enc      zapcore.Encoder
+	minLevel Level
+}
+
+// WriterHandle detaches a writer added by AddWriter
+type WriterHandle struct {
+	tap  *writerTap
+	once sync.Once
+}
+
+// AddWriter copies the entries at minLevel and above written by the loggers
+// of this package to w, e.g. to capture errors into an in-process alert
+// buffer. Entries are encoded as JSON with asJSON and like the console
+// otherwise, with the time and level settings of the default logger. Only
+// the entries the loggers write are seen, not the ones below their level.
+// Write errors of w are ignored, writes to w are serialized.
+func AddWriter(w io.Writer, minLevel Level, asJSON bool) *WriterHandle {
+	config := defaults.Load().config
+	config.EncodeLogsAsJson = asJSON
+	tap := &writerTap{
+		out:      zapcore.Lock(zapcore.AddSync(w)),
+		enc:      newEncoder(config),
+		minLevel: minLevel,
+	}
+
+	writerTapsMu.Lock()
+	defer writerTapsMu.Unlock()
+	var taps []*writerTap
+	if old := writerTaps.Load(); old != nil {
+		taps = append(taps, *old...)
+	}
+	taps = append(taps, tap)
+	writerTaps.Store(&taps)
+	return &WriterHandle{tap: tap}
+}
+
+// Remove detaches the writer, entries being written may still reach it.
+// Calling Remove again does nothing.
+func (h *WriterHandle) Remove() {
+	h.once.Do(func() {
+		writerTapsMu.Lock()
+		defer writerTapsMu.Unlock()
+		old := writerTaps.Load()
+		if old == nil {
+			return
+		}
+		taps := make([]*writerTap, 0, len(*old))
+		for _, tap := range *old {
+			if tap != h.tap {
+				taps = append(taps, tap)
+			}
+		}
+		writerTaps.Store(&taps)
+	})
+}
+
+// enabled reports whether the tap takes the entry
+func (t *writerTap) enabled(ent zapcore.Entry, fields []zapcore.Field) bool {
+	return levelAccepts(t.minLevel, ent, fields)
+}
+
+// writerCore copies the entries to the writers added by AddWriter. Like
+// tailCore it keeps the With fields to encode them only when needed.
+type writerCore struct {
+	zapcore.Core
+	context []zapcore.Field
+}
+
+func newWriterCore(core zapcore.Core) zapcore.Core {
+	return &writerCore{Core: core}
+}
+
+func (c *writerCore) With(fields []zapcore.Field) zapcore.Core {
+	context := make([]zapcore.Field, 0, len(c.context)+len(fields))
+	context = append(context, c.context...)
+	return &writerCore{Core: c.Core.With(fields), context: append(context, fields...)}
+}
+
+func (c *writerCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *writerCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	err := c.Core.Write(ent, fields)
+	taps := writerTaps.Load()
+	if taps == nil || hasBelowLevelMarker(fields) {
+		return err
+	}
+	for _, tap := range *taps {
+		if !tap.enabled(ent, fields) {
+			continue
+		}
+		enc := tap.enc.Clone()
+		for _, f := range c.context {
+			f.AddTo(enc)
+		}
+		if buf, encErr := enc.EncodeEntry(ent, fields); encErr == nil {
+			_, _ = tap.out.Write(buf.Bytes())
+			buf.Free()
+		}
+	}
+	return err
+}